@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/database"
+)
+
+const minBootstrapPasswordLength = 12
+
+func runBootstrapAdmin(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("bootstrap-admin", flag.ExitOnError)
+	email := flags.String("email", "", "email of the first superadmin (required)")
+	name := flags.String("name", "", "display name of the first superadmin")
+	passwordStdin := flags.Bool("password-stdin", false, "read the initial password from stdin instead of generating one")
+	flags.Parse(args)
+
+	if *email == "" {
+		return errors.New("usage: bootstrap-admin --email <email> [--name <name>] [--password-stdin]")
+	}
+
+	password, generated, err := bootstrapPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := requireMigrated(db); err != nil {
+		return err
+	}
+
+	authService, err := newAuthService(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	user, err := authService.BootstrapSuperAdmin(strings.TrimSpace(*email), *name, password)
+	if err != nil {
+		return fmt.Errorf("failed to bootstrap superadmin: %w", err)
+	}
+
+	fmt.Printf("Created superadmin %s (id %d)\n", user.Email, user.ID)
+	if generated {
+		fmt.Printf("Initial password: %s\n", password)
+	}
+	fmt.Println("The password must be changed on first login.")
+
+	return nil
+}
+
+func bootstrapPassword(fromStdin bool) (string, bool, error) {
+	if !fromStdin {
+		buf := make([]byte, 18)
+		if _, err := rand.Read(buf); err != nil {
+			return "", false, fmt.Errorf("failed to generate password: %w", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(buf), true, nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false, fmt.Errorf("failed to read password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) < minBootstrapPasswordLength {
+		return "", false, fmt.Errorf("password must be at least %d characters", minBootstrapPasswordLength)
+	}
+
+	return password, false, nil
+}
@@ -4,7 +4,7 @@ import { useEffect, useState } from 'react'
 import { useRouter } from 'next/navigation'
 import Link from 'next/link'
 import { api, User } from '@/lib/api'
-import { clearTokens, isAuthenticated, isSuperAdmin } from '@/lib/auth'
+import { clearTokens, isAuthenticated, canManageRestaurants } from '@/lib/auth'
 
 export default function DashboardPage() {
   const router = useRouter()
@@ -68,7 +68,7 @@ export default function DashboardPage() {
                 >
                   Dashboard
                 </Link>
-                {isSuperAdmin(user) && (
+                {canManageRestaurants(user) && (
                   <Link
                     href="/dashboard/restaurants"
                     className="border-transparent text-gray-500 hover:border-gray-300 hover:text-gray-700 inline-flex items-center px-1 pt-1 border-b-2 text-sm font-medium"
@@ -103,13 +103,13 @@ export default function DashboardPage() {
               Hello, {user.name || user.email}! You are logged in as {user.role}.
             </p>
 
-            {isSuperAdmin(user) ? (
+            {canManageRestaurants(user) ? (
               <div className="bg-blue-50 border border-blue-200 rounded-lg p-4">
                 <h2 className="text-lg font-semibold text-blue-900 mb-2">
-                  Super Admin Access
+                  Restaurant Management
                 </h2>
                 <p className="text-blue-700 mb-4">
-                  As a superadmin, you have access to manage restaurants.
+                  Your role gives you access to manage restaurants.
                 </p>
                 <Link
                   href="/dashboard/restaurants"
@@ -4,7 +4,7 @@ import { useEffect, useState } from 'react'
 import { useRouter } from 'next/navigation'
 import Link from 'next/link'
 import { api, User, Restaurant } from '@/lib/api'
-import { clearTokens, isAuthenticated, isSuperAdmin } from '@/lib/auth'
+import { clearTokens, isAuthenticated, canManageRestaurants } from '@/lib/auth'
 
 export default function RestaurantsPage() {
   const router = useRouter()
@@ -33,7 +33,7 @@ export default function RestaurantsPage() {
   }, [router])
 
   useEffect(() => {
-    if (user && isSuperAdmin(user)) {
+    if (user && canManageRestaurants(user)) {
       loadRestaurants()
     }
   }, [user])
@@ -43,7 +43,7 @@ export default function RestaurantsPage() {
       const response = await api.getMe()
       const userData = response.user
       setUser(userData)
-      if (!isSuperAdmin(userData)) {
+      if (!canManageRestaurants(userData)) {
         router.push('/dashboard')
       }
     } catch (err) {
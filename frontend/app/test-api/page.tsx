@@ -7,6 +7,8 @@ const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080/api/v1
 export default function TestAPIPage() {
   const [result, setResult] = useState<any>(null)
   const [loading, setLoading] = useState(false)
+  const [email, setEmail] = useState('')
+  const [password, setPassword] = useState('')
 
   const testHealth = async () => {
     setLoading(true)
@@ -27,10 +29,7 @@ export default function TestAPIPage() {
       const response = await fetch(`${API_URL}/auth/login`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({
-          email: 'admin@saas-platform.com',
-          password: 'Admin123!'
-        })
+        body: JSON.stringify({ email, password })
       })
       const data = await response.json()
       setResult({ success: response.ok, status: response.status, data })
@@ -57,6 +56,22 @@ export default function TestAPIPage() {
 
         <div className="bg-white p-6 rounded-lg shadow mb-6">
           <h2 className="text-xl font-semibold mb-4">Tests</h2>
+          <div className="space-y-2 mb-4">
+            <input
+              type="email"
+              placeholder="Email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              className="w-full px-3 py-2 border rounded"
+            />
+            <input
+              type="password"
+              placeholder="Password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              className="w-full px-3 py-2 border rounded"
+            />
+          </div>
           <div className="space-x-4">
             <button
               onClick={testHealth}
@@ -4,22 +4,373 @@ export interface User {
   id: number;
   email: string;
   name?: string;
-  role: 'user' | 'admin' | 'superadmin';
+  role: string;
+  permissions?: string[];
   created_at: string;
   updated_at: string;
 }
 
-export interface Restaurant {
+export interface GeoPoint {
+  lat: number;
+  lng: number;
+}
+
+export interface RestaurantLocation {
+  street?: string;
+  city?: string;
+  postcode?: string;
+  country?: string;
+  latitude?: number;
+  longitude?: number;
+  delivery_radius_m?: number;
+  delivery_polygon?: GeoPoint[];
+}
+
+export interface OpenStatus {
+  time_zone: string;
+  is_open_now: boolean;
+  next_opening_at: string | null;
+}
+
+// weekday 0 is Sunday; times are local HH:MM
+export interface OpeningHours {
+  time_zone: string;
+  weekly: { weekday: number; opens: string; closes: string }[];
+  exceptions: { date: string; opens?: string; closes?: string; note?: string }[];
+}
+
+// Amounts are in minor units of the restaurant's currency; rates in basis
+// points (2000 is 20%)
+export interface PricingRules {
+  prices_include_tax: boolean;
+  tax_rate_bp: number;
+  service_fee_bp: number;
+  service_fee_min: number;
+  service_fee_max?: number;
+  minimum_order: number;
+  delivery_tiers: { up_to_m: number; fee: number }[];
+}
+
+export type FeeType = 'delivery' | 'service' | 'small_order';
+
+export interface PriceBreakdown {
+  currency: string;
+  subtotal: number;
+  prices_include_tax: boolean;
+  taxes: { rate_bp: number; base: number; amount: number }[];
+  tax_total: number;
+  fees: { type: FeeType; amount: number }[];
+  fee_total: number;
+  total: number;
+  distance_m?: number;
+}
+
+export interface GeoLocation {
+  lat: number;
+  lng: number;
+}
+
+export interface Restaurant extends RestaurantLocation, OpenStatus {
   id: number;
   name: string;
+  slug: string;
   description?: string;
   address?: string;
   phone?: string;
   email?: string;
   image_url?: string;
+  cuisines: string[];
+  currency: string; // ISO 4217; every price of the restaurant is in it
   is_active: boolean;
   created_at: string;
   updated_at: string;
+  distance_m?: number;
+}
+
+export interface PublicRestaurant extends RestaurantLocation, OpenStatus {
+  slug: string;
+  name: string;
+  description?: string;
+  address?: string;
+  phone?: string;
+  image_url?: string;
+  cuisines: string[];
+  currency: string;
+  updated_at: string;
+  distance_m?: number;
+}
+
+export interface RestaurantListParams {
+  q?: string;
+  is_active?: boolean;
+  open_now?: boolean;
+  cuisine?: string[];
+  created_from?: string;
+  created_to?: string;
+  lat?: number;
+  lng?: number;
+  radius?: number;
+  sort?: string;
+  page?: number;
+  page_size?: number;
+}
+
+// Cursor pagination: pass next_cursor from the previous page as cursor
+export interface CursorListParams extends Omit<RestaurantListParams, 'page' | 'page_size'> {
+  cursor?: string;
+  limit?: number;
+  total?: 'none' | 'exact' | 'estimate';
+}
+
+export interface CursorPage<T> {
+  data: T[];
+  next_cursor: string | null;
+  limit: number;
+  total?: number;
+  total_estimated?: boolean;
+}
+
+function listQuery(params: RestaurantListParams | CursorListParams | OrderListParams): string {
+  const query = new URLSearchParams();
+  for (const [key, value] of Object.entries(params)) {
+    if (value === undefined || value === '') continue;
+    query.set(key, Array.isArray(value) ? value.join(',') : String(value));
+  }
+  return query.toString();
+}
+
+// Prices are in minor units of currency (kopecks, cents)
+export interface MenuModifierOption {
+  id: number;
+  group_id: number;
+  name: string;
+  price: number;
+  is_available: boolean;
+  available_at?: string;
+  position: number;
+}
+
+export interface MenuModifierGroup {
+  id: number;
+  item_id: number;
+  name: string;
+  min_selections: number;
+  max_selections: number;
+  position: number;
+  options: MenuModifierOption[];
+}
+
+export interface MenuItem {
+  id: number;
+  category_id: number;
+  name: string;
+  description?: string;
+  image_url?: string;
+  price: number;
+  currency: string;
+  is_available: boolean;
+  available_at?: string;
+  daily_stock?: number;
+  stock_remaining?: number;
+  position: number;
+  modifier_groups: MenuModifierGroup[];
+}
+
+export interface MenuCategory {
+  id: number;
+  name: string;
+  description?: string;
+  position: number;
+  tax_rate_bp?: number;
+  items: MenuItem[];
+}
+
+export interface Menu {
+  categories: MenuCategory[];
+}
+
+// Cart prices are worked out by the server from the current menu
+export interface CartLineOption {
+  id: number;
+  name: string;
+  price: number;
+}
+
+export interface CartLine {
+  id: number;
+  item_id: number;
+  quantity: number;
+  option_ids: number[];
+  notes?: string;
+  name: string;
+  options: CartLineOption[];
+  unit_price: number;
+  total: number;
+  tax_rate_bp: number;
+  is_valid: boolean;
+  problem?: string;
+}
+
+export interface Cart {
+  id: number;
+  restaurant_id: number;
+  restaurant_slug: string;
+  restaurant_name: string;
+  status: string;
+  lines: CartLine[];
+  currency: string;
+  subtotal: number;
+  item_count: number;
+  is_valid: boolean;
+  created_at: string;
+  updated_at: string;
+  breakdown?: PriceBreakdown; // Only on a quote
+}
+
+export interface CartLineRequest {
+  item_id: number;
+  quantity: number;
+  option_ids?: number[];
+  notes?: string;
+}
+
+export type OrderStatus =
+  | 'placed'
+  | 'accepted'
+  | 'preparing'
+  | 'ready'
+  | 'picked_up'
+  | 'delivered'
+  | 'rejected'
+  | 'cancelled';
+
+export interface OrderLine {
+  id: number;
+  item_id: number;
+  name: string;
+  quantity: number;
+  unit_price: number;
+  total: number;
+  tax_rate_bp: number;
+  options: CartLineOption[];
+  notes?: string;
+}
+
+export interface OrderEvent {
+  id: number;
+  from_status: OrderStatus | null;
+  to_status: OrderStatus;
+  actor_id?: number;
+  reason?: string;
+  created_at: string;
+}
+
+export interface Order {
+  id: number;
+  user_id?: number;
+  restaurant_id: number;
+  status: OrderStatus;
+  currency: string;
+  subtotal: number;
+  total: number;
+  breakdown?: PriceBreakdown;
+  notes?: string;
+  lines: OrderLine[];
+  events?: OrderEvent[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface OrderListParams extends Pick<CursorListParams, 'cursor' | 'limit' | 'total'> {
+  status?: OrderStatus[];
+}
+
+export type PaymentStatus = 'pending' | 'authorized' | 'captured' | 'voided' | 'failed';
+
+export interface Payment {
+  id: number;
+  order_id: number;
+  provider: string;
+  provider_payment_id: string;
+  status: PaymentStatus;
+  amount: number;
+  captured_amount: number;
+  refunded_amount: number;
+  currency: string;
+  client_secret?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export type RefundReasonCode =
+  | 'missing_item'
+  | 'wrong_item'
+  | 'quality_issue'
+  | 'late_delivery'
+  | 'goodwill'
+  | 'other';
+
+export interface RefundLine {
+  id: number;
+  order_line_id: number;
+  quantity: number;
+  amount: number;
+}
+
+export interface Refund {
+  id: number;
+  order_id: number;
+  payment_id: number;
+  amount: number;
+  currency: string;
+  reason_code: RefundReasonCode;
+  note?: string;
+  status: 'pending' | 'succeeded' | 'failed';
+  provider_refund_id?: string;
+  failure_reason?: string;
+  created_by?: number;
+  lines: RefundLine[];
+  created_at: string;
+  updated_at: string;
+}
+
+// Either lines or amount; with neither, everything left is refunded
+export interface RefundRequest {
+  lines?: { line_id: number; quantity: number }[];
+  amount?: number;
+  reason_code: RefundReasonCode;
+  note?: string;
+}
+
+export interface LedgerEntry {
+  id: number;
+  payment_id: number;
+  entry_type: 'authorization' | 'capture' | 'refund' | 'void';
+  amount: number;
+  currency: string;
+  provider_reference?: string;
+  created_at: string;
+}
+
+export interface OrderFinancials {
+  order_id: number;
+  status: OrderStatus;
+  currency: string;
+  total: number;
+  captured: number;
+  refunded: number;
+  net: number;
+  refundable: number;
+  payments: Payment[];
+  refunds: Refund[];
+  ledger: LedgerEntry[];
+}
+
+// Switching off may set restore_at or for_today (until local midnight)
+export interface AvailabilityRequest {
+  is_available: boolean;
+  restore_at?: string;
+  for_today?: boolean;
 }
 
 export interface AuthResponse {
@@ -100,19 +451,77 @@ class ApiClient {
   }
 
   // Restaurants
-  async getRestaurants(page = 1, pageSize = 10): Promise<{
+  async getRestaurants(params: RestaurantListParams = {}): Promise<{
     data: Restaurant[];
     total: number;
     page: number;
     page_size: number;
   }> {
-    return this.request(`/restaurants?page=${page}&page_size=${pageSize}`);
+    return this.request(`/restaurants?${listQuery({ page: 1, page_size: 10, ...params })}`);
+  }
+
+  async getRestaurantsPage(params: CursorListParams = {}): Promise<CursorPage<Restaurant>> {
+    return this.request(`/restaurants?${listQuery({ limit: 20, ...params })}`);
   }
 
   async getRestaurant(id: number): Promise<Restaurant> {
     return this.request<Restaurant>(`/restaurants/${id}`);
   }
 
+  async getOpeningHours(id: number): Promise<OpeningHours> {
+    return this.request<OpeningHours>(`/restaurants/${id}/hours`);
+  }
+
+  async setOpeningHours(id: number, hours: OpeningHours): Promise<OpeningHours> {
+    return this.request<OpeningHours>(`/restaurants/${id}/hours`, {
+      method: 'PUT',
+      body: JSON.stringify(hours),
+    });
+  }
+
+  async getPricing(id: number): Promise<PricingRules> {
+    return this.request<PricingRules>(`/restaurants/${id}/pricing`);
+  }
+
+  async setPricing(id: number, rules: PricingRules): Promise<PricingRules> {
+    return this.request<PricingRules>(`/restaurants/${id}/pricing`, {
+      method: 'PUT',
+      body: JSON.stringify(rules),
+    });
+  }
+
+  async getMenu(restaurantId: number): Promise<Menu> {
+    return this.request<Menu>(`/restaurants/${restaurantId}/menu`);
+  }
+
+  async setItemAvailability(restaurantId: number, itemId: number, data: AvailabilityRequest): Promise<MenuItem> {
+    return this.request<MenuItem>(`/restaurants/${restaurantId}/menu/items/${itemId}/availability`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async setCategoryAvailability(restaurantId: number, categoryId: number, data: AvailabilityRequest): Promise<MenuCategory> {
+    return this.request<MenuCategory>(`/restaurants/${restaurantId}/menu/categories/${categoryId}/availability`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async setModifierOptionAvailability(restaurantId: number, optionId: number, data: AvailabilityRequest): Promise<MenuModifierOption> {
+    return this.request<MenuModifierOption>(`/restaurants/${restaurantId}/menu/modifier-options/${optionId}/availability`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async setItemStock(restaurantId: number, itemId: number, dailyStock: number | null, remaining?: number): Promise<MenuItem> {
+    return this.request<MenuItem>(`/restaurants/${restaurantId}/menu/items/${itemId}/stock`, {
+      method: 'PUT',
+      body: JSON.stringify({ daily_stock: dailyStock, remaining }),
+    });
+  }
+
   async createRestaurant(data: Partial<Restaurant>): Promise<Restaurant> {
     return this.request<Restaurant>('/restaurants', {
       method: 'POST',
@@ -132,6 +541,137 @@ class ApiClient {
       method: 'DELETE',
     });
   }
+
+  // Public catalogue (no login required)
+  async getPublicRestaurants(params: RestaurantListParams = {}): Promise<{
+    data: PublicRestaurant[];
+    total: number;
+    page: number;
+    page_size: number;
+  }> {
+    return this.request(`/public/restaurants?${listQuery({ page: 1, page_size: 20, ...params })}`);
+  }
+
+  async getPublicRestaurantsPage(params: CursorListParams = {}): Promise<CursorPage<PublicRestaurant>> {
+    return this.request(`/public/restaurants?${listQuery({ limit: 20, ...params })}`);
+  }
+
+  async getPublicMenu(slug: string): Promise<Menu> {
+    return this.request<Menu>(`/public/restaurants/${encodeURIComponent(slug)}/menu`);
+  }
+
+  async getCarts(): Promise<Cart[]> {
+    return this.request<Cart[]>('/me/carts');
+  }
+
+  async openCart(restaurantSlug: string): Promise<Cart> {
+    return this.request<Cart>('/me/carts', {
+      method: 'POST',
+      body: JSON.stringify({ restaurant_slug: restaurantSlug }),
+    });
+  }
+
+  async getCart(id: number): Promise<Cart> {
+    return this.request<Cart>(`/me/carts/${id}`);
+  }
+
+  async getCartQuote(id: number, location?: GeoLocation): Promise<Cart> {
+    const query = location ? `?lat=${location.lat}&lng=${location.lng}` : '';
+    return this.request<Cart>(`/me/carts/${id}/quote${query}`);
+  }
+
+  async deleteCart(id: number): Promise<{ message: string }> {
+    return this.request<{ message: string }>(`/me/carts/${id}`, {
+      method: 'DELETE',
+    });
+  }
+
+  async addCartLine(cartId: number, line: CartLineRequest): Promise<Cart> {
+    return this.request<Cart>(`/me/carts/${cartId}/lines`, {
+      method: 'POST',
+      body: JSON.stringify(line),
+    });
+  }
+
+  async updateCartLine(cartId: number, lineId: number, data: Partial<Omit<CartLineRequest, 'item_id'>>): Promise<Cart> {
+    return this.request<Cart>(`/me/carts/${cartId}/lines/${lineId}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async placeOrder(cartId: number, notes?: string, location?: GeoLocation): Promise<Order> {
+    return this.request<Order>('/me/orders', {
+      method: 'POST',
+      body: JSON.stringify({ cart_id: cartId, notes, latitude: location?.lat, longitude: location?.lng }),
+    });
+  }
+
+  async getMyOrders(params: OrderListParams = {}): Promise<CursorPage<Order>> {
+    return this.request(`/me/orders?${listQuery(params)}`);
+  }
+
+  async getMyOrder(id: number): Promise<Order> {
+    return this.request<Order>(`/me/orders/${id}`);
+  }
+
+  async cancelOrder(id: number, reason?: string): Promise<Order> {
+    return this.request<Order>(`/me/orders/${id}/cancel`, {
+      method: 'POST',
+      body: JSON.stringify({ reason }),
+    });
+  }
+
+  async getRestaurantOrders(restaurantId: number, params: OrderListParams = {}): Promise<CursorPage<Order>> {
+    return this.request(`/restaurants/${restaurantId}/orders?${listQuery(params)}`);
+  }
+
+  async getRestaurantOrder(restaurantId: number, orderId: number): Promise<Order> {
+    return this.request<Order>(`/restaurants/${restaurantId}/orders/${orderId}`);
+  }
+
+  async changeOrderStatus(restaurantId: number, orderId: number, status: OrderStatus, reason?: string): Promise<Order> {
+    return this.request<Order>(`/restaurants/${restaurantId}/orders/${orderId}/status`, {
+      method: 'POST',
+      body: JSON.stringify({ status, reason }),
+    });
+  }
+
+  async payOrder(orderId: number): Promise<Payment> {
+    return this.request<Payment>(`/me/orders/${orderId}/payment`, {
+      method: 'POST',
+    });
+  }
+
+  async getOrderPayment(orderId: number): Promise<Payment> {
+    return this.request<Payment>(`/me/orders/${orderId}/payment`);
+  }
+
+  async getRestaurantOrderPayment(restaurantId: number, orderId: number): Promise<Payment> {
+    return this.request<Payment>(`/restaurants/${restaurantId}/orders/${orderId}/payment`);
+  }
+
+  async refundOrder(orderId: number, data: RefundRequest, idempotencyKey?: string): Promise<Refund> {
+    return this.request<Refund>(`/orders/${orderId}/refunds`, {
+      method: 'POST',
+      headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined,
+      body: JSON.stringify(data),
+    });
+  }
+
+  async getOrderFinancials(orderId: number): Promise<OrderFinancials> {
+    return this.request<OrderFinancials>(`/orders/${orderId}/financials`);
+  }
+
+  async deleteCartLine(cartId: number, lineId: number): Promise<Cart> {
+    return this.request<Cart>(`/me/carts/${cartId}/lines/${lineId}`, {
+      method: 'DELETE',
+    });
+  }
+
+  async getPublicRestaurant(slug: string): Promise<PublicRestaurant> {
+    return this.request<PublicRestaurant>(`/public/restaurants/${encodeURIComponent(slug)}`);
+  }
 }
 
 export const api = new ApiClient();
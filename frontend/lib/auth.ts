@@ -28,6 +28,11 @@ export function isAuthenticated(): boolean {
   return getAccessToken() !== null;
 }
 
-export function isSuperAdmin(user: User | null): boolean {
-  return user?.role === 'superadmin';
+export function hasPermission(user: User | null, permission: string): boolean {
+  const permissions = user?.permissions ?? [];
+  return permissions.includes('*') || permissions.includes(permission);
+}
+
+export function canManageRestaurants(user: User | null): boolean {
+  return hasPermission(user, 'restaurants:read');
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,6 +11,9 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Auth     AuthConfig
+	Mail     MailConfig
+	Payments PaymentsConfig
 }
 
 type ServerConfig struct {
@@ -18,6 +22,9 @@ type ServerConfig struct {
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 	Environment  string
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is believed. Without any,
+	// the client IP is the address of the connection.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -31,13 +38,50 @@ type DatabaseConfig struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
+	AutoMigrate     bool
 }
 
 type JWTConfig struct {
-	AccessSecret  string
-	RefreshSecret string
-	AccessTTL     time.Duration
-	RefreshTTL    time.Duration
+	AccessSecret       string // Signs MFA challenge tokens
+	RefreshSecret      string
+	AccessTTL          time.Duration
+	RefreshTTL         time.Duration
+	SigningAlgorithm   string        // "RS256" or "EdDSA", for newly generated access token keys
+	KeyRefreshInterval time.Duration // How often replicas reload the key set
+	KeyEncryptionKey   string        // Encrypts the private signing keys stored in the database
+}
+
+type AuthConfig struct {
+	RequireVerifiedEmail bool
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	RequireMFAForAdmins  bool
+	MFAIssuer            string // Shown by authenticator apps next to the account
+	LoginMaxAttempts     int    // Failures per account before a lockout
+	LoginIPMaxAttempts   int    // Failures per client IP before a lockout
+	LoginLockoutDuration time.Duration
+	LoginBackoffBase     time.Duration
+	LoginThrottleStore   string // "memory" (single instance) or "postgres"
+}
+
+type MailConfig struct {
+	Driver       string // "log" or "smtp"
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	FileDir      string // Where the log driver writes messages; empty logs them instead
+	AppURL       string // Frontend base URL used to build links in emails
+}
+
+type PaymentsConfig struct {
+	Provider        string // "fake" (in memory, for development) or "stripe"
+	StripeSecretKey string
+	StripeAPIURL    string // Stripe or a compatible service
+
+	WebhookSecret    string        // Shared with the provider to sign webhooks; without it webhooks are refused
+	WebhookTolerance time.Duration // How far a webhook's signed timestamp may be from now
 }
 
 func Load() *Config {
@@ -48,6 +92,8 @@ func Load() *Config {
 			WriteTimeout: parseDuration(getEnv("SERVER_WRITE_TIMEOUT", "15s")),
 			IdleTimeout:  parseDuration(getEnv("SERVER_IDLE_TIMEOUT", "60s")),
 			Environment:  getEnv("ENVIRONMENT", "development"),
+
+			TrustedProxies: parseList(getEnv("SERVER_TRUSTED_PROXIES", "")),
 		},
 		Database: DatabaseConfig{
 			Host:            getEnv("DB_HOST", "localhost"),
@@ -60,12 +106,46 @@ func Load() *Config {
 			MaxIdleConns:    parseInt(getEnv("DB_MAX_IDLE_CONNS", "5")),
 			ConnMaxLifetime: parseDuration(getEnv("DB_CONN_MAX_LIFETIME", "5m")),
 			ConnMaxIdleTime: parseDuration(getEnv("DB_CONN_MAX_IDLE_TIME", "10m")),
+			AutoMigrate:     parseBool(getEnv("DB_AUTO_MIGRATE", "false")),
 		},
 		JWT: JWTConfig{
-			AccessSecret:  getEnv("JWT_ACCESS_SECRET", "your-access-secret-key-minimum-32-characters-long"),
-			RefreshSecret: getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-key-minimum-32-characters-long"),
-			AccessTTL:     parseDuration(getEnv("JWT_ACCESS_TTL", "15m")),
-			RefreshTTL:    parseDuration(getEnv("JWT_REFRESH_TTL", "168h")),
+			AccessSecret:       getEnv("JWT_ACCESS_SECRET", "your-access-secret-key-minimum-32-characters-long"),
+			RefreshSecret:      getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-key-minimum-32-characters-long"),
+			AccessTTL:          parseDuration(getEnv("JWT_ACCESS_TTL", "15m")),
+			RefreshTTL:         parseDuration(getEnv("JWT_REFRESH_TTL", "168h")),
+			SigningAlgorithm:   getEnv("JWT_SIGNING_ALGORITHM", "RS256"),
+			KeyRefreshInterval: parseDuration(getEnv("JWT_KEY_REFRESH_INTERVAL", "1m")),
+			KeyEncryptionKey:   getEnv("JWT_KEY_ENCRYPTION_KEY", "your-key-encryption-key-minimum-32-characters-long"),
+		},
+		Auth: AuthConfig{
+			RequireVerifiedEmail: parseBool(getEnv("AUTH_REQUIRE_VERIFIED_EMAIL", "false")),
+			PasswordResetTTL:     parseDuration(getEnv("AUTH_PASSWORD_RESET_TTL", "1h")),
+			EmailVerificationTTL: parseDuration(getEnv("AUTH_EMAIL_VERIFICATION_TTL", "48h")),
+			RequireMFAForAdmins:  parseBool(getEnv("AUTH_REQUIRE_MFA_FOR_ADMINS", "false")),
+			MFAIssuer:            getEnv("AUTH_MFA_ISSUER", "SaaS Platform"),
+			LoginMaxAttempts:     parseInt(getEnv("AUTH_LOGIN_MAX_ATTEMPTS", "10")),
+			LoginIPMaxAttempts:   parseInt(getEnv("AUTH_LOGIN_IP_MAX_ATTEMPTS", "100")),
+			LoginLockoutDuration: parseDuration(getEnv("AUTH_LOGIN_LOCKOUT_DURATION", "15m")),
+			LoginBackoffBase:     parseDuration(getEnv("AUTH_LOGIN_BACKOFF_BASE", "1s")),
+			LoginThrottleStore:   getEnv("AUTH_LOGIN_THROTTLE_STORE", "memory"),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "noreply@saas-platform.com"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUser:     getEnv("SMTP_USER", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", ""),
+			AppURL:       getEnv("APP_URL", "http://localhost:3000"),
+		},
+		Payments: PaymentsConfig{
+			Provider:        getEnv("PAYMENTS_PROVIDER", "fake"),
+			StripeSecretKey: getEnv("STRIPE_SECRET_KEY", ""),
+			StripeAPIURL:    getEnv("STRIPE_API_URL", "https://api.stripe.com"),
+
+			WebhookSecret:    getEnv("PAYMENTS_WEBHOOK_SECRET", ""),
+			WebhookTolerance: parseDuration(getEnv("PAYMENTS_WEBHOOK_TOLERANCE", "5m")),
 		},
 	}
 }
@@ -85,6 +165,25 @@ func parseInt(s string) int {
 	return value
 }
 
+func parseBool(s string) bool {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return false
+	}
+	return value
+}
+
+// parseList splits a comma-separated value, dropping empty entries.
+func parseList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func parseDuration(s string) time.Duration {
 	duration, err := time.ParseDuration(s)
 	if err != nil {
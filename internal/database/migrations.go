@@ -1,138 +1,426 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"embed"
+	"encoding/hex"
 	"fmt"
-
-	"golang.org/x/crypto/bcrypt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Migrations live in migrations/ as <version>_<name>.up.sql and an optional
+// <version>_<name>.down.sql. The version prefix is numeric and determines the
+// order in which they are applied.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the Postgres advisory lock key held while migrations run,
+// so that replicas starting at the same time apply them one after another.
+const migrationLockID int64 = 4_815_162_342
+
+var migrationNamePattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+type Migration struct {
+	Version  int
+	Name     string // Full identifier stored in schema_migrations, e.g. "001_initial"
+	UpSQL    string
+	DownSQL  string
+	Checksum string
+}
+
+type AppliedMigration struct {
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type MigrationStatus struct {
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // Applied, but the file no longer matches the recorded checksum
+	Missing   bool // Applied, but this build has no file for it
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// RunMigrations applies every pending migration.
 func RunMigrations(db *sql.DB) error {
-	// Create migrations table if it doesn't exist
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(0)
+	return err
+}
+
+// LoadMigrations reads the embedded migration files, ordered by version.
+func LoadMigrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byName := make(map[string]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var name, direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			name, direction = strings.TrimSuffix(fileName, ".up.sql"), "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			name, direction = strings.TrimSuffix(fileName, ".down.sql"), "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", fileName)
+		}
+
+		migration, ok := byName[name]
+		if !ok {
+			prefix, _, _ := strings.Cut(name, "_")
+			version, err := strconv.Atoi(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration version in %s", fileName)
+			}
+			migration = &Migration{Version: version, Name: name}
+			byName[name] = migration
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+
+		if direction == "up" {
+			sum := sha256.Sum256(content)
+			migration.UpSQL = string(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byName))
+	versions := make(map[int]string)
+	for _, migration := range byName {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration.Name)
+		}
+		if other, ok := versions[migration.Version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, migration.Name, migration.Version)
+		}
+		versions[migration.Version] = migration.Name
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies up to limit pending migrations in version order. A limit of zero
+// or less applies all of them. It returns the migrations that were applied.
+func (m *Migrator) Up(limit int) ([]*Migration, error) {
+	var applied []*Migration
+
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if limit > 0 && len(applied) >= limit {
+				break
+			}
+			if _, ok := done[migration.Name]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.UpSQL, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx,
+					"INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)",
+					migration.Name, migration.Checksum,
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+			}
+
+			log.Printf("Applied migration %s", migration.Name)
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the last limit applied migrations, newest first.
+func (m *Migrator) Down(limit int) ([]*Migration, error) {
+	var reverted []*Migration
+
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < limit; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Name]; !ok {
+				continue
+			}
+			if migration.DownSQL == "" {
+				return fmt.Errorf("migration %s cannot be rolled back: no down file", migration.Name)
+			}
+
+			if err := m.apply(ctx, conn, migration.DownSQL, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to roll back migration %s: %w", migration.Name, err)
+			}
+
+			log.Printf("Rolled back migration %s", migration.Name)
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status reports every known migration in version order, followed by any
+// applied versions that this build does not know about.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		done, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[string]bool, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Name] = true
+			status := MigrationStatus{Name: migration.Name}
+			if record, ok := done[migration.Name]; ok {
+				status.Applied = true
+				status.AppliedAt = record.AppliedAt
+				status.Modified = record.Checksum != "" && record.Checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+
+		var missing []MigrationStatus
+		for name, record := range done {
+			if !known[name] {
+				missing = append(missing, MigrationStatus{Name: name, Applied: true, AppliedAt: record.AppliedAt, Missing: true})
+			}
+		}
+		sort.Slice(missing, func(i, j int) bool { return missing[i].Name < missing[j].Name })
+		statuses = append(statuses, missing...)
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Pending returns the migrations that have not been applied yet. Like Up,
+// it fails if an applied migration was modified since, so that nothing
+// starts on a schema that does not match its files.
+func (m *Migrator) Pending() ([]*Migration, error) {
+	var pending []*Migration
+
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Name]; !ok {
+				pending = append(pending, migration)
+			}
+		}
+
+		return nil
+	})
+
+	return pending, err
+}
+
+func (m *Migrator) withLock(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	// Advisory locks belong to a session, so everything runs on one connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+
 	createMigrationsTable := `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version VARCHAR(255) PRIMARY KEY,
 			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
+
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);
 	`
 
-	if _, err := db.Exec(createMigrationsTable); err != nil {
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	// Check if migrations have already been applied
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = $1", "001_initial").Scan(&count)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to check migration status: %w", err)
-	}
-
-	if count == 0 {
-		// Initial migration - create users table with roles
-		migration := `
-			-- Create enum type if it doesn't exist
-			DO $$ BEGIN
-				CREATE TYPE user_role AS ENUM ('user', 'admin', 'superadmin');
-			EXCEPTION
-				WHEN duplicate_object THEN null;
-			END $$;
-			
-			-- Create users table if it doesn't exist
-			CREATE TABLE IF NOT EXISTS users (
-				id BIGSERIAL PRIMARY KEY,
-				email VARCHAR(255) UNIQUE NOT NULL,
-				password_hash VARCHAR(255) NOT NULL,
-				name VARCHAR(255),
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-			);
-
-			-- Add role column if it doesn't exist
-			DO $$ BEGIN
-				ALTER TABLE users ADD COLUMN role user_role DEFAULT 'user' NOT NULL;
-			EXCEPTION
-				WHEN duplicate_column THEN null;
-			END $$;
-
-			CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-			CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
-		`
-
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("failed to apply migration 001_initial: %w", err)
-		}
-
-		// Record migration
-		if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", "001_initial"); err != nil {
-			return fmt.Errorf("failed to record migration: %w", err)
-		}
-	}
-
-	// Migration 002 - create restaurants table
-	var count2 int
-	err2 := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = $1", "002_restaurants").Scan(&count2)
-	if err2 != nil && err2 != sql.ErrNoRows {
-		return fmt.Errorf("failed to check migration status: %w", err2)
-	}
-
-	if count2 == 0 {
-		migration := `
-			CREATE TABLE IF NOT EXISTS restaurants (
-				id BIGSERIAL PRIMARY KEY,
-				name VARCHAR(255) NOT NULL,
-				description TEXT,
-				address VARCHAR(500),
-				phone VARCHAR(50),
-				email VARCHAR(255),
-				image_url VARCHAR(500),
-				is_active BOOLEAN DEFAULT true,
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-			);
-
-			CREATE INDEX IF NOT EXISTS idx_restaurants_name ON restaurants(name);
-			CREATE INDEX IF NOT EXISTS idx_restaurants_is_active ON restaurants(is_active);
-		`
-
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("failed to apply migration 002_restaurants: %w", err)
-		}
-
-		if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", "002_restaurants"); err != nil {
-			return fmt.Errorf("failed to record migration: %w", err)
-		}
-	}
-
-	// Migration 003 - create default superadmin
-	var count3 int
-	err3 := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = $1", "003_create_superadmin").Scan(&count3)
-	if err3 != nil && err3 != sql.ErrNoRows {
-		return fmt.Errorf("failed to check migration status: %w", err3)
-	}
-
-	if count3 == 0 {
-		// Create default superadmin
-		// Email: admin@saas-platform.com
-		// Password: Admin123!
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("Admin123!"), bcrypt.DefaultCost)
-		if err != nil {
-			return fmt.Errorf("failed to hash superadmin password: %w", err)
+	return fn(ctx, conn)
+}
+
+// verify loads the applied migrations and checks that none of their files
+// changed since. Rows recorded before checksums existed are backfilled.
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[string]AppliedMigration, error) {
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range m.migrations {
+		record, ok := done[migration.Name]
+		if !ok {
+			continue
+		}
+
+		if record.Checksum == "" {
+			if _, err := conn.ExecContext(ctx,
+				"UPDATE schema_migrations SET checksum = $1 WHERE version = $2",
+				migration.Checksum, migration.Name,
+			); err != nil {
+				return nil, fmt.Errorf("failed to record checksum for %s: %w", migration.Name, err)
+			}
+			record.Checksum = migration.Checksum
+			done[migration.Name] = record
+			continue
+		}
+
+		if record.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %s was modified after it was applied (checksum mismatch)", migration.Name)
 		}
+	}
+
+	return done, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, query string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateMigration writes an empty up/down pair into dir using the next free
+// version number and returns the paths of the new files.
+func CreateMigration(dir, name string) (string, string, error) {
+	slug := strings.Trim(migrationNamePattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return "", "", fmt.Errorf("invalid migration name %q", name)
+	}
 
-		query := `
-			INSERT INTO users (email, password_hash, role, name)
-			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (email) DO NOTHING
-		`
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read migrations directory: %w", err)
+	}
 
-		if _, err := db.Exec(query, "admin@saas-platform.com", string(hashedPassword), "superadmin", "Super Admin"); err != nil {
-			return fmt.Errorf("failed to create superadmin: %w", err)
+	next := 1
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		if version, err := strconv.Atoi(prefix); err == nil && version >= next {
+			next = version + 1
 		}
+	}
+
+	base := fmt.Sprintf("%03d_%s", next, slug)
+	upPath := filepath.Join(dir, base+".up.sql")
+	downPath := filepath.Join(dir, base+".down.sql")
 
-		if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", "003_create_superadmin"); err != nil {
-			return fmt.Errorf("failed to record migration: %w", err)
+	if err := os.WriteFile(upPath, []byte("-- "+base+" (up)\n"), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	if err := os.WriteFile(downPath, []byte("-- "+base+" (down)\n"), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to create migration: %w", err)
+	}
+
+	return upPath, downPath, nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]AppliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check migration status: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[string]AppliedMigration)
+	for rows.Next() {
+		var record AppliedMigration
+		var checksum sql.NullString
+		var appliedAt sql.NullTime
+
+		if err := rows.Scan(&record.Name, &checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
 		}
+
+		record.Checksum = checksum.String
+		record.AppliedAt = appliedAt.Time
+		done[record.Name] = record
 	}
 
-	return nil
+	return done, rows.Err()
 }
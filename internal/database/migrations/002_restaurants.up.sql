@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS restaurants (
+	id BIGSERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	address VARCHAR(500),
+	phone VARCHAR(50),
+	email VARCHAR(255),
+	image_url VARCHAR(500),
+	is_active BOOLEAN DEFAULT true,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_restaurants_name ON restaurants(name);
+CREATE INDEX IF NOT EXISTS idx_restaurants_is_active ON restaurants(is_active);
@@ -0,0 +1 @@
+DELETE FROM users WHERE email = 'admin@saas-platform.com' AND role = 'superadmin';
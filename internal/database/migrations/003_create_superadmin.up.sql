@@ -0,0 +1,6 @@
+-- Create default superadmin
+-- Email: admin@saas-platform.com
+-- Password: Admin123!
+INSERT INTO users (email, password_hash, role, name)
+VALUES ('admin@saas-platform.com', '$2a$10$FJSfHsYVwikMJXLQrPblneaQx3djWUNKGjAg82jFOZfanqrfx4upO', 'superadmin', 'Super Admin')
+ON CONFLICT (email) DO NOTHING;
@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT false;
+
+-- 003_create_superadmin seeded a superadmin with publicly documented
+-- credentials. Where that password is still set it is revoked, so nobody can
+-- sign in with it, and the account has to pick a new password, for example
+-- through a password reset; create the first superadmin with
+-- "bootstrap-admin" instead.
+UPDATE users
+SET password_hash = '',
+	must_change_password = true,
+	updated_at = CURRENT_TIMESTAMP
+WHERE email = 'admin@saas-platform.com'
+	AND password_hash = '$2a$10$FJSfHsYVwikMJXLQrPblneaQx3djWUNKGjAg82jFOZfanqrfx4upO';
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id VARCHAR(64) PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	user_agent VARCHAR(500),
+	ip_address VARCHAR(64),
+	expires_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- Every refresh token family becomes a session
+INSERT INTO sessions (id, user_id, expires_at, last_used_at, revoked_at, created_at)
+SELECT family_id,
+	MIN(user_id),
+	MAX(expires_at),
+	MAX(created_at),
+	CASE WHEN bool_and(revoked_at IS NOT NULL) THEN MAX(revoked_at) END,
+	MIN(created_at)
+FROM refresh_tokens
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE refresh_tokens
+	ADD CONSTRAINT fk_refresh_tokens_session
+	FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
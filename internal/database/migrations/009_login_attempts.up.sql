@@ -0,0 +1,19 @@
+-- Failed login counters, keyed by "email:<address>" or "ip:<address>"
+CREATE TABLE IF NOT EXISTS login_attempts (
+	key VARCHAR(320) PRIMARY KEY,
+	failures INTEGER NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMP NOT NULL,
+	locked_until TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS security_events (
+	id BIGSERIAL PRIMARY KEY,
+	event_type VARCHAR(64) NOT NULL,
+	user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	email VARCHAR(255),
+	ip_address VARCHAR(64),
+	details TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_created_at ON security_events(created_at);
@@ -0,0 +1,13 @@
+-- Keys for access tokens. The key with retired_at NULL signs new tokens;
+-- retired keys keep verifying until expires_at.
+CREATE TABLE IF NOT EXISTS signing_keys (
+	kid VARCHAR(64) PRIMARY KEY,
+	algorithm VARCHAR(16) NOT NULL,
+	private_key TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	retired_at TIMESTAMP,
+	expires_at TIMESTAMP
+);
+
+-- At most one active signing key, even when several replicas start at once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_active ON signing_keys ((retired_at IS NULL)) WHERE retired_at IS NULL;
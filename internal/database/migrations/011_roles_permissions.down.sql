@@ -0,0 +1,13 @@
+CREATE TYPE user_role AS ENUM ('user', 'admin', 'superadmin');
+
+-- Users with custom roles fall back to the least privileged one
+UPDATE users SET role = 'user' WHERE role NOT IN ('user', 'admin', 'superadmin');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_role;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
@@ -0,0 +1,51 @@
+-- Named permissions; "*" grants every permission, including ones added later
+CREATE TABLE IF NOT EXISTS permissions (
+	name VARCHAR(64) PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT ''
+);
+
+-- Roles are permission sets. System roles cannot be deleted.
+CREATE TABLE IF NOT EXISTS roles (
+	name VARCHAR(64) PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	is_system BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+	role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+	permission VARCHAR(64) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+	PRIMARY KEY (role, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+	('*', 'Every permission'),
+	('restaurants:read', 'View restaurants'),
+	('restaurants:write', 'Create, update and delete restaurants'),
+	('users:manage', 'Assign roles to users'),
+	('roles:manage', 'Create and edit roles'),
+	('security:manage', 'Unlock locked-out logins')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description, is_system) VALUES
+	('user', 'Regular user', TRUE),
+	('admin', 'Platform administrator', TRUE),
+	('superadmin', 'Full access', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+	('admin', 'restaurants:read'),
+	('admin', 'restaurants:write'),
+	('admin', 'users:manage'),
+	('admin', 'security:manage'),
+	('superadmin', '*')
+ON CONFLICT DO NOTHING;
+
+-- users.role now references roles instead of the user_role enum
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(64) USING role::text;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name);
+
+DROP TYPE IF EXISTS user_role;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS restaurant_members (
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'manager', 'staff')),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (restaurant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_restaurant_members_user_id ON restaurant_members(user_id);
+
+-- Invitations are addressed to an email and accepted by the account that has
+-- verified it
+CREATE TABLE IF NOT EXISTS restaurant_invitations (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	email VARCHAR(255) NOT NULL,
+	role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'manager', 'staff')),
+	status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'revoked')),
+	invited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	expires_at TIMESTAMP NOT NULL,
+	responded_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurant_invitations_pending ON restaurant_invitations(restaurant_id, email) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_restaurant_invitations_email ON restaurant_invitations(email) WHERE status = 'pending';
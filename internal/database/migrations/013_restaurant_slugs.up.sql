@@ -0,0 +1,35 @@
+-- Same rules as slugify() in the restaurants module: Russian letters are
+-- transliterated, everything else outside [a-z0-9] becomes a dash
+CREATE OR REPLACE FUNCTION pg_temp.slugify(value TEXT) RETURNS TEXT AS $$
+	SELECT trim(BOTH '-' FROM regexp_replace(
+		lower(translate(
+			replace(replace(replace(replace(replace(replace(replace(replace(
+			replace(replace(replace(replace(replace(replace(replace(replace(
+				value,
+				'Щ', 'Shch'), 'щ', 'shch'), 'Ж', 'Zh'), 'ж', 'zh'), 'Х', 'Kh'), 'х', 'kh'),
+				'Ц', 'Ts'), 'ц', 'ts'), 'Ч', 'Ch'), 'ч', 'ch'), 'Ш', 'Sh'), 'ш', 'sh'),
+				'Ю', 'Yu'), 'ю', 'yu'), 'Я', 'Ya'), 'я', 'ya'),
+			'АБВГДЕЁЗИЙКЛМНОПРСТУФЫЭабвгдеёзийклмнопрстуфыэЪЬъь',
+			'ABVGDEEZIYKLMNOPRSTUFYEabvgdeeziyklmnoprstufye'
+		)),
+		'[^a-z0-9]+', '-', 'g'
+	))
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+
+-- Duplicates and names without usable characters get the id appended
+UPDATE restaurants r
+SET slug = CASE
+		WHEN s.base = '' THEN 'restaurant-' || r.id
+		WHEN s.rn > 1 THEN s.base || '-' || r.id
+		ELSE s.base
+	END
+FROM (
+	SELECT id, base, row_number() OVER (PARTITION BY base ORDER BY id) AS rn
+	FROM (SELECT id, trim(BOTH '-' FROM left(pg_temp.slugify(name), 200)) AS base FROM restaurants) b
+) s
+WHERE s.id = r.id AND r.slug IS NULL;
+
+ALTER TABLE restaurants ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurants_slug ON restaurants(slug);
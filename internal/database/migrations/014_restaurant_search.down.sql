@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_restaurants_created_at;
+DROP INDEX IF EXISTS idx_restaurants_cuisines;
+DROP INDEX IF EXISTS idx_restaurants_search;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS cuisines;
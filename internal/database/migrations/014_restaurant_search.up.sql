@@ -0,0 +1,16 @@
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS cuisines TEXT[] NOT NULL DEFAULT '{}';
+
+-- Indexed in both Russian and English so that either language matches word
+-- forms; the query side ORs the two parses (see restaurants.Repository)
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+	setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+	setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+	setweight(to_tsvector('russian', coalesce(address, '')), 'C') ||
+	setweight(to_tsvector('english', coalesce(address, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_restaurants_search ON restaurants USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_restaurants_cuisines ON restaurants USING GIN (cuisines);
+CREATE INDEX IF NOT EXISTS idx_restaurants_created_at ON restaurants(created_at);
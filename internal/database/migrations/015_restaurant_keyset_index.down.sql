@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_restaurants_created_at_id;
+CREATE INDEX IF NOT EXISTS idx_restaurants_created_at ON restaurants(created_at);
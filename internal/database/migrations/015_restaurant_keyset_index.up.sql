@@ -0,0 +1,3 @@
+-- Cursor pagination seeks on (created_at, id) in either direction
+DROP INDEX IF EXISTS idx_restaurants_created_at;
+CREATE INDEX IF NOT EXISTS idx_restaurants_created_at_id ON restaurants(created_at, id);
@@ -0,0 +1,13 @@
+DROP FUNCTION IF EXISTS geo_polygon_contains(JSONB, DOUBLE PRECISION, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS geo_distance_m(DOUBLE PRECISION, DOUBLE PRECISION, DOUBLE PRECISION, DOUBLE PRECISION);
+DROP INDEX IF EXISTS idx_restaurants_location;
+ALTER TABLE restaurants DROP CONSTRAINT IF EXISTS chk_restaurants_delivery_radius;
+ALTER TABLE restaurants DROP CONSTRAINT IF EXISTS chk_restaurants_location;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS delivery_polygon;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS delivery_radius_m;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS country;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS postcode;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS city;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS street;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS longitude;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS latitude;
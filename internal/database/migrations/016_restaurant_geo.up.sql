@@ -0,0 +1,46 @@
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS street VARCHAR(255);
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS city VARCHAR(100);
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS postcode VARCHAR(20);
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS country CHAR(2);
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS delivery_radius_m INTEGER;
+-- Array of {"lat": ..., "lng": ...} vertices; takes precedence over the radius
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS delivery_polygon JSONB;
+
+ALTER TABLE restaurants ADD CONSTRAINT chk_restaurants_location CHECK (
+	(latitude IS NULL) = (longitude IS NULL)
+	AND latitude BETWEEN -90 AND 90
+	AND longitude BETWEEN -180 AND 180
+);
+ALTER TABLE restaurants ADD CONSTRAINT chk_restaurants_delivery_radius CHECK (delivery_radius_m > 0);
+
+-- Bounding boxes narrow "near me" queries before distances are computed
+CREATE INDEX IF NOT EXISTS idx_restaurants_location ON restaurants(latitude, longitude) WHERE latitude IS NOT NULL;
+
+-- Great-circle distance in meters (haversine on a spherical Earth), so that
+-- no PostGIS or earthdistance extension is needed
+CREATE OR REPLACE FUNCTION geo_distance_m(lat1 DOUBLE PRECISION, lng1 DOUBLE PRECISION, lat2 DOUBLE PRECISION, lng2 DOUBLE PRECISION)
+RETURNS DOUBLE PRECISION
+LANGUAGE SQL IMMUTABLE STRICT PARALLEL SAFE AS $$
+	SELECT 2 * 6371008.8 * asin(least(1, sqrt(
+		power(sin(radians(lat2 - lat1) / 2), 2) +
+		cos(radians(lat1)) * cos(radians(lat2)) * power(sin(radians(lng2 - lng1) / 2), 2)
+	)))
+$$;
+
+-- Ray casting point-in-polygon test over a delivery_polygon value. Polygons
+-- are small enough for plane geometry on lat/lng to be accurate.
+CREATE OR REPLACE FUNCTION geo_polygon_contains(polygon JSONB, lat DOUBLE PRECISION, lng DOUBLE PRECISION)
+RETURNS BOOLEAN
+LANGUAGE SQL IMMUTABLE STRICT PARALLEL SAFE AS $$
+	WITH vertices AS (
+		SELECT (v->>'lat')::DOUBLE PRECISION AS y, (v->>'lng')::DOUBLE PRECISION AS x, i
+		FROM jsonb_array_elements(polygon) WITH ORDINALITY AS e(v, i)
+	)
+	SELECT count(*) % 2 = 1
+	FROM vertices a
+	JOIN vertices b ON b.i = a.i % (SELECT count(*) FROM vertices) + 1
+	WHERE (a.y > lat) <> (b.y > lat)
+		AND lng < (b.x - a.x) * (lat - a.y) / NULLIF(b.y - a.y, 0) + a.x
+$$;
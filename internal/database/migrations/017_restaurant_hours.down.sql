@@ -0,0 +1,4 @@
+DROP FUNCTION IF EXISTS restaurant_open_at(BIGINT, TEXT, TIMESTAMPTZ);
+DROP TABLE IF EXISTS restaurant_hour_exceptions;
+DROP TABLE IF EXISTS restaurant_opening_hours;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS time_zone;
@@ -0,0 +1,60 @@
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Weekly hours; weekday 0 is Sunday. An interval whose closes_at is not after
+-- opens_at ends on the next day, so 22:00-02:00 crosses midnight and
+-- 00:00-00:00 is open around the clock.
+CREATE TABLE IF NOT EXISTS restaurant_opening_hours (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+	opens_at TIME NOT NULL,
+	closes_at TIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_restaurant_opening_hours_restaurant ON restaurant_opening_hours(restaurant_id, weekday);
+
+-- Dated exceptions replace the weekly hours of their day. A row without
+-- times closes the restaurant for the whole day.
+CREATE TABLE IF NOT EXISTS restaurant_hour_exceptions (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	date DATE NOT NULL,
+	opens_at TIME,
+	closes_at TIME,
+	note VARCHAR(255),
+	CHECK ((opens_at IS NULL) = (closes_at IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_restaurant_hour_exceptions_restaurant ON restaurant_hour_exceptions(restaurant_id, date);
+
+-- Whether a restaurant is open at a moment, following the same rules as
+-- restaurants.schedule in Go. Intervals starting the day before are checked
+-- for the part after midnight.
+CREATE OR REPLACE FUNCTION restaurant_open_at(rid BIGINT, tz TEXT, at TIMESTAMPTZ)
+RETURNS BOOLEAN
+LANGUAGE SQL STABLE AS $$
+	WITH local AS (
+		SELECT at AT TIME ZONE tz AS t
+	), days AS (
+		SELECT (local.t::date - back) AS day FROM local, (VALUES (0), (1)) AS v(back)
+	), intervals AS (
+		SELECT days.day, e.opens_at, e.closes_at
+		FROM days
+		JOIN restaurant_hour_exceptions e ON e.restaurant_id = rid AND e.date = days.day
+		UNION ALL
+		SELECT days.day, h.opens_at, h.closes_at
+		FROM days
+		JOIN restaurant_opening_hours h ON h.restaurant_id = rid AND h.weekday = EXTRACT(DOW FROM days.day)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM restaurant_hour_exceptions e WHERE e.restaurant_id = rid AND e.date = days.day
+		)
+	)
+	SELECT EXISTS (
+		SELECT 1
+		FROM intervals, local
+		WHERE intervals.opens_at IS NOT NULL
+			AND local.t >= intervals.day + intervals.opens_at
+			AND local.t < intervals.day + intervals.closes_at
+				+ CASE WHEN intervals.closes_at <= intervals.opens_at THEN INTERVAL '1 day' ELSE INTERVAL '0' END
+	)
+$$;
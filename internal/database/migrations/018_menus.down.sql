@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS menu_modifier_options;
+DROP TABLE IF EXISTS menu_modifier_groups;
+DROP TABLE IF EXISTS menu_items;
+DROP TABLE IF EXISTS menu_categories;
@@ -0,0 +1,61 @@
+-- Every menu table carries restaurant_id so that rows can be scoped to the
+-- restaurant in the URL with a single condition.
+CREATE TABLE IF NOT EXISTS menu_categories (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_categories_restaurant ON menu_categories(restaurant_id, position);
+
+-- Prices are in minor units of currency (kopecks, cents)
+CREATE TABLE IF NOT EXISTS menu_items (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	category_id BIGINT NOT NULL REFERENCES menu_categories(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	image_url VARCHAR(500),
+	price BIGINT NOT NULL CHECK (price >= 0),
+	currency CHAR(3) NOT NULL,
+	is_available BOOLEAN NOT NULL DEFAULT true,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_restaurant ON menu_items(restaurant_id, category_id, position);
+
+CREATE TABLE IF NOT EXISTS menu_modifier_groups (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	item_id BIGINT NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	min_selections INTEGER NOT NULL DEFAULT 0 CHECK (min_selections >= 0),
+	max_selections INTEGER NOT NULL DEFAULT 1 CHECK (max_selections >= 1),
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (min_selections <= max_selections)
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_modifier_groups_restaurant ON menu_modifier_groups(restaurant_id, item_id, position);
+
+-- Option prices are added to the item price, in the item's currency
+CREATE TABLE IF NOT EXISTS menu_modifier_options (
+	id BIGSERIAL PRIMARY KEY,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	group_id BIGINT NOT NULL REFERENCES menu_modifier_groups(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	price BIGINT NOT NULL DEFAULT 0 CHECK (price >= 0),
+	is_available BOOLEAN NOT NULL DEFAULT true,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_modifier_options_restaurant ON menu_modifier_options(restaurant_id, group_id, position);
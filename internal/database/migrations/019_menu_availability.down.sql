@@ -0,0 +1,7 @@
+DROP FUNCTION IF EXISTS restaurant_next_midnight(BIGINT);
+DROP FUNCTION IF EXISTS restaurant_local_date(BIGINT);
+ALTER TABLE menu_items DROP COLUMN IF EXISTS stock_date;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS stock_remaining;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS daily_stock;
+ALTER TABLE menu_modifier_options DROP COLUMN IF EXISTS available_at;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS available_at;
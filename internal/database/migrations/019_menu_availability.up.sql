@@ -0,0 +1,25 @@
+-- An unavailable item or option with available_at becomes available again
+-- at that moment (UTC) without anyone touching it.
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS available_at TIMESTAMP;
+ALTER TABLE menu_modifier_options ADD COLUMN IF NOT EXISTS available_at TIMESTAMP;
+
+-- Daily stock: stock_remaining counts down for stock_date, the restaurant's
+-- local date. A counter from an earlier day reads as a full daily_stock.
+-- NULL daily_stock means unlimited.
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS daily_stock INTEGER CHECK (daily_stock >= 0);
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS stock_remaining INTEGER;
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS stock_date DATE;
+
+CREATE OR REPLACE FUNCTION restaurant_local_date(rid BIGINT)
+RETURNS DATE
+LANGUAGE SQL STABLE AS $$
+	SELECT (CURRENT_TIMESTAMP AT TIME ZONE time_zone)::date FROM restaurants WHERE id = rid
+$$;
+
+-- The next local midnight of a restaurant, as a UTC timestamp
+CREATE OR REPLACE FUNCTION restaurant_next_midnight(rid BIGINT)
+RETURNS TIMESTAMP
+LANGUAGE SQL STABLE AS $$
+	SELECT ((date_trunc('day', CURRENT_TIMESTAMP AT TIME ZONE time_zone) + INTERVAL '1 day') AT TIME ZONE time_zone) AT TIME ZONE 'UTC'
+	FROM restaurants WHERE id = rid
+$$;
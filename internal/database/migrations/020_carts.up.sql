@@ -0,0 +1,28 @@
+-- A customer has at most one active cart per restaurant. Carts that turned
+-- into orders keep their rows with another status.
+CREATE TABLE IF NOT EXISTS carts (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'ordered')),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_active ON carts(user_id, restaurant_id) WHERE status = 'active';
+
+-- Lines hold no prices: they are worked out from the current menu whenever
+-- the cart is read. item_id and option_ids (sorted) have no foreign keys, so
+-- a line whose dish left the menu stays and shows why it cannot be ordered.
+CREATE TABLE IF NOT EXISTS cart_lines (
+	id BIGSERIAL PRIMARY KEY,
+	cart_id BIGINT NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+	item_id BIGINT NOT NULL,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	option_ids BIGINT[] NOT NULL DEFAULT '{}',
+	notes TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_lines_cart_id ON cart_lines(cart_id);
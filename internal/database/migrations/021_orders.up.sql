@@ -0,0 +1,48 @@
+-- Orders keep a snapshot of names and prices at checkout, so later menu
+-- changes do not alter them. Amounts are in minor units of currency.
+CREATE TABLE IF NOT EXISTS orders (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	restaurant_id BIGINT NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+	cart_id BIGINT UNIQUE REFERENCES carts(id) ON DELETE SET NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'placed' CHECK (status IN (
+		'placed', 'accepted', 'preparing', 'ready', 'picked_up', 'delivered', 'rejected', 'cancelled'
+	)),
+	currency CHAR(3) NOT NULL,
+	subtotal BIGINT NOT NULL CHECK (subtotal >= 0),
+	total BIGINT NOT NULL CHECK (total >= 0),
+	notes TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user ON orders(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant ON orders(restaurant_id, created_at DESC, id DESC);
+
+-- options is a snapshot of the chosen modifier options: [{"id", "name", "price"}]
+CREATE TABLE IF NOT EXISTS order_lines (
+	id BIGSERIAL PRIMARY KEY,
+	order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	item_id BIGINT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	unit_price BIGINT NOT NULL CHECK (unit_price >= 0),
+	total BIGINT NOT NULL CHECK (total >= 0),
+	options JSONB NOT NULL DEFAULT '[]',
+	notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_lines_order_id ON order_lines(order_id);
+
+-- Every status change, starting with placing the order (from_status NULL)
+CREATE TABLE IF NOT EXISTS order_events (
+	id BIGSERIAL PRIMARY KEY,
+	order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	from_status VARCHAR(16),
+	to_status VARCHAR(16) NOT NULL,
+	actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	reason TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_order_id ON order_events(order_id, id);
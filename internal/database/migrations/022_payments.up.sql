@@ -0,0 +1,38 @@
+-- A payment is one attempt to pay for an order through a provider. Amounts
+-- are in minor units; captured and refunded amounts follow the ledger.
+CREATE TABLE IF NOT EXISTS payments (
+	id BIGSERIAL PRIMARY KEY,
+	order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	provider VARCHAR(32) NOT NULL,
+	provider_payment_id VARCHAR(255) NOT NULL,
+	status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'authorized', 'captured', 'voided', 'failed')),
+	amount BIGINT NOT NULL CHECK (amount > 0),
+	captured_amount BIGINT NOT NULL DEFAULT 0 CHECK (captured_amount >= 0),
+	refunded_amount BIGINT NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0),
+	currency CHAR(3) NOT NULL,
+	client_secret TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (provider, provider_payment_id),
+	CHECK (captured_amount <= amount AND refunded_amount <= captured_amount)
+);
+
+-- At most one payment per order is in progress or done
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_order_open ON payments(order_id)
+	WHERE status IN ('pending', 'authorized', 'captured');
+
+-- Append-only record of money movements. Amounts are positive; entry_type
+-- gives the direction. idempotency_key makes retried operations record once.
+CREATE TABLE IF NOT EXISTS payment_ledger (
+	id BIGSERIAL PRIMARY KEY,
+	payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+	order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	entry_type VARCHAR(16) NOT NULL CHECK (entry_type IN ('authorization', 'capture', 'refund', 'void')),
+	amount BIGINT NOT NULL CHECK (amount >= 0),
+	currency CHAR(3) NOT NULL,
+	provider_reference VARCHAR(255),
+	idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_ledger_order_id ON payment_ledger(order_id, id);
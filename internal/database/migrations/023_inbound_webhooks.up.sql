@@ -0,0 +1,20 @@
+-- Every webhook a payment provider sends, stored as received before it is
+-- handled. A provider may deliver an event more than once; the event ID keeps
+-- one copy.
+CREATE TABLE IF NOT EXISTS inbound_webhooks (
+	id BIGSERIAL PRIMARY KEY,
+	provider VARCHAR(32) NOT NULL,
+	event_id VARCHAR(255) NOT NULL,
+	event_type VARCHAR(128) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processed', 'failed', 'ignored')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	processed_at TIMESTAMP,
+	UNIQUE (provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_webhooks_due ON inbound_webhooks(next_attempt_at)
+	WHERE status = 'pending';
@@ -0,0 +1,34 @@
+-- A refund of some or all of a captured payment, for whole order lines or an
+-- amount. Pending refunds count against what is left to refund, so that two
+-- refunds issued at once cannot return more than was captured.
+CREATE TABLE IF NOT EXISTS refunds (
+	id BIGSERIAL PRIMARY KEY,
+	order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+	amount BIGINT NOT NULL CHECK (amount > 0),
+	currency CHAR(3) NOT NULL,
+	reason_code VARCHAR(32) NOT NULL CHECK (reason_code IN ('missing_item', 'wrong_item', 'quality_issue', 'late_delivery', 'goodwill', 'other')),
+	note TEXT,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+	provider_refund_id VARCHAR(255),
+	failure_reason TEXT,
+	idempotency_key VARCHAR(255),
+	created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (order_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_payment_id ON refunds(payment_id);
+
+-- The order lines a refund is for, if it is for lines
+CREATE TABLE IF NOT EXISTS refund_lines (
+	id BIGSERIAL PRIMARY KEY,
+	refund_id BIGINT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+	order_line_id BIGINT NOT NULL REFERENCES order_lines(id) ON DELETE CASCADE,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	amount BIGINT NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_refund_lines_refund_id ON refund_lines(refund_id);
+CREATE INDEX IF NOT EXISTS idx_refund_lines_order_line_id ON refund_lines(order_line_id);
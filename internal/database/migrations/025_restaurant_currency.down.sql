@@ -0,0 +1 @@
+ALTER TABLE restaurants DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,12 @@
+-- Every price of a restaurant is in its currency. Existing restaurants take
+-- the currency most of their dishes are priced in.
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'EUR';
+
+UPDATE restaurants r SET currency = (
+	SELECT i.currency FROM menu_items i
+	WHERE i.restaurant_id = r.id
+	GROUP BY i.currency
+	ORDER BY COUNT(*) DESC, i.currency
+	LIMIT 1
+)
+WHERE EXISTS (SELECT 1 FROM menu_items i WHERE i.restaurant_id = r.id);
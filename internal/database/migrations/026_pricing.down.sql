@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS breakdown;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS tax_rate_bp;
+ALTER TABLE menu_categories DROP COLUMN IF EXISTS tax_rate_bp;
+DROP TABLE IF EXISTS restaurant_pricing;
@@ -0,0 +1,21 @@
+-- Tax and fee settings of a restaurant. Amounts are in minor units of its
+-- currency and rates in basis points. A restaurant without a row charges no
+-- tax or fees.
+CREATE TABLE IF NOT EXISTS restaurant_pricing (
+	restaurant_id BIGINT PRIMARY KEY REFERENCES restaurants(id) ON DELETE CASCADE,
+	prices_include_tax BOOLEAN NOT NULL DEFAULT TRUE,
+	tax_rate_bp INTEGER NOT NULL DEFAULT 0 CHECK (tax_rate_bp BETWEEN 0 AND 10000),
+	service_fee_bp INTEGER NOT NULL DEFAULT 0 CHECK (service_fee_bp BETWEEN 0 AND 10000),
+	service_fee_min BIGINT NOT NULL DEFAULT 0 CHECK (service_fee_min >= 0),
+	service_fee_max BIGINT CHECK (service_fee_max >= service_fee_min),
+	minimum_order BIGINT NOT NULL DEFAULT 0 CHECK (minimum_order >= 0),
+	delivery_tiers JSONB NOT NULL DEFAULT '[]',
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A category's own tax rate, such as a lower VAT rate on food than on drinks
+ALTER TABLE menu_categories ADD COLUMN IF NOT EXISTS tax_rate_bp INTEGER CHECK (tax_rate_bp BETWEEN 0 AND 10000);
+
+-- Orders keep the rates and fees they were placed with
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS tax_rate_bp INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS breakdown JSONB;
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_restaurant_id_fkey;
+ALTER TABLE orders ADD CONSTRAINT orders_restaurant_id_fkey
+	FOREIGN KEY (restaurant_id) REFERENCES restaurants(id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+-- Orders are the history of what was sold and paid for, so deleting a
+-- restaurant must not take them along. A restaurant with orders is
+-- deactivated instead.
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_restaurant_id_fkey;
+ALTER TABLE orders ADD CONSTRAINT orders_restaurant_id_fkey
+	FOREIGN KEY (restaurant_id) REFERENCES restaurants(id) ON DELETE RESTRICT;
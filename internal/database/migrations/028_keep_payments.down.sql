@@ -0,0 +1,11 @@
+ALTER TABLE payment_ledger DROP CONSTRAINT IF EXISTS payment_ledger_order_id_fkey;
+ALTER TABLE payment_ledger ADD CONSTRAINT payment_ledger_order_id_fkey
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
+
+ALTER TABLE payment_ledger DROP CONSTRAINT IF EXISTS payment_ledger_payment_id_fkey;
+ALTER TABLE payment_ledger ADD CONSTRAINT payment_ledger_payment_id_fkey
+	FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_order_id_fkey;
+ALTER TABLE payments ADD CONSTRAINT payments_order_id_fkey
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
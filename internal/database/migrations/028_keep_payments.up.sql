@@ -0,0 +1,13 @@
+-- Payments and the ledger are append-only money history: deleting an order
+-- or a payment must fail rather than take them along.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_order_id_fkey;
+ALTER TABLE payments ADD CONSTRAINT payments_order_id_fkey
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT;
+
+ALTER TABLE payment_ledger DROP CONSTRAINT IF EXISTS payment_ledger_payment_id_fkey;
+ALTER TABLE payment_ledger ADD CONSTRAINT payment_ledger_payment_id_fkey
+	FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE RESTRICT;
+
+ALTER TABLE payment_ledger DROP CONSTRAINT IF EXISTS payment_ledger_order_id_fkey;
+ALTER TABLE payment_ledger ADD CONSTRAINT payment_ledger_order_id_fkey
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT;
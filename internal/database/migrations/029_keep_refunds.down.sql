@@ -0,0 +1,15 @@
+ALTER TABLE refund_lines DROP CONSTRAINT IF EXISTS refund_lines_order_line_id_fkey;
+ALTER TABLE refund_lines ADD CONSTRAINT refund_lines_order_line_id_fkey
+	FOREIGN KEY (order_line_id) REFERENCES order_lines(id) ON DELETE CASCADE;
+
+ALTER TABLE refund_lines DROP CONSTRAINT IF EXISTS refund_lines_refund_id_fkey;
+ALTER TABLE refund_lines ADD CONSTRAINT refund_lines_refund_id_fkey
+	FOREIGN KEY (refund_id) REFERENCES refunds(id) ON DELETE CASCADE;
+
+ALTER TABLE refunds DROP CONSTRAINT IF EXISTS refunds_payment_id_fkey;
+ALTER TABLE refunds ADD CONSTRAINT refunds_payment_id_fkey
+	FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE;
+
+ALTER TABLE refunds DROP CONSTRAINT IF EXISTS refunds_order_id_fkey;
+ALTER TABLE refunds ADD CONSTRAINT refunds_order_id_fkey
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
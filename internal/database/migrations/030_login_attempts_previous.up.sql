@@ -0,0 +1,3 @@
+-- Attempts are now counted before the password is checked, and the backoff
+-- runs from the attempt before the current one
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS previous_failure_at TIMESTAMP;
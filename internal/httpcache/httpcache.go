@@ -0,0 +1,62 @@
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicMaxAge is how long clients and shared caches may reuse a public
+// response without revalidating it.
+const PublicMaxAge = 60 * time.Second
+
+// SetPublic marks a response as cacheable by anyone for PublicMaxAge.
+func SetPublic(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(PublicMaxAge.Seconds())))
+}
+
+// NotModified sets the caching headers of a public response and answers 304
+// when the client's copy is still current. If-None-Match takes precedence
+// over If-Modified-Since, as in RFC 9110. A zero lastModified is not sent.
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	SetPublic(c)
+	return validate(c, etag, lastModified)
+}
+
+// NotModifiedNoCache is NotModified for public responses that may change at
+// any moment: caches keep them but revalidate on every use.
+func NotModifiedNoCache(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("Cache-Control", "public, no-cache")
+	return validate(c, etag, lastModified)
+}
+
+func validate(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				c.Status(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		// HTTP dates have second precision
+		if t, err := http.ParseTime(since); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
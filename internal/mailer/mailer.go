@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"fmt"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // Plain text
+}
+
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// New returns the mailer selected by MAIL_DRIVER: "smtp" for real delivery,
+// "log" (the default) for local development and tests.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		return NewSMTPMailer(cfg), nil
+	case "log", "":
+		return NewLogMailer(cfg.FileDir, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
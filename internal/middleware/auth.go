@@ -9,7 +9,16 @@ import (
 	"github.com/yourcompany/saas-platform/internal/modules/auth"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// Authenticator checks the signature of an access token against the published
+// key set and whether the session behind it has been revoked, e.g. by logging
+// out, and resolves the permissions of its role.
+type Authenticator interface {
+	VerifyAccessToken(token string) (*auth.Claims, error)
+	IsSessionRevoked(sessionID string) (bool, error)
+	RolePermissions(role string) (auth.Permissions, error)
+}
+
+func AuthMiddleware(authenticator Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,42 +36,78 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		token := parts[1]
-		claims, err := auth.ValidateToken(token, jwtSecret)
+		claims, err := authenticator.VerifyAccessToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		// Tokens bound to a revoked session are rejected before they expire
+		if claims.SessionID != "" {
+			revoked, err := authenticator.IsSessionRevoked(claims.SessionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify session"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Permissions are resolved per request, so role edits apply without
+		// reissuing tokens
+		permissions, err := authenticator.RolePermissions(claims.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve permissions"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("user_permissions", permissions)
+		c.Set("must_change_password", claims.MustChangePassword)
+		c.Set("session_id", claims.SessionID)
+		c.Set("email_verified", claims.EmailVerified)
+		c.Set("mfa_setup_required", claims.MFASetupRequired)
 
 		c.Next()
 	}
 }
 
-func RequireRole(requiredRole string) gin.HandlerFunc {
+// RequirePermission allows the request only if the user's role grants every
+// listed permission.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("user_role")
-		if !exists {
+		granted, ok := c.Get("user_permissions")
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			c.Abort()
 			return
 		}
 
-		role := userRole.(string)
-		
-		// Superadmin has access to everything
-		if role == auth.RoleSuperAdmin {
-			c.Next()
+		if !granted.(auth.Permissions).Covers(permissions) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			c.Abort()
 			return
 		}
 
-		// Check if user has required role
-		if role != requiredRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		c.Next()
+	}
+}
+
+// RequirePasswordChanged blocks accounts that still have to replace their
+// initial password, such as a freshly bootstrapped superadmin.
+func RequirePasswordChanged() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("must_change_password") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "password change required"})
 			c.Abort()
 			return
 		}
@@ -71,6 +116,31 @@ func RequireRole(requiredRole string) gin.HandlerFunc {
 	}
 }
 
-func RequireSuperAdmin() gin.HandlerFunc {
-	return RequireRole(auth.RoleSuperAdmin)
+// RequireVerifiedEmail blocks users who have not verified their email address
+// yet. It is enabled with AUTH_REQUIRE_VERIFIED_EMAIL.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("email_verified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email verification required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireMFAEnrolled blocks accounts that must enroll in two-factor
+// authentication first (admins and superadmins when AUTH_REQUIRE_MFA_FOR_ADMINS
+// is set).
+func RequireMFAEnrolled() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("mfa_setup_required") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication setup required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
 }
@@ -7,19 +7,49 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const PurposeMFAChallenge = "mfa_challenge"
+
 type Claims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID             int64  `json:"user_id"`
+	Email              string `json:"email"`
+	Role               string `json:"role"`
+	MustChangePassword bool   `json:"must_change_password,omitempty"`
+	EmailVerified      bool   `json:"email_verified"`
+	MFASetupRequired   bool   `json:"mfa_setup_required,omitempty"`
+	Purpose            string `json:"purpose,omitempty"` // Empty for access and refresh tokens
+	SessionID          string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID int64, email, role, secret string, ttl time.Duration) (string, error) {
+// GenerateAccessToken signs with the active key of the key set, so that other
+// services can verify access tokens with the published public keys alone.
+func GenerateAccessToken(user *User, sessionID string, mfaSetupRequired bool, keys *KeySet, ttl time.Duration) (string, error) {
+	claims := &Claims{
+		UserID:             user.ID,
+		Email:              user.Email,
+		Role:               user.Role,
+		MustChangePassword: user.MustChangePassword,
+		EmailVerified:      user.EmailVerifiedAt != nil,
+		MFASetupRequired:   mfaSetupRequired,
+		SessionID:          sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keys.Sign(claims)
+}
+
+// Refresh and MFA challenge tokens are only ever read by this service, so they
+// stay HMAC-signed with secrets that are never shared.
+
+func GenerateRefreshToken(userID int64, email, tokenID, secret string, ttl time.Duration) (string, error) {
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
-		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -29,10 +59,12 @@ func GenerateAccessToken(userID int64, email, role, secret string, ttl time.Dura
 	return token.SignedString([]byte(secret))
 }
 
-func GenerateRefreshToken(userID int64, email, secret string, ttl time.Duration) (string, error) {
+// GenerateMFAChallengeToken proves that the password step of a login
+// succeeded. Its purpose claim keeps it from being accepted as an access token.
+func GenerateMFAChallengeToken(userID int64, secret string, ttl time.Duration) (string, error) {
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:  userID,
+		Purpose: PurposeMFAChallenge,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -43,7 +75,26 @@ func GenerateRefreshToken(userID int64, email, secret string, ttl time.Duration)
 	return token.SignedString([]byte(secret))
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
+// ValidateToken verifies an access token with the key named by its "kid"
+// header.
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyfunc,
+		jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}))
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// ValidateSecretToken verifies an HMAC-signed refresh or MFA challenge token.
+func ValidateSecretToken(tokenString, secret string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
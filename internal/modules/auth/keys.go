@@ -0,0 +1,373 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+// SigningKey is one entry of the key set. Only the active key signs; retired
+// keys keep verifying until ExpiresAt, when the last token they signed has
+// expired.
+type SigningKey struct {
+	ID         string // "kid" header, the RFC 7638 thumbprint of the public key
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+	ExpiresAt  *time.Time
+
+	stored string // PrivateKey as kept in the database, sealed by keyCipher
+}
+
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// KeySet holds the keys used for access tokens. It is swapped as a whole when
+// keys are reloaded, so readers never see a partial update.
+type KeySet struct {
+	mu       sync.RWMutex
+	signing  *SigningKey
+	keys     map[string]*SigningKey
+	loadedAt time.Time
+}
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*SigningKey)}
+}
+
+func (ks *KeySet) replace(keys []*SigningKey) error {
+	var signing *SigningKey
+	byID := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		byID[key.ID] = key
+		if key.RetiredAt == nil {
+			signing = key
+		}
+	}
+
+	if signing == nil {
+		return errors.New("no active signing key")
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.signing = signing
+	ks.keys = byID
+	ks.loadedAt = time.Now()
+	return nil
+}
+
+func (ks *KeySet) LoadedAt() time.Time {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.loadedAt
+}
+
+// Keys returns every key in the set, the active one included.
+func (ks *KeySet) Keys() []*SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Sign signs claims with the active key and sets the "kid" header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.signing
+	ks.mu.RUnlock()
+
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// keyfunc selects the verification key by the token's "kid" header.
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, errors.New("signing key has expired")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return key.PublicKey(), nil
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key that still verifies tokens.
+func (ks *KeySet) JWKS() *JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	set := &JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+			continue
+		}
+		jwk, err := publicJWK(key.PublicKey())
+		if err != nil {
+			continue
+		}
+		jwk.KeyID = key.ID
+		jwk.Use = "sig"
+		jwk.Algorithm = key.Algorithm
+		set.Keys = append(set.Keys, *jwk)
+	}
+
+	return set
+}
+
+func hasActiveKey(keys []*SigningKey) bool {
+	for _, key := range keys {
+		if key.RetiredAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSigningKey(algorithm string) (*SigningKey, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		private = key
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	kid, err := keyThumbprint(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{ID: kid, Algorithm: algorithm, PrivateKey: private}, nil
+}
+
+func encodePrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// Sealed private keys are stored as this prefix and the base64 of the nonce
+// followed by the ciphertext. Keys written before encryption are plain PEM.
+const sealedKeyPrefix = "v1:"
+
+const minKeyEncryptionKeyLength = 32
+
+// keyCipher encrypts private signing keys at rest with AES-256-GCM. The kid is
+// authenticated along with the key, so a sealed key cannot be moved to
+// another row.
+type keyCipher struct {
+	aead cipher.AEAD
+}
+
+func newKeyCipher(secret string) (*keyCipher, error) {
+	if len(secret) < minKeyEncryptionKeyLength {
+		return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must be at least %d characters long", minKeyEncryptionKeyLength)
+	}
+
+	sum := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key cipher: %w", err)
+	}
+
+	return &keyCipher{aead: aead}, nil
+}
+
+// seal sets key.stored to the encrypted private key.
+func (c *keyCipher) seal(key *SigningKey) error {
+	plaintext, err := encodePrivateKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(key.ID))
+	key.stored = sealedKeyPrefix + base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+// open sets key.PrivateKey from key.stored and reports whether it was stored
+// in plain text.
+func (c *keyCipher) open(key *SigningKey) (bool, error) {
+	if !strings.HasPrefix(key.stored, sealedKeyPrefix) {
+		private, err := decodePrivateKey(key.stored)
+		if err != nil {
+			return false, err
+		}
+		key.PrivateKey = private
+		return true, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(key.stored, sealedKeyPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return false, errors.New("invalid sealed private key")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(key.ID))
+	if err != nil {
+		return false, errors.New("failed to decrypt private key, check JWT_KEY_ENCRYPTION_KEY")
+	}
+
+	key.PrivateKey, err = decodePrivateKey(string(plaintext))
+	return false, err
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+func publicJWK(key crypto.PublicKey) (*JWK, error) {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return &JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return &JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+}
+
+// keyThumbprint computes the RFC 7638 JWK thumbprint: the hash of the
+// required members in lexicographic order.
+func keyThumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	var members interface{}
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+const testKeyEncryptionKey = "test-key-encryption-key-of-32-characters"
+
+func TestKeyCipher(t *testing.T) {
+	c, err := newKeyCipher(testKeyEncryptionKey)
+	if err != nil {
+		t.Fatalf("newKeyCipher: %v", err)
+	}
+
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := generateSigningKey(algorithm)
+			if err != nil {
+				t.Fatalf("generateSigningKey: %v", err)
+			}
+			if err := c.seal(key); err != nil {
+				t.Fatalf("seal: %v", err)
+			}
+			if !strings.HasPrefix(key.stored, sealedKeyPrefix) || strings.Contains(key.stored, "PRIVATE KEY") {
+				t.Fatalf("private key is not sealed: %q", key.stored)
+			}
+
+			loaded := &SigningKey{ID: key.ID, stored: key.stored}
+			plaintext, err := c.open(loaded)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if plaintext {
+				t.Error("sealed key reported as plain text")
+			}
+			if kid, _ := keyThumbprint(loaded.PublicKey()); kid != key.ID {
+				t.Error("opened key does not match the sealed one")
+			}
+
+			moved := &SigningKey{ID: "another-kid", stored: key.stored}
+			if _, err := c.open(moved); err == nil {
+				t.Error("a sealed key opened under another kid")
+			}
+
+			other, err := newKeyCipher(testKeyEncryptionKey + "-rotated")
+			if err != nil {
+				t.Fatalf("newKeyCipher: %v", err)
+			}
+			if _, err := other.open(&SigningKey{ID: key.ID, stored: key.stored}); err == nil {
+				t.Error("a sealed key opened with another encryption key")
+			}
+		})
+	}
+}
+
+func TestKeyCipherOpensPlainKeys(t *testing.T) {
+	c, err := newKeyCipher(testKeyEncryptionKey)
+	if err != nil {
+		t.Fatalf("newKeyCipher: %v", err)
+	}
+
+	key, err := generateSigningKey(AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("generateSigningKey: %v", err)
+	}
+	pem, err := encodePrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatalf("encodePrivateKey: %v", err)
+	}
+
+	loaded := &SigningKey{ID: key.ID, stored: pem}
+	plaintext, err := c.open(loaded)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !plaintext || loaded.PrivateKey == nil {
+		t.Error("plain PEM key was not loaded as plain text")
+	}
+
+	for _, stored := range []string{"", "garbage", sealedKeyPrefix + "not base64!", sealedKeyPrefix + "AAAA"} {
+		if _, err := c.open(&SigningKey{ID: key.ID, stored: stored}); err == nil {
+			t.Errorf("open(%q): expected an error", stored)
+		}
+	}
+}
+
+func TestNewKeyCipherRejectsShortKeys(t *testing.T) {
+	if _, err := newKeyCipher("too-short"); err == nil {
+		t.Error("expected an error for a short key")
+	}
+}
@@ -3,13 +3,51 @@ package auth
 import "time"
 
 type User struct {
-	ID           int64     `json:"id"`
-	Email        string    `json:"email"`
-	Name         *string   `json:"name"`
-	Role         string    `json:"role"`
-	PasswordHash string    `json:"-"` // Never return in JSON
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                 int64      `json:"id"`
+	Email              string     `json:"email"`
+	Name               *string    `json:"name"`
+	Role               string     `json:"role"`
+	PasswordHash       string     `json:"-"` // Never return in JSON
+	MustChangePassword bool       `json:"must_change_password"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled   bool       `json:"two_factor_enabled"`
+	TOTPSecret         string     `json:"-"`
+	TOTPEnabledAt      *time.Time `json:"-"`
+	TOTPLastStep       int64      `json:"-"`
+	Permissions        []string   `json:"permissions,omitempty"` // Resolved from the role, only for /me
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type RefreshTokenRecord struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string // Shared by every token rotated from the same login
+	TokenHash string
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Session is one signed-in device. Its ID is the refresh token family ID and
+// is carried in access tokens as the "sid" claim.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int64      `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt time.Time  `json:"last_used_at"` // Updated whenever the session refreshes its tokens
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Current    bool       `json:"current"`
+}
+
+// ClientInfo describes the device a request came from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
 }
 
 type RegisterRequest struct {
@@ -23,16 +61,117 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Shown only once
+}
+
+// AuthResponse carries a token pair, or only an MFA challenge token when the
+// account has two-factor authentication enabled.
+type UnlockLoginRequest struct {
+	Email     string `json:"email" binding:"omitempty,email"`
+	IPAddress string `json:"ip_address"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Role is a named set of permissions. System roles cannot be deleted.
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=64"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest leaves omitted fields unchanged; an empty permissions
+// list removes every permission.
+type UpdateRoleRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// SecurityEvent is an audit record of a security relevant action.
+type SecurityEvent struct {
+	ID        int64     `json:"id"`
+	EventType string    `json:"event_type"`
+	UserID    *int64    `json:"user_id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type AuthResponse struct {
-	User         *User  `json:"user"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	User         *User  `json:"user,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// Purposes of the single-use tokens sent by email
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+const (
+	SecurityEventLoginLocked   = "login_locked"
+	SecurityEventLoginUnlocked = "login_unlocked"
+	SecurityEventRoleChanged   = "role_changed"
+)
+
+// Built-in roles
 const (
 	RoleUser       = "user"
 	RoleAdmin      = "admin"
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Permissions known to the code. Roles are stored in the database as sets of
+// these; new permissions are added by migrations.
+const (
+	PermissionAll              = "*"
+	PermissionRestaurantsRead  = "restaurants:read"
+	PermissionRestaurantsWrite = "restaurants:write"
+	PermissionUsersManage      = "users:manage"
+	PermissionRolesManage      = "roles:manage"
+	PermissionSecurityManage   = "security:manage"
+)
+
+// Permissions is the resolved permission set of a role.
+type Permissions map[string]bool
+
+func NewPermissions(names []string) Permissions {
+	p := make(Permissions, len(names))
+	for _, name := range names {
+		p[name] = true
+	}
+	return p
+}
+
+func (p Permissions) Has(permission string) bool {
+	return p[PermissionAll] || p[permission]
+}
+
+// Covers reports whether p includes every permission in names. It is used to
+// stop users from granting more than they hold themselves.
+func (p Permissions) Covers(names []string) bool {
+	for _, name := range names {
+		if !p.Has(name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Permissions) List() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// roleCacheTTL bounds how long a role edited on another replica keeps its old
+// permissions on this one. Edits made locally take effect immediately.
+const roleCacheTTL = 15 * time.Second
+
+type roleCacheEntry struct {
+	permissions Permissions
+	loadedAt    time.Time
+}
+
+// roleCache saves resolving the permissions of a role on every request.
+type roleCache struct {
+	mu      sync.Mutex
+	entries map[string]roleCacheEntry
+}
+
+func newRoleCache() *roleCache {
+	return &roleCache{entries: make(map[string]roleCacheEntry)}
+}
+
+func (c *roleCache) get(role string) (Permissions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[role]
+	if !ok || time.Since(entry.loadedAt) > roleCacheTTL {
+		return nil, false
+	}
+	return entry.permissions, true
+}
+
+func (c *roleCache) set(role string, permissions Permissions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[role] = roleCacheEntry{permissions: permissions, loadedAt: time.Now()}
+}
+
+func (c *roleCache) invalidate(role string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, role)
+}
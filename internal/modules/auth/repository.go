@@ -3,6 +3,9 @@ package auth
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type Repository struct {
@@ -15,11 +18,11 @@ func NewRepository(db *sql.DB) *Repository {
 
 func (r *Repository) CreateUser(user *User) error {
 	query := `
-		INSERT INTO users (email, password_hash, name, role)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (email, password_hash, name, role, must_change_password)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at
 	`
-	
+
 	var namePtr *string
 	if user.Name != nil && *user.Name != "" {
 		namePtr = user.Name
@@ -31,6 +34,7 @@ func (r *Repository) CreateUser(user *User) error {
 		user.PasswordHash,
 		namePtr,
 		user.Role,
+		user.MustChangePassword,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -41,21 +45,35 @@ func (r *Repository) CreateUser(user *User) error {
 }
 
 func (r *Repository) GetUserByEmail(email string) (*User, error) {
+	return r.getUser("email = $1", email)
+}
+
+func (r *Repository) GetUserByID(id int64) (*User, error) {
+	return r.getUser("id = $1", id)
+}
+
+func (r *Repository) getUser(condition string, arg interface{}) (*User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT id, email, password_hash, name, role, must_change_password, email_verified_at,
+			totp_secret, totp_enabled_at, totp_last_step, created_at, updated_at
 		FROM users
-		WHERE email = $1
-	`
+		WHERE ` + condition
 
 	user := &User{}
-	var namePtr sql.NullString
+	var namePtr, totpSecret sql.NullString
+	var emailVerifiedAt, totpEnabledAt sql.NullTime
 
-	err := r.db.QueryRow(query, email).Scan(
+	err := r.db.QueryRow(query, arg).Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
 		&namePtr,
 		&user.Role,
+		&user.MustChangePassword,
+		&emailVerifiedAt,
+		&totpSecret,
+		&totpEnabledAt,
+		&user.TOTPLastStep,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -70,40 +88,845 @@ func (r *Repository) GetUserByEmail(email string) (*User, error) {
 	if namePtr.Valid {
 		user.Name = &namePtr.String
 	}
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	user.TOTPSecret = totpSecret.String
+	if totpEnabledAt.Valid {
+		user.TOTPEnabledAt = &totpEnabledAt.Time
+		user.TwoFactorEnabled = true
+	}
 
 	return user, nil
 }
 
-func (r *Repository) GetUserByID(id int64) (*User, error) {
+func (r *Repository) CountUsersByRole(role string) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE role = $1", role).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountUsersWithPasswordByRole counts the users of a role who can sign in
+// with a password, leaving out accounts whose password was revoked.
+func (r *Repository) CountUsersWithPasswordByRole(role string) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE role = $1 AND password_hash <> ''", role).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *Repository) UpdatePassword(id int64, passwordHash string, mustChangePassword bool) error {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
-		FROM users
+		UPDATE users
+		SET password_hash = $1,
+			must_change_password = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`
+
+	result, err := r.db.Exec(query, passwordHash, mustChangePassword, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateRefreshToken(token *RefreshTokenRecord) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetRefreshTokenByHash(tokenHash string) (*RefreshTokenRecord, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, rotated_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	token := &RefreshTokenRecord{}
+	var rotatedAt, revokedAt sql.NullTime
+
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&rotatedAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if rotatedAt.Valid {
+		token.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+// MarkRefreshTokenRotated flags a token as used. It reports false when the
+// token had already been rotated or revoked, e.g. by a concurrent request.
+func (r *Repository) MarkRefreshTokenRotated(id int64) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET rotated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *Repository) CreateSession(session *Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING last_used_at, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		session.ID,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.LastUsedAt, &session.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) TouchSession(id string, client ClientInfo, expiresAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET user_agent = $1,
+			ip_address = $2,
+			expires_at = $3,
+			last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`
+
+	if _, err := r.db.Exec(query, client.UserAgent, client.IPAddress, expiresAt, id); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetActiveSessions(userID int64) ([]*Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, expires_at, last_used_at, created_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_used_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session := &Session{}
+		var userAgent, ipAddress sql.NullString
+
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&userAgent,
+			&ipAddress,
+			&session.ExpiresAt,
+			&session.LastUsedAt,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// IsSessionRevoked reports whether a session was revoked, has expired or no
+// longer exists.
+func (r *Repository) IsSessionRevoked(id string) (bool, error) {
+	query := `
+		SELECT revoked_at IS NOT NULL OR expires_at <= CURRENT_TIMESTAMP
+		FROM sessions
 		WHERE id = $1
 	`
 
-	user := &User{}
-	var namePtr sql.NullString
+	var revoked bool
+	err := r.db.QueryRow(query, id).Scan(&revoked)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&namePtr,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
+	return revoked, nil
+}
+
+// RevokeSession revokes a session of the given user together with its refresh
+// tokens. It reports false when there was no such active session.
+func (r *Repository) RevokeSession(userID int64, id string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	); err != nil {
+		return false, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// RevokeUserSessions revokes every active session of a user and returns
+// their IDs.
+func (r *Repository) RevokeUserSessions(userID int64) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL RETURNING id",
+		userID,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(
+		"UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL",
+		userID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *Repository) MarkEmailVerified(userID int64) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, userID); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateUserToken(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.Exec(query, userID, purpose, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
 
+	return nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns the
+// user it belongs to. Each token can be consumed only once.
+func (r *Repository) ConsumeUserToken(purpose, tokenHash string) (int64, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1
+			AND purpose = $2
+			AND used_at IS NULL
+			AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id
+	`
+
+	var userID int64
+	err := r.db.QueryRow(query, tokenHash, purpose).Scan(&userID)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return 0, fmt.Errorf("invalid or expired token")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return 0, fmt.Errorf("failed to consume token: %w", err)
 	}
 
-	if namePtr.Valid {
-		user.Name = &namePtr.String
+	return userID, nil
+}
+
+// InvalidateUserTokens retires every outstanding token of a purpose, so that
+// only the most recently sent link works.
+func (r *Repository) InvalidateUserTokens(userID int64, purpose string) error {
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
 	}
 
-	return user, nil
+	return nil
+}
+
+// SetPendingTOTPSecret stores a secret that is not enforced until confirmed.
+func (r *Repository) SetPendingTOTPSecret(userID int64, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $1,
+			totp_last_step = 0,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND totp_enabled_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed to store two-factor secret: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code. It reports false if
+// that step (or a later one) was already used, i.e. the code is a replay.
+func (r *Repository) UseTOTPStep(userID, step int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1",
+		step, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// EnableTOTP turns on two-factor authentication and replaces the recovery
+// codes in one transaction.
+func (r *Repository) EnableTOTP(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		userID,
+	); err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec(
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, codeHash,
+		); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) DisableTOTP(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE users
+		SET totp_secret = NULL,
+			totp_enabled_at = NULL,
+			totp_last_step = 0,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code. It reports false when the
+// code does not exist or was used before.
+func (r *Repository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *Repository) CreateSecurityEvent(event *SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (event_type, user_id, email, ip_address, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		event.EventType,
+		event.UserID,
+		event.Email,
+		event.IPAddress,
+		event.Details,
+	).Scan(&event.ID, &event.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create security event: %w", err)
+	}
+
+	return nil
+}
+
+// ListSigningKeys returns the keys that still verify tokens at now, with the
+// private keys as stored.
+func (r *Repository) ListSigningKeys(now time.Time) ([]*SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, created_at, retired_at, expires_at
+		FROM signing_keys
+		WHERE expires_at IS NULL OR expires_at > $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*SigningKey
+	for rows.Next() {
+		key := &SigningKey{}
+		var retiredAt, expiresAt sql.NullTime
+
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.stored, &key.CreatedAt, &retiredAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CreateSigningKey stores key as the active signing key unless another one is
+// already active, and reports whether it was stored. The private key must
+// already be sealed.
+func (r *Repository) CreateSigningKey(key *SigningKey) (bool, error) {
+	result, err := r.db.Exec(
+		"INSERT INTO signing_keys (kid, algorithm, private_key) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		key.ID, key.Algorithm, key.stored,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create signing key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RotateSigningKey retires the active key, keeping it valid for verification
+// until expiresAt, and makes key the active one.
+func (r *Repository) RotateSigningKey(key *SigningKey, now, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE signing_keys SET retired_at = $1, expires_at = $2 WHERE retired_at IS NULL",
+		now, expiresAt,
+	); err != nil {
+		return fmt.Errorf("failed to retire signing key: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO signing_keys (kid, algorithm, private_key, created_at) VALUES ($1, $2, $3, $4)",
+		key.ID, key.Algorithm, key.stored, now,
+	); err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceSigningKeyPrivateKey stores key's private key in place of previous,
+// unless another replica has already replaced it.
+func (r *Repository) ReplaceSigningKeyPrivateKey(key *SigningKey, previous string) error {
+	if _, err := r.db.Exec(
+		"UPDATE signing_keys SET private_key = $1 WHERE kid = $2 AND private_key = $3",
+		key.stored, key.ID, previous,
+	); err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetRolePermissions(role string) ([]string, error) {
+	rows, err := r.db.Query("SELECT permission FROM role_permissions WHERE role = $1", role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (r *Repository) ListPermissions() ([]*Permission, error) {
+	rows, err := r.db.Query("SELECT name, description FROM permissions ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []*Permission
+	for rows.Next() {
+		permission := &Permission{}
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+const roleSelect = `
+	SELECT r.name, r.description, r.is_system, r.created_at, r.updated_at,
+		COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.role = r.name
+`
+
+func (r *Repository) ListRoles() ([]*Role, error) {
+	rows, err := r.db.Query(roleSelect + " GROUP BY r.name ORDER BY r.name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *Repository) GetRole(name string) (*Role, error) {
+	role, err := scanRole(r.db.QueryRow(roleSelect+" WHERE r.name = $1 GROUP BY r.name", name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("role not found")
+	}
+	return role, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRole(row rowScanner) (*Role, error) {
+	role := &Role{}
+	err := row.Scan(&role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt, pq.Array(&role.Permissions))
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+	return role, nil
+}
+
+func (r *Repository) CreateRole(role *Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"INSERT INTO roles (name, description) VALUES ($1, $2)",
+		role.Name, role.Description,
+	); err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	if err := setRolePermissions(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateRole(role *Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE roles SET description = $1, updated_at = CURRENT_TIMESTAMP WHERE name = $2",
+		role.Description, role.Name,
+	); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role = $1", role.Name); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+
+	if err := setRolePermissions(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func setRolePermissions(tx *sql.Tx, role string, permissions []string) error {
+	for _, permission := range permissions {
+		if _, err := tx.Exec(
+			"INSERT INTO role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			role, permission,
+		); err != nil {
+			return fmt.Errorf("failed to grant permission %s: %w", permission, err)
+		}
+	}
+	return nil
+}
+
+func (r *Repository) DeleteRole(name string) error {
+	result, err := r.db.Exec("DELETE FROM roles WHERE name = $1 AND NOT is_system", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("role not found")
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateUserRole(userID int64, role string) error {
+	result, err := r.db.Exec(
+		"UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		role, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
 }
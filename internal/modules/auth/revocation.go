@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// sessionCacheTTL bounds how long a revocation made by another replica can go
+// unnoticed by this one. Revocations made locally take effect immediately.
+const sessionCacheTTL = 15 * time.Second
+
+const sessionCacheMaxEntries = 10000
+
+type sessionCacheEntry struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+// sessionCache remembers recent session lookups so that checking the "sid"
+// claim does not cost a database round trip on every request.
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+}
+
+func newSessionCache() *sessionCache {
+	return &sessionCache{entries: make(map[string]sessionCacheEntry)}
+}
+
+func (c *sessionCache) get(id string) (revoked, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok {
+		return false, false
+	}
+
+	// Revoked sessions never come back, so only active entries expire
+	if !entry.revoked && time.Since(entry.checkedAt) > sessionCacheTTL {
+		delete(c.entries, id)
+		return false, false
+	}
+
+	return entry.revoked, true
+}
+
+func (c *sessionCache) set(id string, revoked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= sessionCacheMaxEntries {
+		for key, entry := range c.entries {
+			if entry.revoked || time.Since(entry.checkedAt) > sessionCacheTTL {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	c.entries[id] = sessionCacheEntry{revoked: revoked, checkedAt: time.Now()}
+}
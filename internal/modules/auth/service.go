@@ -3,25 +3,69 @@ package auth
 import (
 	"errors"
 	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/mailer"
 )
 
+const mfaChallengeTTL = 5 * time.Minute
+
+var ErrInsufficientPermissions = errors.New("insufficient permissions")
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// Tokens with an unknown "kid" reload the key set at most this often.
+const keyReloadMinInterval = 10 * time.Second
+
 type Service struct {
-	repo     *Repository
-	jwtConfig config.JWTConfig
+	repo       *Repository
+	jwtConfig  config.JWTConfig
+	authConfig config.AuthConfig
+	mailer     mailer.Mailer
+	appURL     string
+	sessions   *sessionCache
+	throttler  *loginThrottler
+	keys       *KeySet
+	keyCipher  *keyCipher
+	roles      *roleCache
 }
 
-func NewService(repo *Repository, jwtConfig config.JWTConfig) *Service {
+func NewService(repo *Repository, jwtConfig config.JWTConfig, authConfig config.AuthConfig, mail mailer.Mailer, appURL string) (*Service, error) {
+	keyCipher, err := newKeyCipher(jwtConfig.KeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
-		repo:      repo,
-		jwtConfig: jwtConfig,
+		repo:       repo,
+		jwtConfig:  jwtConfig,
+		authConfig: authConfig,
+		mailer:     mail,
+		appURL:     strings.TrimRight(appURL, "/"),
+		sessions:   newSessionCache(),
+		throttler:  newLoginThrottler(repo, authConfig),
+		keys:       NewKeySet(),
+		keyCipher:  keyCipher,
+		roles:      newRoleCache(),
+	}, nil
+}
+
+func newLoginThrottler(repo *Repository, authConfig config.AuthConfig) *loginThrottler {
+	var store AttemptStore = NewMemoryAttemptStore()
+	if authConfig.LoginThrottleStore == "postgres" {
+		store = NewPostgresAttemptStore(repo.db)
 	}
+
+	return &loginThrottler{store: store, config: authConfig}
 }
 
-func (s *Service) Register(req *RegisterRequest) (*AuthResponse, error) {
+func (s *Service) Register(req *RegisterRequest, client ClientInfo) (*AuthResponse, error) {
 	// Check if user already exists
 	existingUser, _ := s.repo.GetUserByEmail(req.Email)
 	if existingUser != nil {
@@ -48,85 +92,986 @@ func (s *Service) Register(req *RegisterRequest) (*AuthResponse, error) {
 		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, err := GenerateAccessToken(user.ID, user.Email, user.Role, s.jwtConfig.AccessSecret, s.jwtConfig.AccessTTL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	// A failed email must not fail the registration; it can be resent
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
 	}
 
-	refreshToken, err := GenerateRefreshToken(user.ID, user.Email, s.jwtConfig.RefreshSecret, s.jwtConfig.RefreshTTL)
+	// Generate tokens
+	return s.startSession(user, client)
+}
+
+func (s *Service) Login(req *LoginRequest, client ClientInfo) (*AuthResponse, error) {
+	email := strings.ToLower(req.Email)
+
+	// Count the attempt before checking the password, refusing it while the
+	// account or client IP is backing off or locked out
+	attempt, err := s.beginLogin(email, nil, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
 
-	return &AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
-}
-
-func (s *Service) Login(req *LoginRequest) (*AuthResponse, error) {
 	// Get user by email
 	user, err := s.repo.GetUserByEmail(req.Email)
 	if err != nil {
+		s.recordLoginFailure(attempt, email, nil, client)
 		return nil, errors.New("invalid email or password")
 	}
 
 	// Check password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.recordLoginFailure(attempt, email, &user.ID, client)
 		return nil, errors.New("invalid email or password")
 	}
 
+	if err := s.throttler.succeed(attempt); err != nil {
+		return nil, err
+	}
+
+	// With two-factor authentication the client has to complete the login
+	// with a code through LoginMFA
+	if user.TwoFactorEnabled {
+		mfaToken, err := GenerateMFAChallengeToken(user.ID, s.jwtConfig.AccessSecret, mfaChallengeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+		return &AuthResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
 	// Generate tokens
-	accessToken, err := GenerateAccessToken(user.ID, user.Email, user.Role, s.jwtConfig.AccessSecret, s.jwtConfig.AccessTTL)
+	return s.startSession(user, client)
+}
+
+func (s *Service) LoginMFA(req *MFALoginRequest, client ClientInfo) (*AuthResponse, error) {
+	claims, err := ValidateSecretToken(req.MFAToken, s.jwtConfig.AccessSecret)
+	if err != nil || claims.Purpose != PurposeMFAChallenge {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	user, err := s.repo.GetUserByID(claims.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, errors.New("invalid or expired mfa token")
 	}
 
-	refreshToken, err := GenerateRefreshToken(user.ID, user.Email, s.jwtConfig.RefreshSecret, s.jwtConfig.RefreshTTL)
+	// Guessing codes counts against the same limits as guessing passwords
+	email := strings.ToLower(user.Email)
+	attempt, err := s.beginLogin(email, &user.ID, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
 
-	return &AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	if err := s.verifySecondFactor(user, req.Code); err != nil {
+		s.recordLoginFailure(attempt, email, &user.ID, client)
+		return nil, err
+	}
+
+	if err := s.throttler.succeed(attempt); err != nil {
+		return nil, err
+	}
+
+	// Generate tokens
+	return s.startSession(user, client)
 }
 
-func (s *Service) RefreshToken(refreshToken string) (*AuthResponse, error) {
+func (s *Service) RefreshToken(refreshToken string, client ClientInfo) (*AuthResponse, error) {
 	// Validate refresh token
-	claims, err := ValidateToken(refreshToken, s.jwtConfig.RefreshSecret)
+	claims, err := ValidateSecretToken(refreshToken, s.jwtConfig.RefreshSecret)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
 
+	record, err := s.repo.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil || record.UserID != claims.UserID {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// A token that was already rotated or revoked is being replayed: whoever
+	// holds it may have stolen it, so the whole family (session) is revoked
+	rotated := false
+	if record.RotatedAt == nil && record.RevokedAt == nil {
+		if rotated, err = s.repo.MarkRefreshTokenRotated(record.ID); err != nil {
+			return nil, err
+		}
+	}
+	if !rotated {
+		if _, err := s.revokeSession(record.UserID, record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
+
 	// Get user
 	user, err := s.repo.GetUserByID(claims.UserID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	// Generate new tokens
-	accessToken, err := GenerateAccessToken(user.ID, user.Email, user.Role, s.jwtConfig.AccessSecret, s.jwtConfig.AccessTTL)
+	if err := s.repo.TouchSession(record.FamilyID, client, time.Now().Add(s.jwtConfig.RefreshTTL)); err != nil {
+		return nil, err
+	}
+
+	// Generate new tokens in the same family
+	return s.issueTokens(user, record.FamilyID)
+}
+
+func (s *Service) Logout(userID int64, sessionID string) error {
+	if sessionID == "" {
+		return errors.New("token is not bound to a session")
+	}
+
+	_, err := s.revokeSession(userID, sessionID)
+	return err
+}
+
+func (s *Service) LogoutAll(userID int64) error {
+	ids, err := s.repo.RevokeUserSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		s.sessions.set(id, true)
+	}
+
+	return nil
+}
+
+func (s *Service) GetSessions(userID int64, currentSessionID string) ([]*Session, error) {
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+func (s *Service) RevokeSession(userID int64, sessionID string) error {
+	revoked, err := s.revokeSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// IsSessionRevoked backs the "sid" check in AuthMiddleware.
+func (s *Service) IsSessionRevoked(sessionID string) (bool, error) {
+	if revoked, ok := s.sessions.get(sessionID); ok {
+		return revoked, nil
+	}
+
+	revoked, err := s.repo.IsSessionRevoked(sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	s.sessions.set(sessionID, revoked)
+	return revoked, nil
+}
+
+// VerifyAccessToken backs AuthMiddleware. A token signed with a key this
+// instance has not loaded yet triggers a reload, as another replica may have
+// just rotated the keys.
+func (s *Service) VerifyAccessToken(token string) (*Claims, error) {
+	claims, err := ValidateToken(token, s.keys)
+	if errors.Is(err, ErrUnknownSigningKey) && time.Since(s.keys.LoadedAt()) > keyReloadMinInterval {
+		if err := s.LoadSigningKeys(); err != nil {
+			return nil, err
+		}
+		claims, err = ValidateToken(token, s.keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, errors.New("invalid token purpose")
+	}
+
+	return claims, nil
+}
+
+// LoadSigningKeys reads the key set from the database and creates the first
+// signing key when there is none yet.
+func (s *Service) LoadSigningKeys() error {
+	now := time.Now().UTC()
+
+	keys, err := s.listSigningKeys(now)
+	if err != nil {
+		return err
+	}
+
+	if !hasActiveKey(keys) {
+		key, err := generateSigningKey(s.jwtConfig.SigningAlgorithm)
+		if err != nil {
+			return err
+		}
+		if err := s.keyCipher.seal(key); err != nil {
+			return err
+		}
+		if _, err := s.repo.CreateSigningKey(key); err != nil {
+			return err
+		}
+
+		// Another replica may have created one first, so read back whichever won
+		keys, err = s.listSigningKeys(now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.keys.replace(keys)
+}
+
+// listSigningKeys decrypts the stored keys, encrypting any that were stored
+// before private keys were.
+func (s *Service) listSigningKeys(now time.Time) ([]*SigningKey, error) {
+	keys, err := s.repo.ListSigningKeys(now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		plaintext, err := s.keyCipher.open(key)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", key.ID, err)
+		}
+		if !plaintext {
+			continue
+		}
+
+		previous := key.stored
+		if err := s.keyCipher.seal(key); err != nil {
+			return nil, err
+		}
+		if err := s.repo.ReplaceSigningKeyPrivateKey(key, previous); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKey makes a new key the signing key. The previous one keeps
+// verifying while the tokens it signed can still be in use: other replicas
+// sign with it until their next reload, and those tokens live for AccessTTL.
+func (s *Service) RotateSigningKey(algorithm string) (*SigningKey, error) {
+	if algorithm == "" {
+		algorithm = s.jwtConfig.SigningAlgorithm
+	}
+
+	key, err := generateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keyCipher.seal(key); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.jwtConfig.KeyRefreshInterval + s.jwtConfig.AccessTTL)
+	if err := s.repo.RotateSigningKey(key, now, expiresAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.LoadSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// WatchSigningKeys reloads the key set every interval until stop is closed, so
+// that rotations made elsewhere are picked up.
+func (s *Service) WatchSigningKeys(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.LoadSigningKeys(); err != nil {
+				log.Printf("Failed to reload signing keys: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Service) SigningKeys() []*SigningKey {
+	return s.keys.Keys()
+}
+
+func (s *Service) JWKS() *JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *Service) GetUserByID(userID int64) (*User, error) {
+	return s.repo.GetUserByID(userID)
+}
+
+func (s *Service) ChangePassword(userID int64, req *ChangePasswordRequest, client ClientInfo) (*AuthResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check current password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		return nil, errors.New("new password must differ from the current password")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.repo.UpdatePassword(user.ID, string(hashedPassword), false); err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = string(hashedPassword)
+	user.MustChangePassword = false
+
+	// Sign out every device that knew the old password
+	if err := s.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	// Issue tokens without the password change flag
+	return s.startSession(user, client)
+}
+
+// BootstrapSuperAdmin creates the first superadmin of a fresh installation.
+// It refuses to run once any superadmin can sign in, so the seeded account
+// whose password migration 004 revoked does not count, and the new account
+// has to change its password on first login.
+func (s *Service) BootstrapSuperAdmin(email, name, password string) (*User, error) {
+	count, err := s.repo.CountUsersWithPasswordByRole(RoleSuperAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("a superadmin already exists")
+	}
+
+	existingUser, _ := s.repo.GetUserByEmail(email)
+	if existingUser != nil {
+		return nil, errors.New("user with this email already exists")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &User{
+		Email:              email,
+		PasswordHash:       string(hashedPassword),
+		Role:               RoleSuperAdmin,
+		MustChangePassword: true,
+	}
+	if name != "" {
+		user.Name = &name
+	}
+
+	if err := s.repo.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	// The operator vouches for the address
+	if err := s.repo.MarkEmailVerified(user.ID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUserByID(user.ID)
+}
+
+// UnlockLogin lifts a lockout of an account and/or a client IP.
+func (s *Service) UnlockLogin(adminID int64, req *UnlockLoginRequest) error {
+	if req.Email == "" && req.IPAddress == "" {
+		return errors.New("email or ip_address is required")
+	}
+
+	email := strings.ToLower(req.Email)
+	if email != "" {
+		if err := s.throttler.reset(emailAttemptKey(email)); err != nil {
+			return err
+		}
+	}
+	if req.IPAddress != "" {
+		if err := s.throttler.reset(ipAttemptKey(req.IPAddress)); err != nil {
+			return err
+		}
+	}
+
+	s.recordSecurityEvent(&SecurityEvent{
+		EventType: SecurityEventLoginUnlocked,
+		Email:     email,
+		IPAddress: req.IPAddress,
+		Details:   fmt.Sprintf("unlocked by user %d", adminID),
+	})
+
+	return nil
+}
+
+// RolePermissions resolves the permissions of a role for AuthMiddleware.
+func (s *Service) RolePermissions(role string) (Permissions, error) {
+	if permissions, ok := s.roles.get(role); ok {
+		return permissions, nil
+	}
+
+	names, err := s.repo.GetRolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := NewPermissions(names)
+	s.roles.set(role, permissions)
+	return permissions, nil
+}
+
+func (s *Service) ListPermissions() ([]*Permission, error) {
+	return s.repo.ListPermissions()
+}
+
+func (s *Service) ListRoles() ([]*Role, error) {
+	return s.repo.ListRoles()
+}
+
+// CreateRole adds a role. granter is the permission set of the user creating
+// it, who cannot grant permissions they do not hold.
+func (s *Service) CreateRole(granter Permissions, req *CreateRoleRequest) (*Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, errors.New("role name may only contain lowercase letters, digits, '-' and '_'")
+	}
+
+	if err := s.checkGrantable(granter, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	existing, _ := s.repo.GetRole(req.Name)
+	if existing != nil {
+		return nil, errors.New("role already exists")
+	}
+
+	role := &Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := s.repo.CreateRole(role); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetRole(role.Name)
+}
+
+func (s *Service) UpdateRole(granter Permissions, name string, req *UpdateRoleRequest) (*Role, error) {
+	role, err := s.repo.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything else is recovered through the superadmin role
+	if role.Name == RoleSuperAdmin {
+		return nil, errors.New("the superadmin role cannot be modified")
+	}
+
+	// Holders of the role are affected too, so the editor must hold everything
+	// it grants before and after the change
+	if !granter.Covers(role.Permissions) {
+		return nil, ErrInsufficientPermissions
+	}
+
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if req.Permissions != nil {
+		if err := s.checkGrantable(granter, req.Permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = req.Permissions
+	}
+
+	if err := s.repo.UpdateRole(role); err != nil {
+		return nil, err
+	}
+	s.roles.invalidate(role.Name)
+
+	return s.repo.GetRole(role.Name)
+}
+
+func (s *Service) DeleteRole(granter Permissions, name string) error {
+	role, err := s.repo.GetRole(name)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		return errors.New("system roles cannot be deleted")
+	}
+	if !granter.Covers(role.Permissions) {
+		return ErrInsufficientPermissions
+	}
+
+	count, err := s.repo.CountUsersByRole(role.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("role is assigned to %d user(s)", count)
+	}
+
+	if err := s.repo.DeleteRole(role.Name); err != nil {
+		return err
+	}
+	s.roles.invalidate(role.Name)
+
+	return nil
+}
+
+// AssignRole changes the role of another user. The actor must hold every
+// permission of both the current and the new role, so nobody can promote
+// past themselves or demote someone more privileged.
+func (s *Service) AssignRole(actorID int64, granter Permissions, userID int64, roleName string) (*User, error) {
+	if actorID == userID {
+		return nil, errors.New("you cannot change your own role")
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.repo.GetRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.RolePermissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !granter.Covers(role.Permissions) || !granter.Covers(current.List()) {
+		return nil, ErrInsufficientPermissions
+	}
+
+	if err := s.repo.UpdateUserRole(user.ID, role.Name); err != nil {
+		return nil, err
+	}
+
+	// Access tokens carry the role, so end the user's sessions to apply the
+	// change right away
+	if err := s.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	s.recordSecurityEvent(&SecurityEvent{
+		EventType: SecurityEventRoleChanged,
+		UserID:    &user.ID,
+		Email:     user.Email,
+		Details:   fmt.Sprintf("%s -> %s by user %d", user.Role, role.Name, actorID),
+	})
+
+	return s.repo.GetUserByID(user.ID)
+}
+
+// checkGrantable validates permission names and makes sure granter holds them.
+func (s *Service) checkGrantable(granter Permissions, names []string) error {
+	known, err := s.repo.ListPermissions()
+	if err != nil {
+		return err
+	}
+
+	exists := make(map[string]bool, len(known))
+	for _, permission := range known {
+		exists[permission.Name] = true
+	}
+
+	for _, name := range names {
+		if !exists[name] {
+			return fmt.Errorf("unknown permission %q", name)
+		}
+	}
+
+	if !granter.Covers(names) {
+		return ErrInsufficientPermissions
+	}
+
+	return nil
+}
+
+// beginLogin counts a login attempt against the account and the client IP,
+// recording any lockout that causes.
+func (s *Service) beginLogin(email string, userID *int64, client ClientInfo) (*loginAttempt, error) {
+	attempt, locked, err := s.throttler.begin(email, client.IPAddress)
+	s.recordLockouts(locked, email, userID, client)
+	return attempt, err
+}
+
+// recordLoginFailure locks out the account and/or client IP of a failed
+// attempt once they reach their thresholds.
+func (s *Service) recordLoginFailure(attempt *loginAttempt, email string, userID *int64, client ClientInfo) {
+	locked, err := s.throttler.fail(attempt)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s from %s: %v", email, client.IPAddress, err)
+	}
+	s.recordLockouts(locked, email, userID, client)
+}
+
+func (s *Service) recordLockouts(keys []string, email string, userID *int64, client ClientInfo) {
+	for _, key := range keys {
+		if key == emailAttemptKey(email) {
+			s.recordSecurityEvent(&SecurityEvent{
+				EventType: SecurityEventLoginLocked,
+				UserID:    userID,
+				Email:     email,
+				IPAddress: client.IPAddress,
+				Details:   fmt.Sprintf("account locked for %s", s.authConfig.LoginLockoutDuration),
+			})
+		} else {
+			s.recordSecurityEvent(&SecurityEvent{
+				EventType: SecurityEventLoginLocked,
+				IPAddress: client.IPAddress,
+				Details:   fmt.Sprintf("client IP locked for %s", s.authConfig.LoginLockoutDuration),
+			})
+		}
+	}
+}
+
+// recordSecurityEvent writes an audit record. Failing to do so is logged but
+// does not fail the request.
+func (s *Service) recordSecurityEvent(event *SecurityEvent) {
+	log.Printf("Security event %s: email=%q ip=%q %s", event.EventType, event.Email, event.IPAddress, event.Details)
+
+	if err := s.repo.CreateSecurityEvent(event); err != nil {
+		log.Printf("Failed to record security event %s: %v", event.EventType, err)
+	}
+}
+
+// SetupTwoFactor generates a new TOTP secret for the user. It is not enforced
+// until confirmed with a code through ConfirmTwoFactor.
+func (s *Service) SetupTwoFactor(userID int64) (*TwoFactorSetupResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPendingTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totpURI(s.authConfig.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *Service) ConfirmTwoFactor(userID int64, req *TwoFactorCodeRequest) (*TwoFactorConfirmResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+
+	if err := s.verifyTOTP(user, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := s.repo.EnableTOTP(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *Service) DisableTwoFactor(userID int64, req *TwoFactorCodeRequest) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifySecondFactor(user, req.Code); err != nil {
+		return err
+	}
+
+	return s.repo.DisableTOTP(user.ID)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code.
+func (s *Service) verifySecondFactor(user *User, code string) error {
+	if !user.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifyTOTP(user, code); err == nil {
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("invalid two-factor code")
+	}
+
+	return nil
+}
+
+func (s *Service) verifyTOTP(user *User, code string) error {
+	step, ok := validateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+
+	// Guards against the same code being used twice concurrently
+	fresh, err := s.repo.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errors.New("invalid two-factor code")
+	}
+
+	return nil
+}
+
+// mfaSetupRequired reports whether the user must enroll in two-factor
+// authentication before using the API.
+func (s *Service) mfaSetupRequired(user *User) bool {
+	if !s.authConfig.RequireMFAForAdmins || user.TwoFactorEnabled {
+		return false
+	}
+
+	// Any role with permissions is privileged; fail closed if it cannot be resolved
+	permissions, err := s.RolePermissions(user.Role)
+	if err != nil {
+		return true
+	}
+	return len(permissions) > 0
+}
+
+// ForgotPassword emails a reset link if the address belongs to a user. It
+// does not reveal whether it does.
+func (s *Service) ForgotPassword(req *ForgotPasswordRequest) error {
+	user, err := s.repo.GetUserByEmail(req.Email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := s.createUserToken(user.ID, TokenPurposePasswordReset, s.authConfig.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your account.\n\n"+
+				"Open this link to choose a new password:\n%s/reset-password?token=%s\n\n"+
+				"The link expires in %s. If you did not ask for it, ignore this email.\n",
+			s.appURL, token, s.authConfig.PasswordResetTTL,
+		),
+	})
+}
+
+func (s *Service) ResetPassword(req *ResetPasswordRequest) error {
+	userID, err := s.repo.ConsumeUserToken(TokenPurposePasswordReset, hashToken(req.Token))
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.repo.UpdatePassword(userID, string(hashedPassword), false); err != nil {
+		return err
+	}
+
+	// Receiving the link proves ownership of the address
+	if err := s.repo.MarkEmailVerified(userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.InvalidateUserTokens(userID, TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	return s.LogoutAll(userID)
+}
+
+func (s *Service) VerifyEmail(req *VerifyEmailRequest) error {
+	userID, err := s.repo.ConsumeUserToken(TokenPurposeEmailVerification, hashToken(req.Token))
+	if err != nil {
+		return err
+	}
+
+	return s.repo.MarkEmailVerified(userID)
+}
+
+func (s *Service) ResendVerificationEmail(userID int64) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return errors.New("email is already verified")
+	}
+
+	return s.sendVerificationEmail(user)
+}
+
+func (s *Service) sendVerificationEmail(user *User) error {
+	token, err := s.createUserToken(user.ID, TokenPurposeEmailVerification, s.authConfig.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Open this link to verify your email address:\n%s/verify-email?token=%s\n\n"+
+				"The link expires in %s.\n",
+			s.appURL, token, s.authConfig.EmailVerificationTTL,
+		),
+	})
+}
+
+// createUserToken replaces any outstanding token of the purpose with a new one
+// and returns it in plain text. Only its hash is stored.
+func (s *Service) createUserToken(userID int64, purpose string, ttl time.Duration) (string, error) {
+	if err := s.repo.InvalidateUserTokens(userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.CreateUserToken(userID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *Service) revokeSession(userID int64, sessionID string) (bool, error) {
+	revoked, err := s.repo.RevokeSession(userID, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	if revoked {
+		s.sessions.set(sessionID, true)
+	}
+
+	return revoked, nil
+}
+
+// startSession records a new session for the device and issues its first
+// token pair.
+func (s *Service) startSession(user *User, client ClientInfo) (*AuthResponse, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTTL),
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, sessionID)
+}
+
+// issueTokens creates an access/refresh pair within a session. The session ID
+// doubles as the refresh token family.
+func (s *Service) issueTokens(user *User, sessionID string) (*AuthResponse, error) {
+	accessToken, err := GenerateAccessToken(user, sessionID, s.mfaSetupRequired(user), s.keys, s.jwtConfig.AccessTTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	newRefreshToken, err := GenerateRefreshToken(user.ID, user.Email, s.jwtConfig.RefreshSecret, s.jwtConfig.RefreshTTL)
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := GenerateRefreshToken(user.ID, user.Email, tokenID, s.jwtConfig.RefreshSecret, s.jwtConfig.RefreshTTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
+	if err := s.repo.CreateRefreshToken(&RefreshTokenRecord{
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+
 	return &AuthResponse{
 		User:         user,
 		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
-
-func (s *Service) GetUserByID(userID int64) (*User, error) {
-	return s.repo.GetUserByID(userID)
-}
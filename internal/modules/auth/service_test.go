@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/database/dbtest"
+	"github.com/yourcompany/saas-platform/internal/mailer"
+)
+
+var resetLinkPattern = regexp.MustCompile(`/reset-password\?token=([0-9a-f]+)`)
+
+type resetEnv struct {
+	service *Service
+	mailDir string
+	user    *User
+}
+
+func newResetEnv(t *testing.T) *resetEnv {
+	db := dbtest.Open(t)
+	mailDir := t.TempDir()
+
+	service, err := NewService(
+		NewRepository(db),
+		config.JWTConfig{KeyEncryptionKey: testKeyEncryptionKey, AccessTTL: time.Minute, RefreshTTL: time.Hour},
+		config.AuthConfig{PasswordResetTTL: time.Hour, EmailVerificationTTL: time.Hour},
+		mailer.NewLogMailer(mailDir, "test@example.com"),
+		"http://app.test",
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &User{Email: dbtest.Unique("reset") + "@example.com", PasswordHash: string(hash), Role: "user"}
+	if err := service.repo.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return &resetEnv{service: service, mailDir: mailDir, user: user}
+}
+
+// requestReset asks for a reset link and returns the token from the email
+// the log mailer wrote.
+func (env *resetEnv) requestReset(t *testing.T) string {
+	t.Helper()
+
+	before := env.mails(t)
+	if err := env.service.ForgotPassword(&ForgotPasswordRequest{Email: env.user.Email}); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	after := env.mails(t)
+	if len(after) != len(before)+1 {
+		t.Fatalf("got %d new emails, want 1", len(after)-len(before))
+	}
+
+	body, err := os.ReadFile(after[len(after)-1])
+	if err != nil {
+		t.Fatalf("failed to read email: %v", err)
+	}
+	match := resetLinkPattern.FindSubmatch(body)
+	if match == nil {
+		t.Fatalf("no reset link in email:\n%s", body)
+	}
+	return string(match[1])
+}
+
+func (env *resetEnv) mails(t *testing.T) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(env.mailDir, "*.eml"))
+	if err != nil {
+		t.Fatalf("failed to list emails: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (env *resetEnv) assertPassword(t *testing.T, password string) {
+	t.Helper()
+
+	user, err := env.service.repo.GetUserByID(env.user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		t.Errorf("password is not %q", password)
+	}
+}
+
+func TestResetTokenWorksOnce(t *testing.T) {
+	env := newResetEnv(t)
+	token := env.requestReset(t)
+
+	if err := env.service.ResetPassword(&ResetPasswordRequest{Token: token, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	env.assertPassword(t, "new-password")
+
+	if err := env.service.ResetPassword(&ResetPasswordRequest{Token: token, NewPassword: "another-password"}); err == nil {
+		t.Error("a reset token was used twice")
+	}
+	env.assertPassword(t, "new-password")
+}
+
+func TestExpiredResetTokenIsRefused(t *testing.T) {
+	env := newResetEnv(t)
+	token := env.requestReset(t)
+
+	if _, err := env.service.repo.db.Exec(
+		"UPDATE user_tokens SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE token_hash = $1",
+		hashToken(token),
+	); err != nil {
+		t.Fatalf("failed to expire token: %v", err)
+	}
+
+	if err := env.service.ResetPassword(&ResetPasswordRequest{Token: token, NewPassword: "new-password"}); err == nil {
+		t.Error("an expired reset token was accepted")
+	}
+	env.assertPassword(t, "old-password")
+}
+
+func TestNewResetLinkReplacesOlderOne(t *testing.T) {
+	env := newResetEnv(t)
+	first := env.requestReset(t)
+	second := env.requestReset(t)
+
+	if err := env.service.ResetPassword(&ResetPasswordRequest{Token: first, NewPassword: "new-password"}); err == nil {
+		t.Error("a replaced reset token was accepted")
+	}
+	if err := env.service.ResetPassword(&ResetPasswordRequest{Token: second, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	env.assertPassword(t, "new-password")
+}
+
+func TestForgotPasswordForUnknownEmail(t *testing.T) {
+	env := newResetEnv(t)
+
+	if err := env.service.ForgotPassword(&ForgotPasswordRequest{Email: dbtest.Unique("nobody") + "@example.com"}); err != nil {
+		t.Errorf("ForgotPassword: %v", err)
+	}
+	if mails := env.mails(t); len(mails) != 0 {
+		t.Errorf("sent %d emails for an unknown address", len(mails))
+	}
+}
+
+// Migration 003 still seeds admin@saas-platform.com, and 004 revokes its
+// published password.
+func TestSeededSuperadminPasswordIsRevoked(t *testing.T) {
+	repo := NewRepository(dbtest.Open(t))
+
+	user, err := repo.GetUserByEmail("admin@saas-platform.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("Admin123!")) == nil {
+		t.Error("the published password still signs in")
+	}
+	if !user.MustChangePassword {
+		t.Error("the seeded superadmin does not have to change its password")
+	}
+}
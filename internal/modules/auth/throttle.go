@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+)
+
+// Failed attempts allowed before backoff kicks in, and the longest backoff
+// between two attempts short of a lockout.
+const (
+	loginFreeAttempts = 3
+	loginMaxBackoff   = time.Minute
+)
+
+const memoryAttemptStoreMaxEntries = 100000
+
+// AttemptState counts the login attempts of a key that have not succeeded,
+// including any still being checked. PreviousFailureAt is when the attempt
+// before the last one was made, zero if there was none.
+type AttemptState struct {
+	Failures          int
+	LastFailureAt     time.Time
+	PreviousFailureAt time.Time
+	LockedUntil       *time.Time
+}
+
+// AttemptStore keeps failed login counters. MemoryAttemptStore serves a single
+// instance; PostgresAttemptStore shares the counters between replicas.
+type AttemptStore interface {
+	// AddAttempt atomically counts an attempt and returns the state including
+	// it. The counter starts over when the previous attempt is older than
+	// resetAfter.
+	AddAttempt(key string, now time.Time, resetAfter time.Duration) (*AttemptState, error)
+	// Forgive takes back one counted attempt that succeeded.
+	Forgive(key string) error
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+type MemoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]*AttemptState
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]*AttemptState)}
+}
+
+func (m *MemoryAttemptStore) AddAttempt(key string, now time.Time, resetAfter time.Duration) (*AttemptState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Forget stale counters so the map does not grow without bound
+	if len(m.entries) >= memoryAttemptStoreMaxEntries {
+		for k, s := range m.entries {
+			if now.Sub(s.LastFailureAt) > resetAfter && (s.LockedUntil == nil || s.LockedUntil.Before(now)) {
+				delete(m.entries, k)
+			}
+		}
+	}
+
+	state, ok := m.entries[key]
+	if !ok || now.Sub(state.LastFailureAt) > resetAfter {
+		state = &AttemptState{}
+		m.entries[key] = state
+	}
+
+	state.Failures++
+	state.PreviousFailureAt = state.LastFailureAt
+	state.LastFailureAt = now
+
+	copied := *state
+	return &copied, nil
+}
+
+func (m *MemoryAttemptStore) Forgive(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.entries[key]; ok && state.Failures > 0 {
+		state.Failures--
+	}
+	return nil
+}
+
+func (m *MemoryAttemptStore) Lock(key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.entries[key]; ok {
+		state.LockedUntil = &until
+	}
+	return nil
+}
+
+func (m *MemoryAttemptStore) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+type PostgresAttemptStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAttemptStore(db *sql.DB) *PostgresAttemptStore {
+	return &PostgresAttemptStore{db: db}
+}
+
+func (p *PostgresAttemptStore) AddAttempt(key string, now time.Time, resetAfter time.Duration) (*AttemptState, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			locked_until = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN NULL
+				ELSE login_attempts.locked_until
+			END,
+			previous_failure_at = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN NULL
+				ELSE login_attempts.last_failure_at
+			END,
+			last_failure_at = $2
+		RETURNING failures, last_failure_at, previous_failure_at, locked_until
+	`
+
+	state := &AttemptState{}
+	var previousFailureAt, lockedUntil sql.NullTime
+
+	err := p.db.QueryRow(query, key, now, now.Add(-resetAfter)).Scan(
+		&state.Failures, &state.LastFailureAt, &previousFailureAt, &lockedUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	if previousFailureAt.Valid {
+		state.PreviousFailureAt = previousFailureAt.Time
+	}
+	if lockedUntil.Valid {
+		state.LockedUntil = &lockedUntil.Time
+	}
+
+	return state, nil
+}
+
+func (p *PostgresAttemptStore) Forgive(key string) error {
+	if _, err := p.db.Exec(
+		"UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0", key,
+	); err != nil {
+		return fmt.Errorf("failed to forgive login attempt: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresAttemptStore) Lock(key string, until time.Time) error {
+	if _, err := p.db.Exec("UPDATE login_attempts SET locked_until = $1 WHERE key = $2", until, key); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresAttemptStore) Reset(key string) error {
+	if _, err := p.db.Exec("DELETE FROM login_attempts WHERE key = $1", key); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// ThrottledError is returned while a login is locked out or backing off.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, account temporarily locked"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// loginThrottler applies exponential backoff after a few failed attempts and
+// locks a key out once it reaches its threshold. An attempt is counted before
+// the credentials are checked, so that parallel attempts cannot all pass the
+// check before any of them has failed.
+type loginThrottler struct {
+	store  AttemptStore
+	config config.AuthConfig
+}
+
+func emailAttemptKey(email string) string { return "email:" + email }
+
+func ipAttemptKey(ip string) string { return "ip:" + ip }
+
+// loginAttempt is a login counted against its account and client IP.
+type loginAttempt struct {
+	account attemptKey
+	ip      attemptKey
+}
+
+func (a *loginAttempt) keys() []*attemptKey {
+	return []*attemptKey{&a.account, &a.ip}
+}
+
+type attemptKey struct {
+	key       string
+	threshold int // Failures before a lockout; none if not positive
+	state     *AttemptState
+}
+
+// begin counts an attempt against the account and the client IP and returns
+// a ThrottledError if either may not attempt a login right now. A refused
+// attempt stays counted. locked lists the keys the attempt has just locked
+// out.
+func (t *loginThrottler) begin(email, ip string) (attempt *loginAttempt, locked []string, err error) {
+	now := time.Now().UTC()
+
+	attempt = &loginAttempt{
+		account: attemptKey{key: emailAttemptKey(email), threshold: t.config.LoginMaxAttempts},
+		ip:      attemptKey{key: ipAttemptKey(ip), threshold: t.config.LoginIPMaxAttempts},
+	}
+
+	var worst *ThrottledError
+	for _, k := range attempt.keys() {
+		if k.state, err = t.store.AddAttempt(k.key, now, t.config.LoginLockoutDuration); err != nil {
+			return nil, locked, err
+		}
+
+		// The attempts before this one, some possibly still being checked
+		earlier := k.state.Failures - 1
+
+		var throttled *ThrottledError
+		switch {
+		case k.state.LockedUntil != nil && k.state.LockedUntil.After(now):
+			throttled = &ThrottledError{RetryAfter: k.state.LockedUntil.Sub(now), Locked: true}
+		case k.threshold > 0 && earlier >= k.threshold:
+			// The threshold was reached without a lockout, by attempts
+			// refused for backoff or still being checked
+			if err := t.store.Lock(k.key, now.Add(t.config.LoginLockoutDuration)); err != nil {
+				return nil, locked, err
+			}
+			locked = append(locked, k.key)
+			throttled = &ThrottledError{RetryAfter: t.config.LoginLockoutDuration, Locked: true}
+		case earlier >= loginFreeAttempts:
+			if wait := k.state.PreviousFailureAt.Add(t.backoff(earlier)).Sub(now); wait > 0 {
+				throttled = &ThrottledError{RetryAfter: wait}
+			}
+		}
+
+		if throttled != nil && (worst == nil || throttled.RetryAfter > worst.RetryAfter) {
+			worst = throttled
+		}
+	}
+
+	if worst != nil {
+		return nil, locked, worst
+	}
+	return attempt, locked, nil
+}
+
+// fail locks out the keys the failed attempt brought to their threshold and
+// returns them.
+func (t *loginThrottler) fail(attempt *loginAttempt) ([]string, error) {
+	now := time.Now().UTC()
+
+	var locked []string
+	for _, k := range attempt.keys() {
+		if k.threshold <= 0 || k.state.Failures < k.threshold {
+			continue
+		}
+		if k.state.LockedUntil != nil && k.state.LockedUntil.After(now) {
+			continue
+		}
+
+		if err := t.store.Lock(k.key, now.Add(t.config.LoginLockoutDuration)); err != nil {
+			return locked, err
+		}
+		locked = append(locked, k.key)
+	}
+
+	return locked, nil
+}
+
+// succeed clears the account's counter and takes the attempt back from the
+// IP's. The IP keeps its earlier failures, so that an attacker cannot clear
+// them by signing in to an account of their own.
+func (t *loginThrottler) succeed(attempt *loginAttempt) error {
+	if err := t.store.Reset(attempt.account.key); err != nil {
+		return err
+	}
+	return t.store.Forgive(attempt.ip.key)
+}
+
+func (t *loginThrottler) reset(key string) error {
+	return t.store.Reset(key)
+}
+
+func (t *loginThrottler) backoff(failures int) time.Duration {
+	delay := t.config.LoginBackoffBase
+	for i := loginFreeAttempts; i < failures && delay < loginMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > loginMaxBackoff {
+		delay = loginMaxBackoff
+	}
+	return delay
+}
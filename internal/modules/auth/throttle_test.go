@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/database/dbtest"
+)
+
+func newTestThrottler(store AttemptStore, backoffBase time.Duration) *loginThrottler {
+	return &loginThrottler{store: store, config: config.AuthConfig{
+		LoginMaxAttempts:     5,
+		LoginIPMaxAttempts:   100,
+		LoginLockoutDuration: 15 * time.Minute,
+		LoginBackoffBase:     backoffBase,
+	}}
+}
+
+// Parallel attempts with a wrong password must not all be checked before
+// any of them has failed.
+func testParallelLoginsStopAtThreshold(t *testing.T, store AttemptStore) {
+	throttler := newTestThrottler(store, 0)
+	email := dbtest.Unique("parallel") + "@example.com"
+	ip := dbtest.Unique("ip")
+
+	var mu sync.Mutex
+	var checked, locked int
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			attempt, lockouts, err := throttler.begin(email, ip)
+			mu.Lock()
+			locked += len(lockouts)
+			mu.Unlock()
+			if err != nil {
+				var throttled *ThrottledError
+				if !errors.As(err, &throttled) {
+					t.Errorf("begin: %v", err)
+				}
+				return
+			}
+
+			mu.Lock()
+			checked++
+			mu.Unlock()
+
+			lockouts, err = throttler.fail(attempt)
+			if err != nil {
+				t.Errorf("fail: %v", err)
+			}
+			mu.Lock()
+			locked += len(lockouts)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if checked > 5 {
+		t.Errorf("%d of 50 parallel attempts were checked, want at most 5", checked)
+	}
+	if locked == 0 {
+		t.Error("the account was not locked")
+	}
+
+	_, _, err := throttler.begin(email, ip)
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || !throttled.Locked {
+		t.Errorf("attempt after the burst: got %v, want a lockout", err)
+	}
+}
+
+func TestParallelLoginsStopAtThreshold(t *testing.T) {
+	testParallelLoginsStopAtThreshold(t, NewMemoryAttemptStore())
+}
+
+func TestParallelLoginsStopAtThresholdPostgres(t *testing.T) {
+	testParallelLoginsStopAtThreshold(t, NewPostgresAttemptStore(dbtest.Open(t)))
+}
+
+func TestLoginBackoff(t *testing.T) {
+	throttler := newTestThrottler(NewMemoryAttemptStore(), time.Minute)
+
+	for i := 0; i < loginFreeAttempts; i++ {
+		attempt, _, err := throttler.begin("backoff@example.com", "192.0.2.1")
+		if err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+		if _, err := throttler.fail(attempt); err != nil {
+			t.Fatalf("fail: %v", err)
+		}
+	}
+
+	_, _, err := throttler.begin("backoff@example.com", "192.0.2.1")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.Locked || throttled.RetryAfter <= 0 {
+		t.Fatalf("got %v, want a backoff", err)
+	}
+
+	// The refused attempt counts too
+	state, err := throttler.store.AddAttempt(emailAttemptKey("backoff@example.com"), time.Now().UTC(), time.Hour)
+	if err != nil {
+		t.Fatalf("AddAttempt: %v", err)
+	}
+	if state.Failures != loginFreeAttempts+2 {
+		t.Errorf("got %d attempts counted, want %d", state.Failures, loginFreeAttempts+2)
+	}
+}
+
+// A successful login clears the account's counter, but the IP only takes
+// back the successful attempt.
+func TestLoginSuccess(t *testing.T) {
+	store := NewMemoryAttemptStore()
+	throttler := newTestThrottler(store, 0)
+
+	for i := 0; i < 2; i++ {
+		attempt, _, err := throttler.begin("success@example.com", "192.0.2.2")
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		if _, err := throttler.fail(attempt); err != nil {
+			t.Fatalf("fail: %v", err)
+		}
+	}
+
+	attempt, _, err := throttler.begin("success@example.com", "192.0.2.2")
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := throttler.succeed(attempt); err != nil {
+		t.Fatalf("succeed: %v", err)
+	}
+
+	if state := store.entries[emailAttemptKey("success@example.com")]; state != nil {
+		t.Errorf("account still has %d attempts counted", state.Failures)
+	}
+	if state := store.entries[ipAttemptKey("192.0.2.2")]; state == nil || state.Failures != 2 {
+		t.Errorf("IP has %v counted, want the 2 failures", state)
+	}
+}
@@ -0,0 +1,178 @@
+package carts
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetCarts(c *gin.Context) {
+	carts, err := h.service.GetCarts(restaurants.ActorFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, carts)
+}
+
+func (h *Handler) Open(c *gin.Context) {
+	var req OpenCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.Open(restaurants.ActorFromContext(c), &req)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) Get(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	cart, err := h.service.Get(restaurants.ActorFromContext(c), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// Quote prices a cart with tax and fees, delivered to the lat and lng of
+// the query if given.
+func (h *Handler) Quote(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var location *restaurants.GeoPoint
+	if lat, lng := c.Query("lat"), c.Query("lng"); lat != "" || lng != "" {
+		point := restaurants.GeoPoint{}
+		var errLat, errLng error
+		point.Lat, errLat = strconv.ParseFloat(lat, 64)
+		point.Lng, errLng = strconv.ParseFloat(lng, 64)
+		if errLat != nil || errLng != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng must be valid coordinates"})
+			return
+		}
+		location = &point
+	}
+
+	cart, err := h.service.Quote(restaurants.ActorFromContext(c), id, location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) Delete(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(restaurants.ActorFromContext(c), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "cart deleted successfully"})
+}
+
+func (h *Handler) AddLine(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req AddLineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.AddLine(restaurants.ActorFromContext(c), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) UpdateLine(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	lineID, ok := paramID(c, "lineId")
+	if !ok {
+		return
+	}
+
+	var req UpdateLineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.UpdateLine(restaurants.ActorFromContext(c), id, lineID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) DeleteLine(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	lineID, ok := paramID(c, "lineId")
+	if !ok {
+		return
+	}
+
+	cart, err := h.service.DeleteLine(restaurants.ActorFromContext(c), id, lineID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// paramID parses a numeric path parameter, answering 400 if it is not one.
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
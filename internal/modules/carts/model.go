@@ -0,0 +1,80 @@
+package carts
+
+import (
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/pricing"
+)
+
+const (
+	StatusActive  = "active"
+	StatusOrdered = "ordered"
+)
+
+// Cart is a customer's order in progress at one restaurant. Prices and
+// validity are worked out from the current menu every time it is read;
+// Subtotal counts only the valid lines, and IsValid is false while any line
+// cannot be ordered. Breakdown, the tax and fees on top, is set only on a
+// quote.
+type Cart struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"-"`
+	RestaurantID   int64     `json:"restaurant_id"`
+	RestaurantSlug string    `json:"restaurant_slug"`
+	RestaurantName string    `json:"restaurant_name"`
+	Status         string    `json:"status"`
+	Lines          []*Line   `json:"lines"`
+	Currency       string    `json:"currency"` // The restaurant's
+	Subtotal       int64     `json:"subtotal"`
+	ItemCount      int       `json:"item_count"`
+	IsValid        bool      `json:"is_valid"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	Breakdown *pricing.Breakdown `json:"breakdown,omitempty"`
+}
+
+// Line is a dish with its chosen modifier options. Problem says why an
+// invalid line cannot be ordered.
+type Line struct {
+	ID        int64         `json:"id"`
+	CartID    int64         `json:"-"`
+	ItemID    int64         `json:"item_id"`
+	Quantity  int           `json:"quantity"`
+	OptionIDs []int64       `json:"option_ids"`
+	Notes     *string       `json:"notes,omitempty"`
+	Name      string        `json:"name"`
+	Options   []*LineOption `json:"options"`
+	UnitPrice int64         `json:"unit_price"`
+	Total     int64         `json:"total"`
+	TaxRateBP int           `json:"tax_rate_bp"` // Of the dish's category or else the restaurant
+	IsValid   bool          `json:"is_valid"`
+	Problem   string        `json:"problem,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type LineOption struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Price int64  `json:"price"`
+}
+
+// OpenCartRequest returns the active cart at a restaurant, creating it if
+// needed.
+type OpenCartRequest struct {
+	RestaurantSlug string `json:"restaurant_slug" binding:"required"`
+}
+
+type AddLineRequest struct {
+	ItemID    int64   `json:"item_id" binding:"required"`
+	Quantity  int     `json:"quantity" binding:"required,min=1,max=99"`
+	OptionIDs []int64 `json:"option_ids"`
+	Notes     *string `json:"notes" binding:"omitempty,max=500"`
+}
+
+type UpdateLineRequest struct {
+	Quantity  *int    `json:"quantity" binding:"omitempty,min=1,max=99"`
+	OptionIDs []int64 `json:"option_ids"`
+	Notes     *string `json:"notes" binding:"omitempty,max=500"`
+}
@@ -0,0 +1,198 @@
+package carts
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yourcompany/saas-platform/internal/modules/menus"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/money"
+	"github.com/yourcompany/saas-platform/internal/pricing"
+)
+
+// menuIndex looks up the dishes of a menu, and the tax rates of their
+// categories, by ID.
+type menuIndex struct {
+	items    map[int64]*menus.Item
+	taxRates map[int64]*int
+}
+
+func newMenuIndex(menu *menus.Menu) *menuIndex {
+	index := &menuIndex{items: make(map[int64]*menus.Item), taxRates: make(map[int64]*int)}
+	for _, category := range menu.Categories {
+		for _, item := range category.Items {
+			index.items[item.ID] = item
+			index.taxRates[item.ID] = category.TaxRateBP
+		}
+	}
+	return index
+}
+
+// price works out the lines and totals of a cart from the current menu,
+// never from anything the client sent. A line that cannot be ordered any
+// more stays in the cart, marked invalid with the reason. Everything is in
+// the restaurant's currency; a dish priced in another one cannot be ordered.
+func price(cart *Cart, restaurant *restaurants.Restaurant, rules *pricing.Rules, index *menuIndex) {
+	cart.RestaurantSlug = restaurant.Slug
+	cart.RestaurantName = restaurant.Name
+	cart.Currency = restaurant.Currency
+	cart.Subtotal = 0
+	cart.ItemCount = 0
+	cart.IsValid = len(cart.Lines) > 0
+
+	// Every line of a dish draws on the same daily stock
+	ordered := make(map[int64]int)
+	for _, line := range cart.Lines {
+		ordered[line.ItemID] += line.Quantity
+	}
+
+	subtotal := money.Zero(restaurant.Currency)
+	for _, line := range cart.Lines {
+		item := index.items[line.ItemID]
+		problem := priceLine(line, item)
+		line.TaxRateBP = rules.TaxRate(pricing.Line{TaxRateBP: index.taxRates[line.ItemID]})
+		switch {
+		case problem != "":
+		case !restaurant.IsActive:
+			problem = "restaurant is not accepting orders"
+		case item.StockRemaining != nil && ordered[item.ID] > *item.StockRemaining:
+			problem = fmt.Sprintf("only %d left today", *item.StockRemaining)
+		case item.Currency != restaurant.Currency:
+			problem = fmt.Sprintf("item is priced in %s, the restaurant in %s", item.Currency, restaurant.Currency)
+		}
+
+		var sum money.Money
+		if problem == "" {
+			var err error
+			if sum, err = subtotal.Add(money.Money{Amount: line.Total, Currency: item.Currency}); err != nil {
+				problem = "the cart total is too large"
+			}
+		}
+
+		line.IsValid = problem == ""
+		line.Problem = problem
+		if !line.IsValid {
+			cart.IsValid = false
+			continue
+		}
+
+		subtotal = sum
+		cart.ItemCount += line.Quantity
+	}
+	cart.Subtotal = subtotal.Amount
+}
+
+// quote works out the tax and fees on the valid lines of a priced cart.
+// distanceM is how far it is delivered, if known.
+func quote(cart *Cart, rules *pricing.Rules, distanceM *float64) (*pricing.Breakdown, error) {
+	input := &pricing.Input{Currency: cart.Currency, DistanceM: distanceM}
+	for _, line := range cart.Lines {
+		if !line.IsValid {
+			continue
+		}
+		rate := line.TaxRateBP
+		input.Lines = append(input.Lines, pricing.Line{
+			Amount:    money.Money{Amount: line.Total, Currency: cart.Currency},
+			TaxRateBP: &rate,
+		})
+	}
+
+	return pricing.Calculate(rules, input)
+}
+
+// priceLine fills in the name, options and prices of a line and returns why
+// it cannot be ordered, if it cannot. item is nil once the dish has left the
+// menu.
+func priceLine(line *Line, item *menus.Item) string {
+	line.Name = ""
+	line.Options = []*LineOption{}
+	line.UnitPrice = 0
+	line.Total = 0
+
+	if item == nil {
+		return "item is no longer on the menu"
+	}
+
+	line.Name = item.Name
+	unit := money.Money{Amount: item.Price, Currency: item.Currency}
+
+	options := make(map[int64]*menus.ModifierOption)
+	groupIDs := make(map[int64]int64)
+	for _, group := range item.ModifierGroups {
+		for _, option := range group.Options {
+			options[option.ID] = option
+			groupIDs[option.ID] = group.ID
+		}
+	}
+
+	var problem string
+	chosen := make(map[int64]int)
+	for _, id := range line.OptionIDs {
+		option, ok := options[id]
+		if !ok {
+			if problem == "" {
+				problem = "a chosen option is no longer on the menu"
+			}
+			continue
+		}
+
+		line.Options = append(line.Options, &LineOption{ID: option.ID, Name: option.Name, Price: option.Price})
+		chosen[groupIDs[id]]++
+		// Options are priced in the currency of their dish
+		if sum, err := unit.Add(money.Money{Amount: option.Price, Currency: item.Currency}); err == nil {
+			unit = sum
+		} else if problem == "" {
+			problem = "the price is too large"
+		}
+		if problem == "" && !option.IsAvailable {
+			problem = fmt.Sprintf("%q is unavailable", option.Name)
+		}
+	}
+	total, err := unit.Mul(int64(line.Quantity))
+	if err != nil && problem == "" {
+		problem = "the price is too large"
+	}
+	line.UnitPrice = unit.Amount
+	line.Total = total.Amount
+
+	if !item.IsAvailable {
+		return "item is unavailable"
+	}
+	if problem != "" {
+		return problem
+	}
+
+	for _, group := range item.ModifierGroups {
+		if n := chosen[group.ID]; n < group.MinSelections || n > group.MaxSelections {
+			return selectionProblem(group)
+		}
+	}
+
+	return ""
+}
+
+func selectionProblem(group *menus.ModifierGroup) string {
+	switch {
+	case group.MinSelections == group.MaxSelections:
+		return fmt.Sprintf("choose %d of %q", group.MinSelections, group.Name)
+	case group.MinSelections == 0:
+		return fmt.Sprintf("choose at most %d of %q", group.MaxSelections, group.Name)
+	default:
+		return fmt.Sprintf("choose %d to %d of %q", group.MinSelections, group.MaxSelections, group.Name)
+	}
+}
+
+// normalizeOptions sorts option IDs, so that equal choices compare equal,
+// and rejects an option chosen twice.
+func normalizeOptions(ids []int64) ([]int64, error) {
+	normalized := append([]int64{}, ids...)
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i] < normalized[j] })
+
+	for i := 1; i < len(normalized); i++ {
+		if normalized[i] == normalized[i-1] {
+			return nil, fmt.Errorf("option %d is chosen twice", normalized[i])
+		}
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,298 @@
+package carts
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/yourcompany/saas-platform/internal/modules/menus"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/pricing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// testMenu has a burger with a required size and optional extras in a
+// category taxed at 7%, and a few other dishes under the restaurant's rate.
+func testMenu() *menus.Menu {
+	return &menus.Menu{Categories: []*menus.Category{
+		{
+			ID:        1,
+			TaxRateBP: intPtr(700),
+			Items: []*menus.Item{{
+				ID: 1, Name: "Burger", Price: 1000, Currency: "EUR", IsAvailable: true,
+				ModifierGroups: []*menus.ModifierGroup{
+					{ID: 10, Name: "Size", MinSelections: 1, MaxSelections: 1, Options: []*menus.ModifierOption{
+						{ID: 100, Name: "Small", Price: 0, IsAvailable: true},
+						{ID: 101, Name: "Large", Price: 150, IsAvailable: true},
+					}},
+					{ID: 11, Name: "Extras", MinSelections: 0, MaxSelections: 1, Options: []*menus.ModifierOption{
+						{ID: 110, Name: "Cheese", Price: 100, IsAvailable: true},
+						{ID: 111, Name: "Bacon", Price: 200, IsAvailable: false},
+					}},
+				},
+			}},
+		},
+		{
+			ID: 2,
+			Items: []*menus.Item{
+				{ID: 2, Name: "Soup", Price: 500, Currency: "EUR", IsAvailable: true, StockRemaining: intPtr(3)},
+				{ID: 3, Name: "Pie", Price: 400, Currency: "EUR", IsAvailable: false},
+				{ID: 4, Name: "Shake", Price: 300, Currency: "USD", IsAvailable: true},
+				{ID: 5, Name: "Caviar", Price: math.MaxInt64, Currency: "EUR", IsAvailable: true},
+			},
+		},
+	}}
+}
+
+func TestPrice(t *testing.T) {
+	type wantLine struct {
+		unitPrice int64
+		total     int64
+		taxRateBP int
+		problem   string
+	}
+
+	tests := []struct {
+		name         string
+		inactive     bool
+		lines        []*Line
+		want         []wantLine
+		wantSubtotal int64
+		wantCount    int
+	}{
+		{
+			name:         "options are added to the price of each dish",
+			lines:        []*Line{{ItemID: 1, Quantity: 2, OptionIDs: []int64{101, 110}}},
+			want:         []wantLine{{unitPrice: 1250, total: 2500, taxRateBP: 700}},
+			wantSubtotal: 2500,
+			wantCount:    2,
+		},
+		{
+			name: "dishes without a category rate take the restaurant's",
+			lines: []*Line{
+				{ItemID: 1, Quantity: 1, OptionIDs: []int64{100}},
+				{ItemID: 2, Quantity: 3},
+			},
+			want: []wantLine{
+				{unitPrice: 1000, total: 1000, taxRateBP: 700},
+				{unitPrice: 500, total: 1500, taxRateBP: 1900},
+			},
+			wantSubtotal: 2500,
+			wantCount:    4,
+		},
+		{
+			name: "an invalid line is left out of the subtotal",
+			lines: []*Line{
+				{ItemID: 2, Quantity: 1},
+				{ItemID: 99, Quantity: 1},
+			},
+			want: []wantLine{
+				{unitPrice: 500, total: 500, taxRateBP: 1900},
+				{taxRateBP: 1900, problem: "item is no longer on the menu"},
+			},
+			wantSubtotal: 500,
+			wantCount:    1,
+		},
+		{
+			name:  "an unavailable dish",
+			lines: []*Line{{ItemID: 3, Quantity: 1}},
+			want:  []wantLine{{unitPrice: 400, total: 400, taxRateBP: 1900, problem: "item is unavailable"}},
+		},
+		{
+			name:  "an option no longer on the menu",
+			lines: []*Line{{ItemID: 1, Quantity: 1, OptionIDs: []int64{100, 999}}},
+			want:  []wantLine{{unitPrice: 1000, total: 1000, taxRateBP: 700, problem: "a chosen option is no longer on the menu"}},
+		},
+		{
+			name:  "an unavailable option",
+			lines: []*Line{{ItemID: 1, Quantity: 1, OptionIDs: []int64{100, 111}}},
+			want:  []wantLine{{unitPrice: 1200, total: 1200, taxRateBP: 700, problem: `"Bacon" is unavailable`}},
+		},
+		{
+			name:  "a required group left out",
+			lines: []*Line{{ItemID: 1, Quantity: 1, OptionIDs: []int64{110}}},
+			want:  []wantLine{{unitPrice: 1100, total: 1100, taxRateBP: 700, problem: `choose 1 of "Size"`}},
+		},
+		{
+			name:  "too many options of a group",
+			lines: []*Line{{ItemID: 1, Quantity: 1, OptionIDs: []int64{100, 101}}},
+			want:  []wantLine{{unitPrice: 1150, total: 1150, taxRateBP: 700, problem: `choose 1 of "Size"`}},
+		},
+		{
+			name: "lines of one dish share its stock",
+			lines: []*Line{
+				{ItemID: 2, Quantity: 2},
+				{ItemID: 2, Quantity: 2, Notes: new(string)},
+			},
+			want: []wantLine{
+				{unitPrice: 500, total: 1000, taxRateBP: 1900, problem: "only 3 left today"},
+				{unitPrice: 500, total: 1000, taxRateBP: 1900, problem: "only 3 left today"},
+			},
+		},
+		{
+			name:     "an inactive restaurant",
+			inactive: true,
+			lines:    []*Line{{ItemID: 2, Quantity: 1}},
+			want:     []wantLine{{unitPrice: 500, total: 500, taxRateBP: 1900, problem: "restaurant is not accepting orders"}},
+		},
+		{
+			name:  "a dish in another currency",
+			lines: []*Line{{ItemID: 4, Quantity: 1}},
+			want:  []wantLine{{unitPrice: 300, total: 300, taxRateBP: 1900, problem: "item is priced in USD, the restaurant in EUR"}},
+		},
+		{
+			name:  "a total that overflows",
+			lines: []*Line{{ItemID: 5, Quantity: 2}},
+			want:  []wantLine{{unitPrice: math.MaxInt64, taxRateBP: 1900, problem: "the price is too large"}},
+		},
+		{
+			name: "a subtotal that overflows",
+			lines: []*Line{
+				{ItemID: 5, Quantity: 1},
+				{ItemID: 2, Quantity: 1},
+			},
+			want: []wantLine{
+				{unitPrice: math.MaxInt64, total: math.MaxInt64, taxRateBP: 1900},
+				{unitPrice: 500, total: 500, taxRateBP: 1900, problem: "the cart total is too large"},
+			},
+			wantSubtotal: math.MaxInt64,
+			wantCount:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restaurant := &restaurants.Restaurant{Name: "Test", Slug: "test", Currency: "EUR", IsActive: !tt.inactive}
+			cart := &Cart{Lines: tt.lines}
+
+			price(cart, restaurant, &pricing.Rules{TaxRateBP: 1900}, newMenuIndex(testMenu()))
+
+			wantValid := true
+			for i, want := range tt.want {
+				line := cart.Lines[i]
+				if line.UnitPrice != want.unitPrice || line.Total != want.total {
+					t.Errorf("line %d: got %d x %d = %d, want unit price %d, total %d",
+						i, line.UnitPrice, line.Quantity, line.Total, want.unitPrice, want.total)
+				}
+				if line.TaxRateBP != want.taxRateBP {
+					t.Errorf("line %d: got tax rate %d, want %d", i, line.TaxRateBP, want.taxRateBP)
+				}
+				if line.Problem != want.problem || line.IsValid != (want.problem == "") {
+					t.Errorf("line %d: got valid %v, problem %q; want problem %q", i, line.IsValid, line.Problem, want.problem)
+				}
+				wantValid = wantValid && want.problem == ""
+			}
+
+			if cart.Subtotal != tt.wantSubtotal || cart.ItemCount != tt.wantCount {
+				t.Errorf("got subtotal %d of %d items, want %d of %d", cart.Subtotal, cart.ItemCount, tt.wantSubtotal, tt.wantCount)
+			}
+			if cart.IsValid != wantValid {
+				t.Errorf("cart valid: got %v, want %v", cart.IsValid, wantValid)
+			}
+			if cart.Currency != "EUR" || cart.RestaurantSlug != "test" {
+				t.Errorf("got cart of %q in %s, want test in EUR", cart.RestaurantSlug, cart.Currency)
+			}
+		})
+	}
+}
+
+func TestPriceEmptyCart(t *testing.T) {
+	cart := &Cart{}
+	price(cart, &restaurants.Restaurant{Currency: "EUR", IsActive: true}, &pricing.Rules{}, newMenuIndex(testMenu()))
+
+	if cart.IsValid {
+		t.Error("an empty cart is valid")
+	}
+}
+
+func TestPriceLineNames(t *testing.T) {
+	line := &Line{ItemID: 1, Quantity: 1, OptionIDs: []int64{101, 110}}
+	if problem := priceLine(line, newMenuIndex(testMenu()).items[1]); problem != "" {
+		t.Fatalf("priceLine: %s", problem)
+	}
+
+	if line.Name != "Burger" {
+		t.Errorf("got name %q, want Burger", line.Name)
+	}
+	var names []string
+	for _, option := range line.Options {
+		names = append(names, option.Name)
+	}
+	if !slices.Equal(names, []string{"Large", "Cheese"}) {
+		t.Errorf("got options %v, want [Large Cheese]", names)
+	}
+}
+
+func TestSelectionProblem(t *testing.T) {
+	tests := []struct {
+		min, max int
+		want     string
+	}{
+		{1, 1, `choose 1 of "Sauce"`},
+		{0, 2, `choose at most 2 of "Sauce"`},
+		{1, 3, `choose 1 to 3 of "Sauce"`},
+	}
+
+	for _, tt := range tests {
+		group := &menus.ModifierGroup{Name: "Sauce", MinSelections: tt.min, MaxSelections: tt.max}
+		if got := selectionProblem(group); got != tt.want {
+			t.Errorf("%d..%d: got %q, want %q", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeOptions(t *testing.T) {
+	ids := []int64{30, 10, 20}
+	got, err := normalizeOptions(ids)
+	if err != nil {
+		t.Fatalf("normalizeOptions: %v", err)
+	}
+	if !slices.Equal(got, []int64{10, 20, 30}) {
+		t.Errorf("got %v, want [10 20 30]", got)
+	}
+	if !slices.Equal(ids, []int64{30, 10, 20}) {
+		t.Errorf("the IDs passed in were reordered to %v", ids)
+	}
+
+	if _, err := normalizeOptions([]int64{10, 20, 10}); err == nil {
+		t.Error("an option chosen twice was accepted")
+	}
+}
+
+func TestSameLine(t *testing.T) {
+	notes := "no onions"
+	other := "extra onions"
+	cart := &Cart{Lines: []*Line{
+		{ID: 1, ItemID: 1, OptionIDs: []int64{100}},
+		{ID: 2, ItemID: 1, OptionIDs: []int64{100}, Notes: &notes},
+		{ID: 3, ItemID: 1, OptionIDs: []int64{101, 110}},
+	}}
+
+	tests := []struct {
+		name      string
+		itemID    int64
+		optionIDs []int64
+		notes     *string
+		want      int64
+	}{
+		{"same dish and options", 1, []int64{100}, nil, 1},
+		{"same notes", 1, []int64{100}, &notes, 2},
+		{"other notes", 1, []int64{100}, &other, 0},
+		{"other options", 1, []int64{101}, nil, 0},
+		{"several options", 1, []int64{101, 110}, nil, 3},
+		{"other dish", 2, []int64{100}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		var got int64
+		if line := sameLine(cart, tt.itemID, tt.optionIDs, tt.notes); line != nil {
+			got = line.ID
+		}
+		if got != tt.want {
+			t.Errorf("%s: got line %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package carts
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const cartColumns = `id, user_id, restaurant_id, status, created_at, updated_at`
+
+func scanCart(row rowScanner) (*Cart, error) {
+	cart := &Cart{Lines: []*Line{}}
+
+	err := row.Scan(
+		&cart.ID,
+		&cart.UserID,
+		&cart.RestaurantID,
+		&cart.Status,
+		&cart.CreatedAt,
+		&cart.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// Open returns the active cart of a user at a restaurant, creating an empty
+// one if there is none.
+func (r *Repository) Open(userID, restaurantID int64) (*Cart, error) {
+	_, err := r.db.Exec(`
+		INSERT INTO carts (user_id, restaurant_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, restaurant_id) WHERE status = 'active' DO NOTHING
+	`, userID, restaurantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cart: %w", err)
+	}
+
+	cart, err := scanCart(r.db.QueryRow(
+		`SELECT `+cartColumns+` FROM carts WHERE user_id = $1 AND restaurant_id = $2 AND status = 'active'`,
+		userID, restaurantID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	return cart, r.loadLines([]*Cart{cart})
+}
+
+// Get returns an active cart of a user with its lines.
+func (r *Repository) Get(userID, id int64) (*Cart, error) {
+	cart, err := scanCart(r.db.QueryRow(
+		`SELECT `+cartColumns+` FROM carts WHERE id = $1 AND user_id = $2 AND status = 'active'`,
+		id, userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("cart not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	return cart, r.loadLines([]*Cart{cart})
+}
+
+// GetActive returns the active carts of a user, most recently changed first.
+func (r *Repository) GetActive(userID int64) ([]*Cart, error) {
+	rows, err := r.db.Query(
+		`SELECT `+cartColumns+` FROM carts WHERE user_id = $1 AND status = 'active' ORDER BY updated_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get carts: %w", err)
+	}
+	defer rows.Close()
+
+	carts := []*Cart{}
+	for rows.Next() {
+		cart, err := scanCart(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cart: %w", err)
+		}
+		carts = append(carts, cart)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return carts, r.loadLines(carts)
+}
+
+// Delete removes an active cart with its lines.
+func (r *Repository) Delete(userID, id int64) error {
+	result, err := r.db.Exec(`DELETE FROM carts WHERE id = $1 AND user_id = $2 AND status = 'active'`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("cart not found")
+	}
+
+	return nil
+}
+
+// Lines
+
+const lineColumns = `id, cart_id, item_id, quantity, option_ids, notes, created_at, updated_at`
+
+func scanLine(row rowScanner) (*Line, error) {
+	line := &Line{}
+	var notes sql.NullString
+
+	err := row.Scan(
+		&line.ID,
+		&line.CartID,
+		&line.ItemID,
+		&line.Quantity,
+		pq.Array(&line.OptionIDs),
+		&notes,
+		&line.CreatedAt,
+		&line.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if line.OptionIDs == nil {
+		line.OptionIDs = []int64{}
+	}
+	if notes.Valid {
+		line.Notes = &notes.String
+	}
+
+	return line, nil
+}
+
+// loadLines fills in the lines of carts in the order they were added.
+func (r *Repository) loadLines(carts []*Cart) error {
+	if len(carts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(carts))
+	cartByID := make(map[int64]*Cart, len(carts))
+	for i, cart := range carts {
+		ids[i] = cart.ID
+		cartByID[cart.ID] = cart
+	}
+
+	rows, err := r.db.Query(
+		`SELECT `+lineColumns+` FROM cart_lines WHERE cart_id = ANY($1) ORDER BY id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get cart lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line, err := scanLine(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan cart line: %w", err)
+		}
+		cartByID[line.CartID].Lines = append(cartByID[line.CartID].Lines, line)
+	}
+
+	return rows.Err()
+}
+
+func (r *Repository) CreateLine(line *Line) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO cart_lines (cart_id, item_id, quantity, option_ids, notes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, line.CartID, line.ItemID, line.Quantity, pq.Array(line.OptionIDs), line.Notes,
+	).Scan(&line.ID, &line.CreatedAt, &line.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create cart line: %w", err)
+	}
+
+	if err := touch(tx, line.CartID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateLine(line *Line) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE cart_lines
+		SET quantity = $1, option_ids = $2, notes = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND cart_id = $5
+		RETURNING updated_at
+	`, line.Quantity, pq.Array(line.OptionIDs), line.Notes, line.ID, line.CartID,
+	).Scan(&line.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("cart line not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update cart line: %w", err)
+	}
+
+	if err := touch(tx, line.CartID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteLine(cartID, id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM cart_lines WHERE id = $1 AND cart_id = $2`, id, cartID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cart line: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("cart line not found")
+	}
+
+	if err := touch(tx, cartID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// touch marks a cart as changed, so that the list of carts shows the latest
+// first.
+func touch(tx *sql.Tx, cartID int64) error {
+	if _, err := tx.Exec(`UPDATE carts SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, cartID); err != nil {
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package carts
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yourcompany/saas-platform/internal/modules/menus"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/pricing"
+)
+
+// maxQuantity caps one line, matching the request bindings.
+const maxQuantity = 99
+
+type Service struct {
+	repo        *Repository
+	restaurants *restaurants.Service
+	menus       *menus.Service
+}
+
+func NewService(repo *Repository, restaurantsService *restaurants.Service, menusService *menus.Service) *Service {
+	return &Service{
+		repo:        repo,
+		restaurants: restaurantsService,
+		menus:       menusService,
+	}
+}
+
+// GetCarts returns the active carts of the current user.
+func (s *Service) GetCarts(actor *restaurants.Actor) ([]*Cart, error) {
+	carts, err := s.repo.GetActive(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cart := range carts {
+		if _, _, err := s.price(cart); err != nil {
+			return nil, err
+		}
+	}
+
+	return carts, nil
+}
+
+// Open returns the user's active cart at a restaurant, creating it if
+// needed. Only active restaurants take new carts.
+func (s *Service) Open(actor *restaurants.Actor, req *OpenCartRequest) (*Cart, error) {
+	restaurant, err := s.restaurants.GetActiveBySlug(req.RestaurantSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.repo.Open(actor.UserID, restaurant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := s.price(cart); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *Service) Get(actor *restaurants.Actor, id int64) (*Cart, error) {
+	cart, err := s.repo.Get(actor.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := s.price(cart); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// Quote returns a cart of the current user with the tax and fees it would
+// be ordered with when delivered to location. Only restaurants that charge
+// for delivery by distance need the location.
+func (s *Service) Quote(actor *restaurants.Actor, id int64, location *restaurants.GeoPoint) (*Cart, error) {
+	cart, err := s.repo.Get(actor.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	restaurant, rules, err := s.price(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	var distanceM *float64
+	if location != nil && len(rules.DeliveryTiers) > 0 {
+		distance, err := restaurant.DistanceTo(*location)
+		if err != nil {
+			return nil, err
+		}
+		distanceM = &distance
+	}
+
+	if cart.Breakdown, err = quote(cart, rules, distanceM); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *Service) Delete(actor *restaurants.Actor, id int64) error {
+	return s.repo.Delete(actor.UserID, id)
+}
+
+// AddLine adds a dish to a cart and returns the cart. The same dish with the
+// same options and notes adds to the quantity of the existing line.
+func (s *Service) AddLine(actor *restaurants.Actor, cartID int64, req *AddLineRequest) (*Cart, error) {
+	cart, err := s.repo.Get(actor.UserID, cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	optionIDs, err := normalizeOptions(req.OptionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	line := sameLine(cart, req.ItemID, optionIDs, req.Notes)
+	if line != nil {
+		line.Quantity += req.Quantity
+		if line.Quantity > maxQuantity {
+			return nil, fmt.Errorf("quantity cannot exceed %d", maxQuantity)
+		}
+	} else {
+		line = &Line{
+			CartID:    cart.ID,
+			ItemID:    req.ItemID,
+			Quantity:  req.Quantity,
+			OptionIDs: optionIDs,
+			Notes:     req.Notes,
+		}
+		cart.Lines = append(cart.Lines, line)
+	}
+
+	if err := s.check(cart, line); err != nil {
+		return nil, err
+	}
+
+	if line.ID == 0 {
+		err = s.repo.CreateLine(line)
+	} else {
+		err = s.repo.UpdateLine(line)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *Service) UpdateLine(actor *restaurants.Actor, cartID, lineID int64, req *UpdateLineRequest) (*Cart, error) {
+	cart, err := s.repo.Get(actor.UserID, cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	var line *Line
+	for _, candidate := range cart.Lines {
+		if candidate.ID == lineID {
+			line = candidate
+		}
+	}
+	if line == nil {
+		return nil, errors.New("cart line not found")
+	}
+
+	if req.Quantity != nil {
+		line.Quantity = *req.Quantity
+	}
+	if req.OptionIDs != nil {
+		if line.OptionIDs, err = normalizeOptions(req.OptionIDs); err != nil {
+			return nil, err
+		}
+	}
+	if req.Notes != nil {
+		line.Notes = req.Notes
+	}
+
+	if err := s.check(cart, line); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateLine(line); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *Service) DeleteLine(actor *restaurants.Actor, cartID, lineID int64) (*Cart, error) {
+	if _, err := s.repo.Get(actor.UserID, cartID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteLine(cartID, lineID); err != nil {
+		return nil, err
+	}
+
+	return s.Get(actor, cartID)
+}
+
+// price works out a cart from its restaurant, its tax rates and the current
+// menu, and returns the restaurant and its pricing rules.
+func (s *Service) price(cart *Cart) (*restaurants.Restaurant, *pricing.Rules, error) {
+	restaurant, err := s.restaurants.Lookup(cart.RestaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rules, err := s.restaurants.LookupPricing(cart.RestaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	menu, err := s.menus.LookupMenu(cart.RestaurantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	price(cart, restaurant, rules, newMenuIndex(menu))
+	return restaurant, rules, nil
+}
+
+// check prices a cart after a change to line and rejects the change if that
+// line cannot be ordered. Other lines may stay invalid.
+func (s *Service) check(cart *Cart, line *Line) error {
+	if _, _, err := s.price(cart); err != nil {
+		return err
+	}
+
+	if !line.IsValid {
+		return errors.New(line.Problem)
+	}
+	return nil
+}
+
+// sameLine finds a line of the cart with the same dish, options and notes.
+func sameLine(cart *Cart, itemID int64, optionIDs []int64, notes *string) *Line {
+	for _, line := range cart.Lines {
+		if line.ItemID != itemID || len(line.OptionIDs) != len(optionIDs) {
+			continue
+		}
+		if (line.Notes == nil) != (notes == nil) || (notes != nil && *line.Notes != *notes) {
+			continue
+		}
+
+		same := true
+		for i := range optionIDs {
+			if line.OptionIDs[i] != optionIDs[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return line
+		}
+	}
+	return nil
+}
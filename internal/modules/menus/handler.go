@@ -0,0 +1,446 @@
+package menus
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourcompany/saas-platform/internal/httpcache"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetMenu(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	menu, err := h.service.GetMenu(restaurants.ActorFromContext(c), restaurantID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, menu)
+}
+
+// PublicGetMenu returns the menu tree of an active restaurant. No
+// authentication is required.
+func (h *Handler) PublicGetMenu(c *gin.Context) {
+	menu, err := h.service.GetPublicMenu(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The menu has no single modification time, so the ETag is a hash of the
+	// response itself. Availability changes at any moment, so clients
+	// revalidate every time rather than keep a sold out dish on sale.
+	body, err := json.Marshal(menu)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode menu"})
+		return
+	}
+	if httpcache.NotModifiedNoCache(c, fmt.Sprintf(`"%x"`, sha256.Sum256(body)), time.Time{}) {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func (h *Handler) CreateCategory(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.CreateCategory(restaurants.ActorFromContext(c), restaurantID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	categoryID, ok := paramID(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.UpdateCategory(restaurants.ActorFromContext(c), restaurantID, categoryID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *Handler) SetCategoryAvailability(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	categoryID, ok := paramID(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	var req AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.SetCategoryAvailability(restaurants.ActorFromContext(c), restaurantID, categoryID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	categoryID, ok := paramID(c, "categoryId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCategory(restaurants.ActorFromContext(c), restaurantID, categoryID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted successfully"})
+}
+
+func (h *Handler) CreateItem(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.CreateItem(restaurants.ActorFromContext(c), restaurantID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdateItem(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req UpdateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.UpdateItem(restaurants.ActorFromContext(c), restaurantID, itemID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) SetItemAvailability(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.SetItemAvailability(restaurants.ActorFromContext(c), restaurantID, itemID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) SetItemStock(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req StockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.SetItemStock(restaurants.ActorFromContext(c), restaurantID, itemID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteItem(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteItem(restaurants.ActorFromContext(c), restaurantID, itemID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "item deleted successfully"})
+}
+
+func (h *Handler) CreateModifierGroup(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+
+	var req CreateModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.service.CreateModifierGroup(restaurants.ActorFromContext(c), restaurantID, itemID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func (h *Handler) UpdateModifierGroup(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	groupID, ok := paramID(c, "groupId")
+	if !ok {
+		return
+	}
+
+	var req UpdateModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.service.UpdateModifierGroup(restaurants.ActorFromContext(c), restaurantID, groupID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *Handler) DeleteModifierGroup(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	groupID, ok := paramID(c, "groupId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteModifierGroup(restaurants.ActorFromContext(c), restaurantID, groupID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "modifier group deleted successfully"})
+}
+
+func (h *Handler) CreateModifierOption(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	groupID, ok := paramID(c, "groupId")
+	if !ok {
+		return
+	}
+
+	var req CreateModifierOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	option, err := h.service.CreateModifierOption(restaurants.ActorFromContext(c), restaurantID, groupID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, option)
+}
+
+func (h *Handler) UpdateModifierOption(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	optionID, ok := paramID(c, "optionId")
+	if !ok {
+		return
+	}
+
+	var req UpdateModifierOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	option, err := h.service.UpdateModifierOption(restaurants.ActorFromContext(c), restaurantID, optionID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, option)
+}
+
+func (h *Handler) SetModifierOptionAvailability(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	optionID, ok := paramID(c, "optionId")
+	if !ok {
+		return
+	}
+
+	var req AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	option, err := h.service.SetModifierOptionAvailability(restaurants.ActorFromContext(c), restaurantID, optionID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, option)
+}
+
+func (h *Handler) DeleteModifierOption(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	optionID, ok := paramID(c, "optionId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteModifierOption(restaurants.ActorFromContext(c), restaurantID, optionID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "modifier option deleted successfully"})
+}
+
+// paramID parses a numeric path parameter, answering 400 if it is not one.
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError answers restaurants.ErrForbidden with 403 and anything else
+// with status.
+func respondError(c *gin.Context, status int, err error) {
+	if errors.Is(err, restaurants.ErrForbidden) {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
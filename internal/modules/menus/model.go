@@ -0,0 +1,182 @@
+package menus
+
+import "time"
+
+// Menu is the whole menu tree of a restaurant.
+type Menu struct {
+	Categories []*Category `json:"categories"`
+}
+
+type Category struct {
+	ID           int64     `json:"id"`
+	RestaurantID int64     `json:"-"`
+	Name         string    `json:"name"`
+	Description  *string   `json:"description,omitempty"`
+	Position     int       `json:"position"`
+	TaxRateBP    *int      `json:"tax_rate_bp,omitempty"` // Overrides the restaurant's rate, in basis points
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Items        []*Item   `json:"items"`
+}
+
+// Item is a dish. Price is in minor units of Currency. IsAvailable is false
+// while the item is switched off or its daily stock has run out;
+// AvailableAt, if set, is when a switched off item comes back by itself.
+type Item struct {
+	ID             int64            `json:"id"`
+	RestaurantID   int64            `json:"-"`
+	CategoryID     int64            `json:"category_id"`
+	Name           string           `json:"name"`
+	Description    *string          `json:"description,omitempty"`
+	ImageURL       *string          `json:"image_url,omitempty"`
+	Price          int64            `json:"price"`
+	Currency       string           `json:"currency"`
+	IsAvailable    bool             `json:"is_available"`
+	AvailableAt    *time.Time       `json:"available_at,omitempty"`
+	DailyStock     *int             `json:"daily_stock,omitempty"`     // Unlimited if nil
+	StockRemaining *int             `json:"stock_remaining,omitempty"` // Left for today
+	Position       int              `json:"position"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	ModifierGroups []*ModifierGroup `json:"modifier_groups"`
+
+	enabled bool // The stored switch, without stock
+}
+
+// refreshAvailability switches the item back on once AvailableAt has passed
+// and works out IsAvailable from the switch and the stock left.
+func (item *Item) refreshAvailability(now time.Time) {
+	item.enabled, item.AvailableAt = restore(item.enabled, item.AvailableAt, now)
+	item.IsAvailable = item.enabled && (item.StockRemaining == nil || *item.StockRemaining > 0)
+}
+
+// ModifierGroup is a choice made for an item, such as a size or toppings.
+// A customer picks between MinSelections and MaxSelections of its options.
+type ModifierGroup struct {
+	ID            int64             `json:"id"`
+	RestaurantID  int64             `json:"-"`
+	ItemID        int64             `json:"item_id"`
+	Name          string            `json:"name"`
+	MinSelections int               `json:"min_selections"`
+	MaxSelections int               `json:"max_selections"`
+	Position      int               `json:"position"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Options       []*ModifierOption `json:"options"`
+}
+
+// ModifierOption adds Price, in the currency of its item, when chosen.
+type ModifierOption struct {
+	ID           int64      `json:"id"`
+	RestaurantID int64      `json:"-"`
+	GroupID      int64      `json:"group_id"`
+	Name         string     `json:"name"`
+	Price        int64      `json:"price"`
+	IsAvailable  bool       `json:"is_available"`
+	AvailableAt  *time.Time `json:"available_at,omitempty"`
+	Position     int        `json:"position"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	enabled bool
+}
+
+func (option *ModifierOption) refreshAvailability(now time.Time) {
+	option.enabled, option.AvailableAt = restore(option.enabled, option.AvailableAt, now)
+	option.IsAvailable = option.enabled
+}
+
+// restore applies an auto-restore time that has passed. A switched on entry
+// has no restore time.
+func restore(enabled bool, availableAt *time.Time, now time.Time) (bool, *time.Time) {
+	if !enabled && availableAt != nil && !availableAt.After(now) {
+		enabled = true
+	}
+	if enabled {
+		availableAt = nil
+	}
+	return enabled, availableAt
+}
+
+type CreateCategoryRequest struct {
+	Name        string  `json:"name" binding:"required,max=255"`
+	Description *string `json:"description"`
+	Position    int     `json:"position"`
+	TaxRateBP   *int    `json:"tax_rate_bp" binding:"omitempty,min=0,max=10000"`
+}
+
+// UpdateCategoryRequest clears the category's own tax rate with a
+// tax_rate_bp of -1, so that the restaurant's applies again.
+type UpdateCategoryRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+	Position    *int    `json:"position"`
+	TaxRateBP   *int    `json:"tax_rate_bp" binding:"omitempty,min=-1,max=10000"`
+}
+
+type CreateItemRequest struct {
+	CategoryID  int64   `json:"category_id" binding:"required"`
+	Name        string  `json:"name" binding:"required,max=255"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"image_url"`
+	Price       *int64  `json:"price" binding:"required,min=0"`
+	Currency    *string `json:"currency" binding:"omitempty,iso4217"` // The restaurant's currency if omitted
+	IsAvailable *bool   `json:"is_available"`
+	Position    int     `json:"position"`
+}
+
+type UpdateItemRequest struct {
+	CategoryID  *int64  `json:"category_id"`
+	Name        *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"image_url"`
+	Price       *int64  `json:"price" binding:"omitempty,min=0"`
+	Currency    *string `json:"currency" binding:"omitempty,iso4217"`
+	IsAvailable *bool   `json:"is_available"`
+	Position    *int    `json:"position"`
+}
+
+type CreateModifierGroupRequest struct {
+	Name          string `json:"name" binding:"required,max=255"`
+	MinSelections int    `json:"min_selections" binding:"min=0"`
+	MaxSelections int    `json:"max_selections" binding:"required,min=1"`
+	Position      int    `json:"position"`
+}
+
+type UpdateModifierGroupRequest struct {
+	Name          *string `json:"name" binding:"omitempty,min=1,max=255"`
+	MinSelections *int    `json:"min_selections" binding:"omitempty,min=0"`
+	MaxSelections *int    `json:"max_selections" binding:"omitempty,min=1"`
+	Position      *int    `json:"position"`
+}
+
+type CreateModifierOptionRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Price       int64  `json:"price" binding:"min=0"`
+	IsAvailable *bool  `json:"is_available"`
+	Position    int    `json:"position"`
+}
+
+type UpdateModifierOptionRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Price       *int64  `json:"price" binding:"omitempty,min=0"`
+	IsAvailable *bool   `json:"is_available"`
+	Position    *int    `json:"position"`
+}
+
+// AvailabilityRequest switches items or options on or off. When switching
+// off, RestoreAt or ForToday (until the restaurant's next local midnight)
+// bring them back automatically.
+type AvailabilityRequest struct {
+	IsAvailable *bool      `json:"is_available" binding:"required"`
+	RestoreAt   *time.Time `json:"restore_at"`
+	ForToday    bool       `json:"for_today"`
+}
+
+// StockRequest sets the daily stock of an item; a null DailyStock makes it
+// unlimited. Remaining overrides what is left today, which otherwise starts
+// from DailyStock.
+type StockRequest struct {
+	DailyStock *int `json:"daily_stock" binding:"omitempty,min=0"`
+	Remaining  *int `json:"remaining" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,67 @@
+package menus
+
+import (
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestRefreshAvailability(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name            string
+		enabled         bool
+		availableAt     *time.Time
+		stockRemaining  *int
+		wantAvailable   bool
+		wantAvailableAt *time.Time
+	}{
+		{name: "switched on", enabled: true, wantAvailable: true},
+		{name: "switched off for good", enabled: false},
+		{name: "switched off until later", enabled: false, availableAt: &future, wantAvailableAt: &future},
+		{name: "restore time has passed", enabled: false, availableAt: &past, wantAvailable: true},
+		{name: "restore time is now", enabled: false, availableAt: &now, wantAvailable: true},
+		{name: "stock left", enabled: true, stockRemaining: intPtr(2), wantAvailable: true},
+		{name: "sold out today", enabled: true, stockRemaining: intPtr(0)},
+		{name: "sold out and switched off", enabled: false, availableAt: &future, stockRemaining: intPtr(0), wantAvailableAt: &future},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &Item{enabled: tt.enabled, AvailableAt: tt.availableAt, StockRemaining: tt.stockRemaining}
+			item.refreshAvailability(now)
+
+			if item.IsAvailable != tt.wantAvailable {
+				t.Errorf("IsAvailable: got %v, want %v", item.IsAvailable, tt.wantAvailable)
+			}
+			if (item.AvailableAt == nil) != (tt.wantAvailableAt == nil) ||
+				(item.AvailableAt != nil && !item.AvailableAt.Equal(*tt.wantAvailableAt)) {
+				t.Errorf("AvailableAt: got %v, want %v", item.AvailableAt, tt.wantAvailableAt)
+			}
+		})
+	}
+}
+
+// An option has no stock of its own, so only its switch counts.
+func TestOptionRefreshAvailability(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+
+	option := &ModifierOption{enabled: false, AvailableAt: &past}
+	option.refreshAvailability(now)
+	if !option.IsAvailable || option.AvailableAt != nil {
+		t.Errorf("got available %v until %v, want available with no restore time", option.IsAvailable, option.AvailableAt)
+	}
+
+	option = &ModifierOption{enabled: false}
+	option.refreshAvailability(now)
+	if option.IsAvailable {
+		t.Error("a switched off option is available")
+	}
+}
@@ -0,0 +1,742 @@
+package menus
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// GetMenu loads the whole menu of a restaurant, ordered by position.
+func (r *Repository) GetMenu(restaurantID int64) (*Menu, error) {
+	menu := &Menu{Categories: []*Category{}}
+
+	categories, err := r.queryCategories(`SELECT `+categoryColumns+` FROM menu_categories WHERE restaurant_id = $1 ORDER BY position, id`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	categoryByID := make(map[int64]*Category, len(categories))
+	for _, category := range categories {
+		categoryByID[category.ID] = category
+		menu.Categories = append(menu.Categories, category)
+	}
+
+	items, err := r.queryItems(`SELECT `+itemColumns+` FROM menu_items WHERE restaurant_id = $1 ORDER BY position, id`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	itemByID := make(map[int64]*Item, len(items))
+	for _, item := range items {
+		itemByID[item.ID] = item
+		if category, ok := categoryByID[item.CategoryID]; ok {
+			category.Items = append(category.Items, item)
+		}
+	}
+
+	groups, err := r.queryModifierGroups(`SELECT `+modifierGroupColumns+` FROM menu_modifier_groups WHERE restaurant_id = $1 ORDER BY position, id`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	groupByID := make(map[int64]*ModifierGroup, len(groups))
+	for _, group := range groups {
+		groupByID[group.ID] = group
+		if item, ok := itemByID[group.ItemID]; ok {
+			item.ModifierGroups = append(item.ModifierGroups, group)
+		}
+	}
+
+	options, err := r.queryModifierOptions(`SELECT `+modifierOptionColumns+` FROM menu_modifier_options WHERE restaurant_id = $1 ORDER BY position, id`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if group, ok := groupByID[option.GroupID]; ok {
+			group.Options = append(group.Options, option)
+		}
+	}
+
+	return menu, nil
+}
+
+// Categories
+
+const categoryColumns = `id, restaurant_id, name, description, position, tax_rate_bp, created_at, updated_at`
+
+func scanCategory(row rowScanner) (*Category, error) {
+	category := &Category{Items: []*Item{}}
+	var description sql.NullString
+	var taxRate sql.NullInt64
+
+	err := row.Scan(
+		&category.ID,
+		&category.RestaurantID,
+		&category.Name,
+		&description,
+		&category.Position,
+		&taxRate,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if description.Valid {
+		category.Description = &description.String
+	}
+	if taxRate.Valid {
+		rate := int(taxRate.Int64)
+		category.TaxRateBP = &rate
+	}
+
+	return category, nil
+}
+
+func (r *Repository) queryCategories(query string, args ...interface{}) ([]*Category, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *Repository) GetCategory(restaurantID, id int64) (*Category, error) {
+	category, err := scanCategory(r.db.QueryRow(
+		`SELECT `+categoryColumns+` FROM menu_categories WHERE id = $1 AND restaurant_id = $2`,
+		id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (r *Repository) CreateCategory(category *Category) error {
+	err := r.db.QueryRow(`
+		INSERT INTO menu_categories (restaurant_id, name, description, position, tax_rate_bp)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, category.RestaurantID, category.Name, category.Description, category.Position, category.TaxRateBP,
+	).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateCategory(category *Category) error {
+	err := r.db.QueryRow(`
+		UPDATE menu_categories
+		SET name = $1, description = $2, position = $3, tax_rate_bp = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND restaurant_id = $6
+		RETURNING updated_at
+	`, category.Name, category.Description, category.Position, category.TaxRateBP, category.ID, category.RestaurantID,
+	).Scan(&category.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCategory deletes a category with all of its items.
+func (r *Repository) DeleteCategory(restaurantID, id int64) error {
+	return r.delete("menu_categories", "category", restaurantID, id)
+}
+
+// Items
+
+// A stock counter from an earlier local day reads as a full daily stock
+const effectiveStock = `CASE WHEN stock_date = restaurant_local_date(restaurant_id) THEN stock_remaining ELSE daily_stock END`
+
+const itemColumns = `id, restaurant_id, category_id, name, description, image_url, price, currency, is_available, available_at,
+	daily_stock, ` + effectiveStock + `, position, created_at, updated_at`
+
+func scanItem(row rowScanner) (*Item, error) {
+	item := &Item{ModifierGroups: []*ModifierGroup{}}
+	var description, imageURL sql.NullString
+	var availableAt sql.NullTime
+	var dailyStock, stockRemaining sql.NullInt64
+
+	err := row.Scan(
+		&item.ID,
+		&item.RestaurantID,
+		&item.CategoryID,
+		&item.Name,
+		&description,
+		&imageURL,
+		&item.Price,
+		&item.Currency,
+		&item.enabled,
+		&availableAt,
+		&dailyStock,
+		&stockRemaining,
+		&item.Position,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if description.Valid {
+		item.Description = &description.String
+	}
+	if imageURL.Valid {
+		item.ImageURL = &imageURL.String
+	}
+	if availableAt.Valid {
+		item.AvailableAt = &availableAt.Time
+	}
+	if dailyStock.Valid {
+		daily, remaining := int(dailyStock.Int64), int(stockRemaining.Int64)
+		item.DailyStock = &daily
+		item.StockRemaining = &remaining
+	}
+	item.refreshAvailability(time.Now().UTC())
+
+	return item, nil
+}
+
+func (r *Repository) queryItems(query string, args ...interface{}) ([]*Item, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *Repository) GetItem(restaurantID, id int64) (*Item, error) {
+	item, err := scanItem(r.db.QueryRow(
+		`SELECT `+itemColumns+` FROM menu_items WHERE id = $1 AND restaurant_id = $2`,
+		id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	return item, nil
+}
+
+func (r *Repository) CreateItem(item *Item) error {
+	err := r.db.QueryRow(`
+		INSERT INTO menu_items (restaurant_id, category_id, name, description, image_url, price, currency, is_available, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`,
+		item.RestaurantID,
+		item.CategoryID,
+		item.Name,
+		item.Description,
+		item.ImageURL,
+		item.Price,
+		item.Currency,
+		item.enabled,
+		item.Position,
+	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create item: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateItem(item *Item) error {
+	err := r.db.QueryRow(`
+		UPDATE menu_items
+		SET category_id = $1,
+			name = $2,
+			description = $3,
+			image_url = $4,
+			price = $5,
+			currency = $6,
+			is_available = $7,
+			available_at = $8,
+			position = $9,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND restaurant_id = $11
+		RETURNING updated_at
+	`,
+		item.CategoryID,
+		item.Name,
+		item.Description,
+		item.ImageURL,
+		item.Price,
+		item.Currency,
+		item.enabled,
+		item.AvailableAt,
+		item.Position,
+		item.ID,
+		item.RestaurantID,
+	).Scan(&item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("item not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	return nil
+}
+
+// SetItemAvailability switches an item on or off. availableAt, when switching
+// off, is when it comes back.
+func (r *Repository) SetItemAvailability(restaurantID, id int64, enabled bool, availableAt *time.Time) (*Item, error) {
+	item, err := scanItem(r.db.QueryRow(`
+		UPDATE menu_items
+		SET is_available = $1, available_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND restaurant_id = $4
+		RETURNING `+itemColumns,
+		enabled, availableAt, id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update item availability: %w", err)
+	}
+
+	return item, nil
+}
+
+// SetCategoryAvailability switches every item of a category on or off and
+// returns them.
+func (r *Repository) SetCategoryAvailability(restaurantID, categoryID int64, enabled bool, availableAt *time.Time) ([]*Item, error) {
+	items, err := r.queryItems(`
+		UPDATE menu_items
+		SET is_available = $1, available_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE category_id = $3 AND restaurant_id = $4
+		RETURNING `+itemColumns,
+		enabled, availableAt, categoryID, restaurantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Position != items[j].Position {
+			return items[i].Position < items[j].Position
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	return items, nil
+}
+
+// NextMidnight returns the next local midnight of a restaurant in UTC.
+func (r *Repository) NextMidnight(restaurantID int64) (time.Time, error) {
+	var midnight time.Time
+	err := r.db.QueryRow(`SELECT restaurant_next_midnight($1)`, restaurantID).Scan(&midnight)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get restaurant time: %w", err)
+	}
+
+	return midnight, nil
+}
+
+// SetItemStock sets the daily stock of an item and what is left of it today.
+// A nil dailyStock makes the item unlimited.
+func (r *Repository) SetItemStock(restaurantID, id int64, dailyStock, remaining *int) (*Item, error) {
+	item, err := scanItem(r.db.QueryRow(`
+		UPDATE menu_items
+		SET daily_stock = $1,
+			stock_remaining = COALESCE($2, $1),
+			stock_date = CASE WHEN $1::integer IS NULL THEN NULL ELSE restaurant_local_date(restaurant_id) END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND restaurant_id = $4
+		RETURNING `+itemColumns,
+		dailyStock, remaining, id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update item stock: %w", err)
+	}
+
+	return item, nil
+}
+
+// ErrOutOfStock is returned when an order asks for more of an item than is
+// left today.
+var ErrOutOfStock = errors.New("item is out of stock")
+
+// ConsumeStock takes quantities, keyed by item ID, from today's stock of the
+// items, all or nothing. Items without a daily stock are left alone.
+func (r *Repository) ConsumeStock(restaurantID int64, quantities map[int64]int) error {
+	// A fixed order keeps concurrent orders from deadlocking on the rows
+	ids := make([]int64, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		result, err := tx.Exec(`
+			UPDATE menu_items
+			SET stock_remaining = `+effectiveStock+` - $1,
+				stock_date = restaurant_local_date(restaurant_id)
+			WHERE id = $2 AND restaurant_id = $3
+				AND daily_stock IS NOT NULL AND `+effectiveStock+` >= $1
+		`, quantities[id], id, restaurantID)
+		if err != nil {
+			return fmt.Errorf("failed to consume stock: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows > 0 {
+			continue
+		}
+
+		var stocked bool
+		err = tx.QueryRow(
+			`SELECT daily_stock IS NOT NULL FROM menu_items WHERE id = $1 AND restaurant_id = $2`,
+			id, restaurantID,
+		).Scan(&stocked)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("item not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get item: %w", err)
+		}
+		if stocked {
+			return fmt.Errorf("%w: %d", ErrOutOfStock, id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseStock puts quantities back into today's stock of the items. Stock
+// taken on an earlier day is not returned, since the counter has been reset
+// since.
+func (r *Repository) ReleaseStock(restaurantID int64, quantities map[int64]int) error {
+	ids := make([]int64, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		_, err := tx.Exec(`
+			UPDATE menu_items
+			SET stock_remaining = LEAST(stock_remaining + $1, daily_stock)
+			WHERE id = $2 AND restaurant_id = $3
+				AND daily_stock IS NOT NULL AND stock_date = restaurant_local_date(restaurant_id)
+		`, quantities[id], id, restaurantID)
+		if err != nil {
+			return fmt.Errorf("failed to release stock: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteItem(restaurantID, id int64) error {
+	return r.delete("menu_items", "item", restaurantID, id)
+}
+
+// Modifier groups
+
+const modifierGroupColumns = `id, restaurant_id, item_id, name, min_selections, max_selections, position, created_at, updated_at`
+
+func scanModifierGroup(row rowScanner) (*ModifierGroup, error) {
+	group := &ModifierGroup{Options: []*ModifierOption{}}
+
+	err := row.Scan(
+		&group.ID,
+		&group.RestaurantID,
+		&group.ItemID,
+		&group.Name,
+		&group.MinSelections,
+		&group.MaxSelections,
+		&group.Position,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (r *Repository) queryModifierGroups(query string, args ...interface{}) ([]*ModifierGroup, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modifier groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*ModifierGroup
+	for rows.Next() {
+		group, err := scanModifierGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan modifier group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+func (r *Repository) GetModifierGroup(restaurantID, id int64) (*ModifierGroup, error) {
+	group, err := scanModifierGroup(r.db.QueryRow(
+		`SELECT `+modifierGroupColumns+` FROM menu_modifier_groups WHERE id = $1 AND restaurant_id = $2`,
+		id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("modifier group not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modifier group: %w", err)
+	}
+
+	return group, nil
+}
+
+func (r *Repository) CreateModifierGroup(group *ModifierGroup) error {
+	err := r.db.QueryRow(`
+		INSERT INTO menu_modifier_groups (restaurant_id, item_id, name, min_selections, max_selections, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, group.RestaurantID, group.ItemID, group.Name, group.MinSelections, group.MaxSelections, group.Position,
+	).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create modifier group: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateModifierGroup(group *ModifierGroup) error {
+	err := r.db.QueryRow(`
+		UPDATE menu_modifier_groups
+		SET name = $1, min_selections = $2, max_selections = $3, position = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND restaurant_id = $6
+		RETURNING updated_at
+	`, group.Name, group.MinSelections, group.MaxSelections, group.Position, group.ID, group.RestaurantID,
+	).Scan(&group.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("modifier group not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update modifier group: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteModifierGroup(restaurantID, id int64) error {
+	return r.delete("menu_modifier_groups", "modifier group", restaurantID, id)
+}
+
+// Modifier options
+
+const modifierOptionColumns = `id, restaurant_id, group_id, name, price, is_available, available_at, position, created_at, updated_at`
+
+func scanModifierOption(row rowScanner) (*ModifierOption, error) {
+	option := &ModifierOption{}
+	var availableAt sql.NullTime
+
+	err := row.Scan(
+		&option.ID,
+		&option.RestaurantID,
+		&option.GroupID,
+		&option.Name,
+		&option.Price,
+		&option.enabled,
+		&availableAt,
+		&option.Position,
+		&option.CreatedAt,
+		&option.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if availableAt.Valid {
+		option.AvailableAt = &availableAt.Time
+	}
+	option.refreshAvailability(time.Now().UTC())
+
+	return option, nil
+}
+
+func (r *Repository) queryModifierOptions(query string, args ...interface{}) ([]*ModifierOption, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modifier options: %w", err)
+	}
+	defer rows.Close()
+
+	var options []*ModifierOption
+	for rows.Next() {
+		option, err := scanModifierOption(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan modifier option: %w", err)
+		}
+		options = append(options, option)
+	}
+
+	return options, rows.Err()
+}
+
+func (r *Repository) GetModifierOption(restaurantID, id int64) (*ModifierOption, error) {
+	option, err := scanModifierOption(r.db.QueryRow(
+		`SELECT `+modifierOptionColumns+` FROM menu_modifier_options WHERE id = $1 AND restaurant_id = $2`,
+		id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("modifier option not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modifier option: %w", err)
+	}
+
+	return option, nil
+}
+
+func (r *Repository) CreateModifierOption(option *ModifierOption) error {
+	err := r.db.QueryRow(`
+		INSERT INTO menu_modifier_options (restaurant_id, group_id, name, price, is_available, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, option.RestaurantID, option.GroupID, option.Name, option.Price, option.enabled, option.Position,
+	).Scan(&option.ID, &option.CreatedAt, &option.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create modifier option: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateModifierOption(option *ModifierOption) error {
+	err := r.db.QueryRow(`
+		UPDATE menu_modifier_options
+		SET name = $1, price = $2, is_available = $3, available_at = $4, position = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND restaurant_id = $7
+		RETURNING updated_at
+	`, option.Name, option.Price, option.enabled, option.AvailableAt, option.Position, option.ID, option.RestaurantID,
+	).Scan(&option.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("modifier option not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update modifier option: %w", err)
+	}
+
+	return nil
+}
+
+// SetModifierOptionAvailability switches an option on or off, like
+// SetItemAvailability.
+func (r *Repository) SetModifierOptionAvailability(restaurantID, id int64, enabled bool, availableAt *time.Time) (*ModifierOption, error) {
+	option, err := scanModifierOption(r.db.QueryRow(`
+		UPDATE menu_modifier_options
+		SET is_available = $1, available_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND restaurant_id = $4
+		RETURNING `+modifierOptionColumns,
+		enabled, availableAt, id, restaurantID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("modifier option not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update modifier option availability: %w", err)
+	}
+
+	return option, nil
+}
+
+func (r *Repository) DeleteModifierOption(restaurantID, id int64) error {
+	return r.delete("menu_modifier_options", "modifier option", restaurantID, id)
+}
+
+// delete removes a row of a menu table scoped to the restaurant. table comes
+// from the callers in this file, never from user input.
+func (r *Repository) delete(table, name string, restaurantID, id int64) error {
+	result, err := r.db.Exec("DELETE FROM "+table+" WHERE id = $1 AND restaurant_id = $2", id, restaurantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", name, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("%s not found", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,512 @@
+package menus
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/modules/auth"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/money"
+)
+
+type Service struct {
+	repo        *Repository
+	restaurants *restaurants.Service
+}
+
+func NewService(repo *Repository, restaurantsService *restaurants.Service) *Service {
+	return &Service{
+		repo:        repo,
+		restaurants: restaurantsService,
+	}
+}
+
+// Any member may read the menu; owners and managers edit it.
+func (s *Service) canRead(actor *restaurants.Actor, restaurantID int64) error {
+	return s.restaurants.Authorize(actor, restaurantID, auth.PermissionRestaurantsRead,
+		restaurants.MemberRoleOwner, restaurants.MemberRoleManager, restaurants.MemberRoleStaff)
+}
+
+func (s *Service) canWrite(actor *restaurants.Actor, restaurantID int64) error {
+	return s.restaurants.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite,
+		restaurants.MemberRoleOwner, restaurants.MemberRoleManager)
+}
+
+// Kitchen staff may also switch items off and manage their stock.
+func (s *Service) canOperate(actor *restaurants.Actor, restaurantID int64) error {
+	return s.restaurants.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite,
+		restaurants.MemberRoleOwner, restaurants.MemberRoleManager, restaurants.MemberRoleStaff)
+}
+
+func (s *Service) GetMenu(actor *restaurants.Actor, restaurantID int64) (*Menu, error) {
+	if err := s.canRead(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMenu(restaurantID)
+}
+
+// GetPublicMenu returns the menu of an active restaurant. Unavailable items
+// and options stay in it, marked as such, so customers see them sold out.
+func (s *Service) GetPublicMenu(slug string) (*Menu, error) {
+	restaurant, err := s.restaurants.GetActiveBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMenu(restaurant.ID)
+}
+
+// LookupMenu returns the menu of a restaurant without checking access, for
+// other modules pricing a customer's order.
+func (s *Service) LookupMenu(restaurantID int64) (*Menu, error) {
+	return s.repo.GetMenu(restaurantID)
+}
+
+func (s *Service) CreateCategory(actor *restaurants.Actor, restaurantID int64, req *CreateCategoryRequest) (*Category, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	category := &Category{
+		RestaurantID: restaurantID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Position:     req.Position,
+		TaxRateBP:    req.TaxRateBP,
+		Items:        []*Item{},
+	}
+
+	if err := s.repo.CreateCategory(category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *Service) UpdateCategory(actor *restaurants.Actor, restaurantID, id int64, req *UpdateCategoryRequest) (*Category, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	category, err := s.repo.GetCategory(restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.Description != nil {
+		category.Description = req.Description
+	}
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+	if req.TaxRateBP != nil {
+		category.TaxRateBP = req.TaxRateBP
+		if *req.TaxRateBP == -1 {
+			category.TaxRateBP = nil
+		}
+	}
+
+	if err := s.repo.UpdateCategory(category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *Service) DeleteCategory(actor *restaurants.Actor, restaurantID, id int64) error {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteCategory(restaurantID, id)
+}
+
+func (s *Service) CreateItem(actor *restaurants.Actor, restaurantID int64, req *CreateItemRequest) (*Item, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetCategory(restaurantID, req.CategoryID); err != nil {
+		return nil, err
+	}
+
+	currency, err := s.itemCurrency(restaurantID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Item{
+		RestaurantID:   restaurantID,
+		CategoryID:     req.CategoryID,
+		Name:           req.Name,
+		Description:    req.Description,
+		ImageURL:       req.ImageURL,
+		Price:          *req.Price,
+		Currency:       currency,
+		Position:       req.Position,
+		ModifierGroups: []*ModifierGroup{},
+		enabled:        true,
+	}
+
+	if req.IsAvailable != nil {
+		item.enabled = *req.IsAvailable
+	}
+
+	if err := s.repo.CreateItem(item); err != nil {
+		return nil, err
+	}
+	item.refreshAvailability(time.Now().UTC())
+
+	return item, nil
+}
+
+// itemCurrency checks that a dish is priced in the restaurant's currency,
+// which it defaults to.
+func (s *Service) itemCurrency(restaurantID int64, requested *string) (string, error) {
+	restaurant, err := s.restaurants.Lookup(restaurantID)
+	if err != nil {
+		return "", err
+	}
+	if requested == nil {
+		return restaurant.Currency, nil
+	}
+
+	currency, err := money.NormalizeCurrency(*requested)
+	if err != nil {
+		return "", err
+	}
+	if currency != restaurant.Currency {
+		return "", fmt.Errorf("dishes are priced in the restaurant's currency, %s", restaurant.Currency)
+	}
+	return currency, nil
+}
+
+func (s *Service) UpdateItem(actor *restaurants.Actor, restaurantID, id int64, req *UpdateItemRequest) (*Item, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	item, err := s.repo.GetItem(restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CategoryID != nil && *req.CategoryID != item.CategoryID {
+		if _, err := s.repo.GetCategory(restaurantID, *req.CategoryID); err != nil {
+			return nil, err
+		}
+		item.CategoryID = *req.CategoryID
+	}
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.Description != nil {
+		item.Description = req.Description
+	}
+	if req.ImageURL != nil {
+		item.ImageURL = req.ImageURL
+	}
+	if req.Price != nil {
+		item.Price = *req.Price
+	}
+	if req.Currency != nil {
+		currency, err := s.itemCurrency(restaurantID, req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		item.Currency = currency
+	}
+	if req.IsAvailable != nil {
+		item.enabled = *req.IsAvailable
+		item.AvailableAt = nil
+	}
+	if req.Position != nil {
+		item.Position = *req.Position
+	}
+
+	if err := s.repo.UpdateItem(item); err != nil {
+		return nil, err
+	}
+	item.refreshAvailability(time.Now().UTC())
+
+	return item, nil
+}
+
+// SetItemAvailability switches an item on or off without editing it.
+func (s *Service) SetItemAvailability(actor *restaurants.Actor, restaurantID, id int64, req *AvailabilityRequest) (*Item, error) {
+	if err := s.canOperate(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	availableAt, err := s.restoreTime(restaurantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.SetItemAvailability(restaurantID, id, *req.IsAvailable, availableAt)
+}
+
+// SetCategoryAvailability switches every item of a category on or off at once
+// and returns the category with its items.
+func (s *Service) SetCategoryAvailability(actor *restaurants.Actor, restaurantID, id int64, req *AvailabilityRequest) (*Category, error) {
+	if err := s.canOperate(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	category, err := s.repo.GetCategory(restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	availableAt, err := s.restoreTime(restaurantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.SetCategoryAvailability(restaurantID, id, *req.IsAvailable, availableAt)
+	if err != nil {
+		return nil, err
+	}
+	category.Items = append(category.Items, items...)
+
+	return category, nil
+}
+
+// SetItemStock sets the daily stock of an item. Remaining defaults to the
+// full daily stock.
+func (s *Service) SetItemStock(actor *restaurants.Actor, restaurantID, id int64, req *StockRequest) (*Item, error) {
+	if err := s.canOperate(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	if req.DailyStock == nil && req.Remaining != nil {
+		return nil, errors.New("remaining requires daily_stock")
+	}
+
+	return s.repo.SetItemStock(restaurantID, id, req.DailyStock, req.Remaining)
+}
+
+// ConsumeStock takes ordered quantities, keyed by item ID, from today's
+// stock, failing with ErrOutOfStock if any item has too little left. An item
+// whose stock reaches zero shows as unavailable until the next local day.
+func (s *Service) ConsumeStock(restaurantID int64, quantities map[int64]int) error {
+	for _, quantity := range quantities {
+		if quantity <= 0 {
+			return errors.New("quantity must be positive")
+		}
+	}
+
+	return s.repo.ConsumeStock(restaurantID, quantities)
+}
+
+// ReleaseStock returns the quantities of a cancelled order to today's stock.
+func (s *Service) ReleaseStock(restaurantID int64, quantities map[int64]int) error {
+	return s.repo.ReleaseStock(restaurantID, quantities)
+}
+
+// restoreTime returns when entries switched off by req come back by
+// themselves, if ever.
+func (s *Service) restoreTime(restaurantID int64, req *AvailabilityRequest) (*time.Time, error) {
+	if *req.IsAvailable {
+		if req.RestoreAt != nil || req.ForToday {
+			return nil, errors.New("restore_at and for_today only apply when switching off")
+		}
+		return nil, nil
+	}
+
+	switch {
+	case req.RestoreAt != nil && req.ForToday:
+		return nil, errors.New("use either restore_at or for_today")
+	case req.RestoreAt != nil:
+		restoreAt := req.RestoreAt.UTC()
+		if !restoreAt.After(time.Now()) {
+			return nil, errors.New("restore_at must be in the future")
+		}
+		return &restoreAt, nil
+	case req.ForToday:
+		midnight, err := s.repo.NextMidnight(restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		return &midnight, nil
+	}
+
+	return nil, nil
+}
+
+func (s *Service) DeleteItem(actor *restaurants.Actor, restaurantID, id int64) error {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteItem(restaurantID, id)
+}
+
+func (s *Service) CreateModifierGroup(actor *restaurants.Actor, restaurantID, itemID int64, req *CreateModifierGroupRequest) (*ModifierGroup, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetItem(restaurantID, itemID); err != nil {
+		return nil, err
+	}
+
+	group := &ModifierGroup{
+		RestaurantID:  restaurantID,
+		ItemID:        itemID,
+		Name:          req.Name,
+		MinSelections: req.MinSelections,
+		MaxSelections: req.MaxSelections,
+		Position:      req.Position,
+		Options:       []*ModifierOption{},
+	}
+
+	if err := validateSelections(group); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateModifierGroup(group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *Service) UpdateModifierGroup(actor *restaurants.Actor, restaurantID, id int64, req *UpdateModifierGroupRequest) (*ModifierGroup, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	group, err := s.repo.GetModifierGroup(restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		group.Name = *req.Name
+	}
+	if req.MinSelections != nil {
+		group.MinSelections = *req.MinSelections
+	}
+	if req.MaxSelections != nil {
+		group.MaxSelections = *req.MaxSelections
+	}
+	if req.Position != nil {
+		group.Position = *req.Position
+	}
+
+	if err := validateSelections(group); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateModifierGroup(group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *Service) DeleteModifierGroup(actor *restaurants.Actor, restaurantID, id int64) error {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteModifierGroup(restaurantID, id)
+}
+
+func (s *Service) CreateModifierOption(actor *restaurants.Actor, restaurantID, groupID int64, req *CreateModifierOptionRequest) (*ModifierOption, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetModifierGroup(restaurantID, groupID); err != nil {
+		return nil, err
+	}
+
+	option := &ModifierOption{
+		RestaurantID: restaurantID,
+		GroupID:      groupID,
+		Name:         req.Name,
+		Price:        req.Price,
+		Position:     req.Position,
+		enabled:      true,
+	}
+
+	if req.IsAvailable != nil {
+		option.enabled = *req.IsAvailable
+	}
+
+	if err := s.repo.CreateModifierOption(option); err != nil {
+		return nil, err
+	}
+	option.refreshAvailability(time.Now().UTC())
+
+	return option, nil
+}
+
+func (s *Service) UpdateModifierOption(actor *restaurants.Actor, restaurantID, id int64, req *UpdateModifierOptionRequest) (*ModifierOption, error) {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	option, err := s.repo.GetModifierOption(restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		option.Name = *req.Name
+	}
+	if req.Price != nil {
+		option.Price = *req.Price
+	}
+	if req.IsAvailable != nil {
+		option.enabled = *req.IsAvailable
+		option.AvailableAt = nil
+	}
+	if req.Position != nil {
+		option.Position = *req.Position
+	}
+
+	if err := s.repo.UpdateModifierOption(option); err != nil {
+		return nil, err
+	}
+	option.refreshAvailability(time.Now().UTC())
+
+	return option, nil
+}
+
+// SetModifierOptionAvailability switches an option on or off without
+// editing it.
+func (s *Service) SetModifierOptionAvailability(actor *restaurants.Actor, restaurantID, id int64, req *AvailabilityRequest) (*ModifierOption, error) {
+	if err := s.canOperate(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	availableAt, err := s.restoreTime(restaurantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.SetModifierOptionAvailability(restaurantID, id, *req.IsAvailable, availableAt)
+}
+
+func (s *Service) DeleteModifierOption(actor *restaurants.Actor, restaurantID, id int64) error {
+	if err := s.canWrite(actor, restaurantID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteModifierOption(restaurantID, id)
+}
+
+func validateSelections(group *ModifierGroup) error {
+	if group.MinSelections > group.MaxSelections {
+		return errors.New("min_selections cannot exceed max_selections")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package menus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yourcompany/saas-platform/internal/database/dbtest"
+)
+
+type stockEnv struct {
+	repo         *Repository
+	restaurantID int64
+	categoryID   int64
+}
+
+func newStockEnv(t *testing.T) *stockEnv {
+	db := dbtest.Open(t)
+	env := &stockEnv{repo: NewRepository(db)}
+
+	if err := db.QueryRow(
+		"INSERT INTO restaurants (name, slug, currency) VALUES ('Test', $1, 'EUR') RETURNING id", dbtest.Unique("test"),
+	).Scan(&env.restaurantID); err != nil {
+		t.Fatalf("failed to create restaurant: %v", err)
+	}
+
+	category := &Category{RestaurantID: env.restaurantID, Name: "Mains"}
+	if err := env.repo.CreateCategory(category); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	env.categoryID = category.ID
+
+	return env
+}
+
+// item creates a dish, with a daily stock unless dailyStock is nil.
+func (env *stockEnv) item(t *testing.T, dailyStock *int) int64 {
+	t.Helper()
+
+	item := &Item{RestaurantID: env.restaurantID, CategoryID: env.categoryID, Name: "Soup", Price: 500, Currency: "EUR", enabled: true}
+	if err := env.repo.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	if dailyStock != nil {
+		if _, err := env.repo.SetItemStock(env.restaurantID, item.ID, dailyStock, nil); err != nil {
+			t.Fatalf("SetItemStock: %v", err)
+		}
+	}
+	return item.ID
+}
+
+func (env *stockEnv) assertStock(t *testing.T, id int64, remaining int, available bool) {
+	t.Helper()
+
+	item, err := env.repo.GetItem(env.restaurantID, id)
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	if item.StockRemaining == nil || *item.StockRemaining != remaining || item.IsAvailable != available {
+		t.Errorf("got %v left, available %v; want %d left, available %v", item.StockRemaining, item.IsAvailable, remaining, available)
+	}
+}
+
+func TestConsumeAndReleaseStock(t *testing.T) {
+	env := newStockEnv(t)
+	soup := env.item(t, intPtr(5))
+	bread := env.item(t, nil)
+
+	if err := env.repo.ConsumeStock(env.restaurantID, map[int64]int{soup: 3, bread: 10}); err != nil {
+		t.Fatalf("ConsumeStock: %v", err)
+	}
+	env.assertStock(t, soup, 2, true)
+
+	err := env.repo.ConsumeStock(env.restaurantID, map[int64]int{soup: 3})
+	if !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("ConsumeStock beyond the stock: got %v, want ErrOutOfStock", err)
+	}
+	env.assertStock(t, soup, 2, true)
+
+	if err := env.repo.ConsumeStock(env.restaurantID, map[int64]int{soup: 2}); err != nil {
+		t.Fatalf("ConsumeStock: %v", err)
+	}
+	env.assertStock(t, soup, 0, false)
+
+	// Released stock never exceeds the daily stock
+	if err := env.repo.ReleaseStock(env.restaurantID, map[int64]int{soup: 9, bread: 1}); err != nil {
+		t.Fatalf("ReleaseStock: %v", err)
+	}
+	env.assertStock(t, soup, 5, true)
+}
+
+func TestConsumeStockIsAllOrNothing(t *testing.T) {
+	env := newStockEnv(t)
+	soup := env.item(t, intPtr(5))
+	stew := env.item(t, intPtr(1))
+
+	err := env.repo.ConsumeStock(env.restaurantID, map[int64]int{soup: 2, stew: 2})
+	if !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("ConsumeStock: got %v, want ErrOutOfStock", err)
+	}
+	env.assertStock(t, soup, 5, true)
+	env.assertStock(t, stew, 1, true)
+}
+
+// Stock counted on an earlier local day is reset, and releasing an order of
+// that day gives nothing back.
+func TestStockResetsEachDay(t *testing.T) {
+	env := newStockEnv(t)
+	soup := env.item(t, intPtr(5))
+
+	if err := env.repo.ConsumeStock(env.restaurantID, map[int64]int{soup: 5}); err != nil {
+		t.Fatalf("ConsumeStock: %v", err)
+	}
+	if _, err := env.repo.db.Exec(
+		"UPDATE menu_items SET stock_date = stock_date - 1 WHERE id = $1", soup,
+	); err != nil {
+		t.Fatalf("failed to age stock: %v", err)
+	}
+	env.assertStock(t, soup, 5, true)
+
+	if err := env.repo.ReleaseStock(env.restaurantID, map[int64]int{soup: 5}); err != nil {
+		t.Fatalf("ReleaseStock: %v", err)
+	}
+	env.assertStock(t, soup, 5, true)
+
+	if err := env.repo.ConsumeStock(env.restaurantID, map[int64]int{soup: 4}); err != nil {
+		t.Fatalf("ConsumeStock: %v", err)
+	}
+	env.assertStock(t, soup, 1, true)
+}
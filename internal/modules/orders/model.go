@@ -0,0 +1,91 @@
+package orders
+
+import (
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/money"
+	"github.com/yourcompany/saas-platform/internal/pricing"
+)
+
+// Order is placed from a cart and keeps the names, prices, tax and fees it
+// was placed with. Amounts are in minor units of Currency. Breakdown is nil
+// on orders placed before tax and fees were worked out.
+type Order struct {
+	ID           int64              `json:"id"`
+	UserID       *int64             `json:"user_id,omitempty"`
+	RestaurantID int64              `json:"restaurant_id"`
+	CartID       *int64             `json:"-"`
+	Status       string             `json:"status"`
+	Currency     string             `json:"currency"`
+	Subtotal     int64              `json:"subtotal"`
+	Total        int64              `json:"total"`
+	Breakdown    *pricing.Breakdown `json:"breakdown,omitempty"`
+	Notes        *string            `json:"notes,omitempty"`
+	Lines        []*Line            `json:"lines"`
+	Events       []*Event           `json:"events,omitempty"` // Only on a single order
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// TotalMoney is the amount the customer pays.
+func (o *Order) TotalMoney() money.Money {
+	return money.Money{Amount: o.Total, Currency: o.Currency}
+}
+
+// quantities sums the ordered quantity of each dish.
+func (o *Order) quantities() map[int64]int {
+	quantities := make(map[int64]int)
+	for _, line := range o.Lines {
+		quantities[line.ItemID] += line.Quantity
+	}
+	return quantities
+}
+
+type Line struct {
+	ID        int64         `json:"id"`
+	OrderID   int64         `json:"-"`
+	ItemID    int64         `json:"item_id"`
+	Name      string        `json:"name"`
+	Quantity  int           `json:"quantity"`
+	UnitPrice int64         `json:"unit_price"`
+	Total     int64         `json:"total"`
+	TaxRateBP int           `json:"tax_rate_bp"`
+	Options   []*LineOption `json:"options"`
+	Notes     *string       `json:"notes,omitempty"`
+}
+
+type LineOption struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Price int64  `json:"price"`
+}
+
+// Event is a status change. FromStatus is nil for placing the order;
+// ActorID is nil once that user is deleted.
+type Event struct {
+	ID         int64     `json:"id"`
+	OrderID    int64     `json:"-"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    *int64    `json:"actor_id,omitempty"`
+	Reason     *string   `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PlaceOrderRequest carries the delivery location when the restaurant
+// charges for delivery by distance.
+type PlaceOrderRequest struct {
+	CartID    int64    `json:"cart_id" binding:"required"`
+	Notes     *string  `json:"notes" binding:"omitempty,max=1000"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+type CancelOrderRequest struct {
+	Reason *string `json:"reason" binding:"omitempty,max=500"`
+}
+
+type ChangeStatusRequest struct {
+	Status string  `json:"status" binding:"required"`
+	Reason *string `json:"reason" binding:"omitempty,max=500"`
+}
@@ -0,0 +1,42 @@
+package orders
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/yourcompany/saas-platform/internal/pagination"
+)
+
+// ListQuery selects a page of orders, newest first, optionally only those
+// in Statuses.
+type ListQuery struct {
+	pagination.Params
+	Statuses []string
+}
+
+// ParseListQuery reads status (comma separated) and the cursor parameters
+// cursor, limit and total.
+func ParseListQuery(values url.Values) (*ListQuery, error) {
+	params, err := pagination.ParseParams(values)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &ListQuery{Params: *params}
+	if query.Limit == 0 {
+		query.Limit = pagination.DefaultLimit
+	}
+
+	if value := values.Get("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if !validStatus(status) {
+				return nil, fmt.Errorf("invalid status %q", status)
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+
+	return query, nil
+}
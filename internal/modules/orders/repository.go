@@ -0,0 +1,363 @@
+package orders
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/yourcompany/saas-platform/internal/pagination"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const orderColumns = `id, user_id, restaurant_id, cart_id, status, currency, subtotal, total, breakdown, notes, created_at, updated_at`
+
+func scanOrder(row rowScanner) (*Order, error) {
+	order := &Order{Lines: []*Line{}}
+	var userID, cartID sql.NullInt64
+	var notes sql.NullString
+	var breakdown []byte
+
+	err := row.Scan(
+		&order.ID,
+		&userID,
+		&order.RestaurantID,
+		&cartID,
+		&order.Status,
+		&order.Currency,
+		&order.Subtotal,
+		&order.Total,
+		&breakdown,
+		&notes,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		order.UserID = &userID.Int64
+	}
+	if cartID.Valid {
+		order.CartID = &cartID.Int64
+	}
+	if notes.Valid {
+		order.Notes = &notes.String
+	}
+	if breakdown != nil {
+		if err := json.Unmarshal(breakdown, &order.Breakdown); err != nil {
+			return nil, fmt.Errorf("failed to decode breakdown: %w", err)
+		}
+	}
+
+	return order, nil
+}
+
+// Create stores a new order with its lines and first event, and closes the
+// cart it was placed from. A cart can be ordered only once.
+func (r *Repository) Create(order *Order) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE carts SET status = 'ordered', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND status = 'active'
+	`, order.CartID, order.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to close cart: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("cart not found")
+	}
+
+	breakdown, err := json.Marshal(order.Breakdown)
+	if err != nil {
+		return fmt.Errorf("failed to encode breakdown: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO orders (user_id, restaurant_id, cart_id, status, currency, subtotal, total, breakdown, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`,
+		order.UserID,
+		order.RestaurantID,
+		order.CartID,
+		order.Status,
+		order.Currency,
+		order.Subtotal,
+		order.Total,
+		breakdown,
+		order.Notes,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	for _, line := range order.Lines {
+		options, err := json.Marshal(line.Options)
+		if err != nil {
+			return fmt.Errorf("failed to encode line options: %w", err)
+		}
+
+		line.OrderID = order.ID
+		err = tx.QueryRow(`
+			INSERT INTO order_lines (order_id, item_id, name, quantity, unit_price, total, tax_rate_bp, options, notes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, line.OrderID, line.ItemID, line.Name, line.Quantity, line.UnitPrice, line.Total, line.TaxRateBP, options, line.Notes,
+		).Scan(&line.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create order line: %w", err)
+		}
+	}
+
+	event := &Event{OrderID: order.ID, ToStatus: order.Status, ActorID: order.UserID}
+	if err := insertEvent(tx, event); err != nil {
+		return err
+	}
+	order.Events = []*Event{event}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns an order with its lines and events.
+func (r *Repository) Get(id int64) (*Order, error) {
+	order, err := scanOrder(r.db.QueryRow(`SELECT `+orderColumns+` FROM orders WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	if err := r.loadLines([]*Order{order}); err != nil {
+		return nil, err
+	}
+
+	events, err := r.getEvents(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	order.Events = events
+
+	return order, nil
+}
+
+// listFilter builds the WHERE clause of an order list.
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+func newListFilter(column string, ownerID int64, query *ListQuery) *listFilter {
+	f := &listFilter{}
+	f.conditions = append(f.conditions, column+" = "+f.arg(ownerID))
+	if len(query.Statuses) > 0 {
+		f.conditions = append(f.conditions, "status = ANY("+f.arg(pq.Array(query.Statuses))+")")
+	}
+	return f
+}
+
+func (f *listFilter) arg(value interface{}) string {
+	f.args = append(f.args, value)
+	return "$" + strconv.Itoa(len(f.args))
+}
+
+func (f *listFilter) where() string {
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// List returns up to Limit+1 orders of a user or restaurant after the
+// cursor, newest first. column is user_id or restaurant_id.
+func (r *Repository) List(column string, ownerID int64, query *ListQuery) ([]*Order, error) {
+	f := newListFilter(column, ownerID, query)
+	if query.Cursor != nil {
+		f.conditions = append(f.conditions, query.Cursor.After(true, f.arg))
+	}
+	limit := f.arg(query.Limit + 1)
+
+	rows, err := r.db.Query(`SELECT `+orderColumns+` FROM orders`+f.where()+` ORDER BY created_at DESC, id DESC LIMIT `+limit, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, r.loadLines(orders)
+}
+
+// Count returns the number of orders List would page through, or the
+// planner's estimate of it.
+func (r *Repository) Count(column string, ownerID int64, query *ListQuery, estimate bool) (int, error) {
+	f := newListFilter(column, ownerID, query)
+	if estimate {
+		return pagination.EstimateCount(r.db, `SELECT id FROM orders`+f.where(), f.args...)
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM orders`+f.where(), f.args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+	return total, nil
+}
+
+func (r *Repository) loadLines(orders []*Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(orders))
+	orderByID := make(map[int64]*Order, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		orderByID[order.ID] = order
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, order_id, item_id, name, quantity, unit_price, total, tax_rate_bp, options, notes
+		FROM order_lines WHERE order_id = ANY($1) ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := &Line{}
+		var options []byte
+		var notes sql.NullString
+		err := rows.Scan(&line.ID, &line.OrderID, &line.ItemID, &line.Name, &line.Quantity, &line.UnitPrice, &line.Total, &line.TaxRateBP, &options, &notes)
+		if err != nil {
+			return fmt.Errorf("failed to scan order line: %w", err)
+		}
+		if err := json.Unmarshal(options, &line.Options); err != nil {
+			return fmt.Errorf("failed to decode line options: %w", err)
+		}
+		if notes.Valid {
+			line.Notes = &notes.String
+		}
+		orderByID[line.OrderID].Lines = append(orderByID[line.OrderID].Lines, line)
+	}
+
+	return rows.Err()
+}
+
+func (r *Repository) getEvents(orderID int64) ([]*Event, error) {
+	rows, err := r.db.Query(`
+		SELECT id, order_id, from_status, to_status, actor_id, reason, created_at
+		FROM order_events WHERE order_id = $1 ORDER BY id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		event := &Event{}
+		var fromStatus, reason sql.NullString
+		var actorID sql.NullInt64
+		err := rows.Scan(&event.ID, &event.OrderID, &fromStatus, &event.ToStatus, &actorID, &reason, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order event: %w", err)
+		}
+		if fromStatus.Valid {
+			event.FromStatus = &fromStatus.String
+		}
+		if actorID.Valid {
+			event.ActorID = &actorID.Int64
+		}
+		if reason.Valid {
+			event.Reason = &reason.String
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// Transition moves an order from its current status to event.ToStatus and
+// records the event. It fails if someone else changed the status first.
+func (r *Repository) Transition(order *Order, event *Event) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3
+		RETURNING updated_at
+	`, event.ToStatus, order.ID, order.Status,
+	).Scan(&order.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order status has changed, reload it and try again")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if err := insertEvent(tx, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	order.Status = event.ToStatus
+	order.Events = append(order.Events, event)
+	return nil
+}
+
+func insertEvent(tx *sql.Tx, event *Event) error {
+	err := tx.QueryRow(`
+		INSERT INTO order_events (order_id, from_status, to_status, actor_id, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, event.OrderID, event.FromStatus, event.ToStatus, event.ActorID, event.Reason,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record order event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yourcompany/saas-platform/internal/modules/auth"
+	"github.com/yourcompany/saas-platform/internal/modules/carts"
+	"github.com/yourcompany/saas-platform/internal/modules/menus"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/pagination"
+)
+
+// StatusListener is told about every status change once it is stored, such
+// as payments capturing the money of a delivered order.
+type StatusListener interface {
+	OrderStatusChanged(order *Order, from string)
+}
+
+type Service struct {
+	repo        *Repository
+	restaurants *restaurants.Service
+	menus       *menus.Service
+	carts       *carts.Service
+	listeners   []StatusListener
+}
+
+func NewService(repo *Repository, restaurantsService *restaurants.Service, menusService *menus.Service, cartsService *carts.Service) *Service {
+	return &Service{
+		repo:        repo,
+		restaurants: restaurantsService,
+		menus:       menusService,
+		carts:       cartsService,
+	}
+}
+
+// AddStatusListener registers a listener. Listeners are added at startup,
+// before the service is used.
+func (s *Service) AddStatusListener(listener StatusListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// Place turns a valid cart of the current user into an order, taking the
+// dishes from today's stock. The order keeps the tax and fees of the cart's
+// quote.
+func (s *Service) Place(actor *restaurants.Actor, req *PlaceOrderRequest) (*Order, error) {
+	var location *restaurants.GeoPoint
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return nil, errors.New("latitude and longitude must be set together")
+	}
+	if req.Latitude != nil {
+		location = &restaurants.GeoPoint{Lat: *req.Latitude, Lng: *req.Longitude}
+	}
+
+	cart, err := s.carts.Quote(actor, req.CartID, location)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cart.Lines) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+	for _, line := range cart.Lines {
+		if !line.IsValid {
+			return nil, fmt.Errorf("cart cannot be ordered: %s: %s", line.Name, line.Problem)
+		}
+	}
+
+	order := &Order{
+		UserID:       &actor.UserID,
+		RestaurantID: cart.RestaurantID,
+		CartID:       &cart.ID,
+		Status:       StatusPlaced,
+		Currency:     cart.Currency,
+		Subtotal:     cart.Subtotal,
+		Total:        cart.Breakdown.Total,
+		Breakdown:    cart.Breakdown,
+		Notes:        req.Notes,
+		Lines:        make([]*Line, 0, len(cart.Lines)),
+	}
+	for _, cartLine := range cart.Lines {
+		line := &Line{
+			ItemID:    cartLine.ItemID,
+			Name:      cartLine.Name,
+			Quantity:  cartLine.Quantity,
+			UnitPrice: cartLine.UnitPrice,
+			Total:     cartLine.Total,
+			TaxRateBP: cartLine.TaxRateBP,
+			Options:   make([]*LineOption, 0, len(cartLine.Options)),
+			Notes:     cartLine.Notes,
+		}
+		for _, option := range cartLine.Options {
+			line.Options = append(line.Options, &LineOption{ID: option.ID, Name: option.Name, Price: option.Price})
+		}
+		order.Lines = append(order.Lines, line)
+	}
+
+	if err := s.menus.ConsumeStock(order.RestaurantID, order.quantities()); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(order); err != nil {
+		s.releaseStock(order)
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetMyOrders returns a page of the current user's orders.
+func (s *Service) GetMyOrders(actor *restaurants.Actor, query *ListQuery) (*pagination.Page[*Order], error) {
+	return s.list("user_id", actor.UserID, query)
+}
+
+func (s *Service) GetMyOrder(actor *restaurants.Actor, id int64) (*Order, error) {
+	order, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID == nil || *order.UserID != actor.UserID {
+		return nil, errors.New("order not found")
+	}
+
+	return order, nil
+}
+
+// Lookup returns an order without checking access, for other modules that
+// check it themselves.
+func (s *Service) Lookup(id int64) (*Order, error) {
+	return s.repo.Get(id)
+}
+
+// Cancel cancels an order of the current user while the restaurant has not
+// accepted it yet.
+func (s *Service) Cancel(actor *restaurants.Actor, id int64, req *CancelOrderRequest) (*Order, error) {
+	order, err := s.GetMyOrder(actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := false
+	for _, role := range allowedRoles(order.Status, StatusCancelled) {
+		if role == RoleCustomer {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("a %s order can no longer be cancelled", order.Status)
+	}
+
+	if err := s.transition(actor, order, StatusCancelled, req.Reason); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetRestaurantOrders returns a page of a restaurant's orders to any of its
+// members.
+func (s *Service) GetRestaurantOrders(actor *restaurants.Actor, restaurantID int64, query *ListQuery) (*pagination.Page[*Order], error) {
+	if err := s.canRead(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	return s.list("restaurant_id", restaurantID, query)
+}
+
+func (s *Service) GetRestaurantOrder(actor *restaurants.Actor, restaurantID, id int64) (*Order, error) {
+	if err := s.canRead(actor, restaurantID); err != nil {
+		return nil, err
+	}
+
+	return s.restaurantOrder(restaurantID, id)
+}
+
+// ChangeStatus moves an order of a restaurant along the state machine, if
+// the actor's role there allows that move.
+func (s *Service) ChangeStatus(actor *restaurants.Actor, restaurantID, id int64, req *ChangeStatusRequest) (*Order, error) {
+	if !validStatus(req.Status) {
+		return nil, fmt.Errorf("invalid status %q", req.Status)
+	}
+
+	order, err := s.restaurantOrder(restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberRoles []string
+	for _, role := range allowedRoles(order.Status, req.Status) {
+		if role != RoleCustomer {
+			memberRoles = append(memberRoles, role)
+		}
+	}
+	if len(memberRoles) == 0 {
+		return nil, fmt.Errorf("cannot move a %s order to %s", order.Status, req.Status)
+	}
+
+	if err := s.restaurants.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite, memberRoles...); err != nil {
+		return nil, err
+	}
+
+	if err := s.transition(actor, order, req.Status, req.Reason); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *Service) canRead(actor *restaurants.Actor, restaurantID int64) error {
+	return s.restaurants.Authorize(actor, restaurantID, auth.PermissionRestaurantsRead, kitchenRoles...)
+}
+
+func (s *Service) restaurantOrder(restaurantID, id int64) (*Order, error) {
+	order, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.RestaurantID != restaurantID {
+		return nil, errors.New("order not found")
+	}
+
+	return order, nil
+}
+
+func (s *Service) list(column string, ownerID int64, query *ListQuery) (*pagination.Page[*Order], error) {
+	orders, err := s.repo.List(column, ownerID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := pagination.NewPage(orders, query.Limit, func(order *Order) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: order.CreatedAt, ID: order.ID}
+	})
+
+	if query.Total == pagination.TotalExact || query.Total == pagination.TotalEstimate {
+		total, err := s.repo.Count(column, ownerID, query, query.Total == pagination.TotalEstimate)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+		page.TotalEstimated = query.Total == pagination.TotalEstimate
+	}
+
+	return page, nil
+}
+
+// transition records a status change made by actor and tells the listeners.
+// Rejected and cancelled orders give their dishes back to today's stock.
+func (s *Service) transition(actor *restaurants.Actor, order *Order, status string, reason *string) error {
+	from := order.Status
+	event := &Event{
+		OrderID:    order.ID,
+		FromStatus: &from,
+		ToStatus:   status,
+		ActorID:    &actor.UserID,
+		Reason:     reason,
+	}
+
+	if err := s.repo.Transition(order, event); err != nil {
+		return err
+	}
+
+	if releasesStock(status) {
+		s.releaseStock(order)
+	}
+	for _, listener := range s.listeners {
+		listener.OrderStatusChanged(order, from)
+	}
+
+	return nil
+}
+
+// releaseStock is best effort: the order itself is already settled.
+func (s *Service) releaseStock(order *Order) {
+	if err := s.menus.ReleaseStock(order.RestaurantID, order.quantities()); err != nil {
+		log.Printf("Failed to release stock of order %d: %v", order.ID, err)
+	}
+}
@@ -0,0 +1,70 @@
+package orders
+
+import (
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+)
+
+const (
+	StatusPlaced    = "placed"
+	StatusAccepted  = "accepted"
+	StatusPreparing = "preparing"
+	StatusReady     = "ready"
+	StatusPickedUp  = "picked_up"
+	StatusDelivered = "delivered"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+)
+
+// RoleCustomer stands for the customer who placed the order next to the
+// member roles of the restaurant.
+const RoleCustomer = "customer"
+
+var (
+	kitchenRoles = []string{restaurants.MemberRoleOwner, restaurants.MemberRoleManager, restaurants.MemberRoleStaff}
+	managerRoles = []string{restaurants.MemberRoleOwner, restaurants.MemberRoleManager}
+)
+
+// transitions lists, for each status, the statuses an order may move to and
+// who may move it there. Delivered, rejected and cancelled are final.
+// Platform users with restaurants:write may make any restaurant-side move.
+var transitions = map[string]map[string][]string{
+	StatusPlaced: {
+		StatusAccepted:  kitchenRoles,
+		StatusRejected:  kitchenRoles,
+		StatusCancelled: {RoleCustomer, restaurants.MemberRoleOwner, restaurants.MemberRoleManager},
+	},
+	StatusAccepted: {
+		StatusPreparing: kitchenRoles,
+		StatusCancelled: managerRoles,
+	},
+	StatusPreparing: {
+		StatusReady:     kitchenRoles,
+		StatusCancelled: managerRoles,
+	},
+	StatusReady: {
+		StatusPickedUp: kitchenRoles,
+	},
+	StatusPickedUp: {
+		StatusDelivered: kitchenRoles,
+	},
+}
+
+// allowedRoles returns who may move an order from one status to another, or
+// nil if the move is not possible at all.
+func allowedRoles(from, to string) []string {
+	return transitions[from][to]
+}
+
+// validStatus reports whether status is a known order status.
+func validStatus(status string) bool {
+	if _, ok := transitions[status]; ok {
+		return true
+	}
+	return status == StatusDelivered || status == StatusRejected || status == StatusCancelled
+}
+
+// releasesStock reports whether moving to status gives the ordered dishes
+// back to today's stock.
+func releasesStock(status string) bool {
+	return status == StatusRejected || status == StatusCancelled
+}
@@ -0,0 +1,68 @@
+package orders
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+)
+
+var allStatuses = []string{
+	StatusPlaced, StatusAccepted, StatusPreparing, StatusReady,
+	StatusPickedUp, StatusDelivered, StatusRejected, StatusCancelled,
+}
+
+func TestTransitions(t *testing.T) {
+	const (
+		owner   = restaurants.MemberRoleOwner
+		manager = restaurants.MemberRoleManager
+		staff   = restaurants.MemberRoleStaff
+	)
+
+	// Every move that is possible; any other pair of statuses is forbidden,
+	// which leaves delivered, rejected and cancelled final
+	allowed := map[[2]string][]string{
+		{StatusPlaced, StatusAccepted}:     {owner, manager, staff},
+		{StatusPlaced, StatusRejected}:     {owner, manager, staff},
+		{StatusPlaced, StatusCancelled}:    {RoleCustomer, owner, manager},
+		{StatusAccepted, StatusPreparing}:  {owner, manager, staff},
+		{StatusAccepted, StatusCancelled}:  {owner, manager},
+		{StatusPreparing, StatusReady}:     {owner, manager, staff},
+		{StatusPreparing, StatusCancelled}: {owner, manager},
+		{StatusReady, StatusPickedUp}:      {owner, manager, staff},
+		{StatusPickedUp, StatusDelivered}:  {owner, manager, staff},
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			got := allowedRoles(from, to)
+			want := allowed[[2]string{from, to}]
+
+			if !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(want))) {
+				t.Errorf("%s -> %s: got roles %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestValidStatus(t *testing.T) {
+	for _, status := range allStatuses {
+		if !validStatus(status) {
+			t.Errorf("validStatus(%q) = false", status)
+		}
+	}
+	for _, status := range []string{"", "new", "Placed", "canceled"} {
+		if validStatus(status) {
+			t.Errorf("validStatus(%q) = true", status)
+		}
+	}
+}
+
+func TestReleasesStock(t *testing.T) {
+	for _, status := range allStatuses {
+		want := status == StatusRejected || status == StatusCancelled
+		if got := releasesStock(status); got != want {
+			t.Errorf("releasesStock(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// FakeProvider keeps payments in memory and authorizes them at once, for
+// development and tests. Decline, if set, picks the amounts to decline.
+// Confirm, if set, picks the amounts left pending until Authenticate, as
+// card payments the customer must approve are. Repeating a call with an
+// idempotency key repeats its outcome.
+type FakeProvider struct {
+	Decline func(amount int64) bool
+	Confirm func(amount int64) bool
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+	results  map[string]fakeResult
+}
+
+type fakePayment struct {
+	status   string
+	amount   int64
+	captured int64
+	refunded int64
+}
+
+type fakeResult struct {
+	payment *ProviderPayment
+	id      string
+	err     error
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		payments: make(map[string]*fakePayment),
+		results:  make(map[string]fakeResult),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Authorize(req *AuthorizeRequest) (*ProviderPayment, error) {
+	result := p.once(req.IdempotencyKey, func() fakeResult {
+		p.seq++
+		id := fmt.Sprintf("fake_pi_%d", p.seq)
+
+		status := StatusAuthorized
+		if p.Decline != nil && p.Decline(req.Amount) {
+			status = StatusFailed
+		} else if p.Confirm != nil && p.Confirm(req.Amount) {
+			status = StatusPending
+		}
+		p.payments[id] = &fakePayment{status: status, amount: req.Amount}
+
+		return fakeResult{payment: &ProviderPayment{ID: id, Status: status}}
+	})
+	return result.payment, result.err
+}
+
+// Authenticate authorizes a pending payment, as the customer approving it
+// does. The provider's payment.authorized event is left to the caller.
+func (p *FakeProvider) Authenticate(paymentID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, err := p.payment(paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.status != StatusPending {
+		return fmt.Errorf("fake: payment %s is %s", paymentID, payment.status)
+	}
+
+	payment.status = StatusAuthorized
+	return nil
+}
+
+func (p *FakeProvider) Capture(paymentID string, amount int64, idempotencyKey string) error {
+	return p.once(idempotencyKey, func() fakeResult {
+		payment, err := p.payment(paymentID)
+		if err != nil {
+			return fakeResult{err: err}
+		}
+		if payment.status != StatusAuthorized {
+			return fakeResult{err: fmt.Errorf("fake: payment %s is %s", paymentID, payment.status)}
+		}
+		if amount > payment.amount {
+			return fakeResult{err: fmt.Errorf("fake: cannot capture more than authorized")}
+		}
+
+		payment.status = StatusCaptured
+		payment.captured = amount
+		return fakeResult{}
+	}).err
+}
+
+func (p *FakeProvider) Refund(paymentID string, amount int64, idempotencyKey string) (string, error) {
+	result := p.once(idempotencyKey, func() fakeResult {
+		payment, err := p.payment(paymentID)
+		if err != nil {
+			return fakeResult{err: err}
+		}
+		if payment.status != StatusCaptured {
+			return fakeResult{err: fmt.Errorf("fake: payment %s is %s", paymentID, payment.status)}
+		}
+		if amount > payment.captured-payment.refunded {
+			return fakeResult{err: fmt.Errorf("fake: cannot refund more than captured")}
+		}
+
+		payment.refunded += amount
+		p.seq++
+		return fakeResult{id: fmt.Sprintf("fake_re_%d", p.seq)}
+	})
+	return result.id, result.err
+}
+
+func (p *FakeProvider) Void(paymentID string, idempotencyKey string) error {
+	return p.once(idempotencyKey, func() fakeResult {
+		payment, err := p.payment(paymentID)
+		if err != nil {
+			return fakeResult{err: err}
+		}
+		if payment.status != StatusAuthorized && payment.status != StatusPending {
+			return fakeResult{err: fmt.Errorf("fake: payment %s is %s", paymentID, payment.status)}
+		}
+
+		payment.status = StatusVoided
+		return fakeResult{}
+	}).err
+}
+
+func (p *FakeProvider) SignatureHeader() string {
+	return "Fake-Signature"
+}
+
+// ParseWebhook takes events already in the module's terms:
+// {"id": "...", "type": "payment.captured", "payment_id": "fake_pi_1", "amount": 1500}.
+func (p *FakeProvider) ParseWebhook(body []byte) (*WebhookEvent, error) {
+	var event struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		PaymentID string `json:"payment_id"`
+		Amount    int64  `json:"amount"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode fake event: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, fmt.Errorf("fake event has no id or type")
+	}
+
+	return &WebhookEvent{ID: event.ID, Type: event.Type, PaymentID: event.PaymentID, Amount: event.Amount}, nil
+}
+
+// once runs call under the lock unless idempotencyKey has been seen, in which
+// case it returns the earlier result.
+func (p *FakeProvider) once(idempotencyKey string, call func() fakeResult) fakeResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if result, ok := p.results[idempotencyKey]; ok {
+		return result
+	}
+
+	result := call()
+	p.results[idempotencyKey] = result
+	return result
+}
+
+func (p *FakeProvider) payment(id string) (*fakePayment, error) {
+	payment, ok := p.payments[id]
+	if !ok {
+		return nil, fmt.Errorf("fake: no such payment %s", id)
+	}
+	return payment, nil
+}
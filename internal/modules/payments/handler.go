@@ -0,0 +1,158 @@
+package payments
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Pay(c *gin.Context) {
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	payment, err := h.service.Pay(restaurants.ActorFromContext(c), orderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+func (h *Handler) GetPayment(c *gin.Context) {
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	payment, err := h.service.GetPayment(restaurants.ActorFromContext(c), orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+func (h *Handler) GetRestaurantPayment(c *gin.Context) {
+	restaurantID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	orderID, ok := paramID(c, "orderId")
+	if !ok {
+		return
+	}
+
+	payment, err := h.service.GetRestaurantPayment(restaurants.ActorFromContext(c), restaurantID, orderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+func (h *Handler) Refund(c *gin.Context) {
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.service.Refund(restaurants.ActorFromContext(c), orderID, &req, c.GetHeader("Idempotency-Key"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+func (h *Handler) GetFinancials(c *gin.Context) {
+	orderID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	financials, err := h.service.GetFinancials(restaurants.ActorFromContext(c), orderID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, financials)
+}
+
+// maxWebhookSize bounds the body of a webhook; provider events are far
+// smaller.
+const maxWebhookSize = 1 << 20
+
+// ReceiveWebhook answers 2xx once a webhook is stored, so that the provider
+// stops delivering it; handling it happens in the background.
+func (h *Handler) ReceiveWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	if len(body) > maxWebhookSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "webhook is too large"})
+		return
+	}
+
+	duplicate, err := h.service.ReceiveWebhook(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidSignature):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			// The provider retries on a server error
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true, "duplicate": duplicate})
+}
+
+// paramID parses a numeric path parameter, answering 400 if it is not one.
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError answers restaurants.ErrForbidden with 403 and anything else
+// with status.
+func respondError(c *gin.Context, status int, err error) {
+	if errors.Is(err, restaurants.ErrForbidden) {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
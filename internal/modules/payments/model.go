@@ -0,0 +1,150 @@
+package payments
+
+import (
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/money"
+)
+
+// Payment is one attempt to pay for an order. Amounts are in minor units of
+// Currency; CapturedAmount and RefundedAmount follow the ledger.
+type Payment struct {
+	ID                int64     `json:"id"`
+	OrderID           int64     `json:"order_id"`
+	Provider          string    `json:"provider"`
+	ProviderPaymentID string    `json:"provider_payment_id"`
+	Status            string    `json:"status"`
+	Amount            int64     `json:"amount"`
+	CapturedAmount    int64     `json:"captured_amount"`
+	RefundedAmount    int64     `json:"refunded_amount"`
+	Currency          string    `json:"currency"`
+	ClientSecret      *string   `json:"client_secret,omitempty"` // Only for the customer
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Money is the amount the payment is for.
+func (p *Payment) Money() money.Money {
+	return money.Money{Amount: p.Amount, Currency: p.Currency}
+}
+
+// Ledger entry types
+const (
+	EntryAuthorization = "authorization"
+	EntryCapture       = "capture"
+	EntryRefund        = "refund"
+	EntryVoid          = "void"
+)
+
+// LedgerEntry records money moving for a payment. Amount is positive;
+// EntryType gives the direction.
+type LedgerEntry struct {
+	ID                int64     `json:"id"`
+	PaymentID         int64     `json:"payment_id"`
+	OrderID           int64     `json:"-"`
+	EntryType         string    `json:"entry_type"`
+	Amount            int64     `json:"amount"`
+	Currency          string    `json:"currency"`
+	ProviderReference *string   `json:"provider_reference,omitempty"`
+	IdempotencyKey    string    `json:"-"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Inbound webhook statuses
+const (
+	WebhookPending   = "pending"
+	WebhookProcessed = "processed"
+	WebhookFailed    = "failed"  // Gave up after the last retry
+	WebhookIgnored   = "ignored" // No handler for the event type
+)
+
+// InboundWebhook is a webhook as received, with the state of handling it.
+type InboundWebhook struct {
+	ID        int64
+	Provider  string
+	EventID   string
+	EventType string
+	Payload   string
+	Status    string
+	Attempts  int
+}
+
+func (e *LedgerEntry) Money() money.Money {
+	return money.Money{Amount: e.Amount, Currency: e.Currency}
+}
+
+// Refund statuses
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund reason codes
+const (
+	ReasonMissingItem  = "missing_item"
+	ReasonWrongItem    = "wrong_item"
+	ReasonQualityIssue = "quality_issue"
+	ReasonLateDelivery = "late_delivery"
+	ReasonGoodwill     = "goodwill"
+	ReasonOther        = "other"
+)
+
+// Refund returns money from a captured payment, for order lines or for an
+// amount.
+type Refund struct {
+	ID               int64         `json:"id"`
+	OrderID          int64         `json:"order_id"`
+	PaymentID        int64         `json:"payment_id"`
+	Amount           int64         `json:"amount"`
+	Currency         string        `json:"currency"`
+	ReasonCode       string        `json:"reason_code"`
+	Note             *string       `json:"note,omitempty"`
+	Status           string        `json:"status"`
+	ProviderRefundID *string       `json:"provider_refund_id,omitempty"`
+	FailureReason    *string       `json:"failure_reason,omitempty"`
+	IdempotencyKey   *string       `json:"-"`
+	CreatedBy        *int64        `json:"created_by,omitempty"`
+	Lines            []*RefundLine `json:"lines"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
+type RefundLine struct {
+	ID          int64 `json:"id"`
+	RefundID    int64 `json:"-"`
+	OrderLineID int64 `json:"order_line_id"`
+	Quantity    int   `json:"quantity"`
+	Amount      int64 `json:"amount"`
+}
+
+// RefundRequest refunds either lines or an amount; with neither it refunds
+// everything not refunded yet.
+type RefundRequest struct {
+	Lines      []RefundLineRequest `json:"lines" binding:"omitempty,dive"`
+	Amount     *int64              `json:"amount" binding:"omitempty,min=1"`
+	ReasonCode string              `json:"reason_code" binding:"required,oneof=missing_item wrong_item quality_issue late_delivery goodwill other"`
+	Note       *string             `json:"note" binding:"omitempty,max=1000"`
+}
+
+type RefundLineRequest struct {
+	LineID   int64 `json:"line_id" binding:"required"`
+	Quantity int   `json:"quantity" binding:"required,min=1"`
+}
+
+// Financials is everything that happened to the money of an order. Captured
+// and Refunded follow the ledger; Refundable is what is left to refund,
+// less refunds still in progress.
+type Financials struct {
+	OrderID    int64          `json:"order_id"`
+	Status     string         `json:"status"`
+	Currency   string         `json:"currency"`
+	Total      int64          `json:"total"`
+	Captured   int64          `json:"captured"`
+	Refunded   int64          `json:"refunded"`
+	Net        int64          `json:"net"`
+	Refundable int64          `json:"refundable"`
+	Payments   []*Payment     `json:"payments"`
+	Refunds    []*Refund      `json:"refunds"`
+	Ledger     []*LedgerEntry `json:"ledger"`
+}
@@ -0,0 +1,81 @@
+package payments
+
+import (
+	"fmt"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+)
+
+const (
+	StatusPending    = "pending" // Waiting for the customer to confirm
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusVoided     = "voided"
+	StatusFailed     = "failed"
+)
+
+// ProviderPayment is a payment as the provider reports it.
+type ProviderPayment struct {
+	ID           string
+	Status       string // One of the payment statuses
+	ClientSecret string // Lets the customer's browser confirm the payment, if the provider needs that
+}
+
+// Webhook event types the payments module handles. Providers translate their
+// own events to these; others keep the provider's type and are only stored.
+const (
+	EventPaymentAuthorized = "payment.authorized"
+	EventPaymentCaptured   = "payment.captured"
+	EventPaymentVoided     = "payment.voided"
+	EventPaymentFailed     = "payment.failed"
+)
+
+// WebhookEvent is what a provider reports about one of its payments.
+type WebhookEvent struct {
+	ID        string // The provider's event ID, the same for every delivery
+	Type      string
+	PaymentID string // The provider's payment ID
+	Amount    int64  // Authorized or captured amount, where the event has one
+}
+
+type AuthorizeRequest struct {
+	Amount         int64
+	Currency       string
+	IdempotencyKey string
+	Metadata       map[string]string
+}
+
+// PaymentProvider moves money through a payment service. Amounts are in
+// minor units of currency. Every call carries an idempotency key, so that
+// retrying it after a timeout cannot move the money twice.
+type PaymentProvider interface {
+	Name() string
+	// Authorize reserves the amount on the customer's payment method, or
+	// starts to when the customer still has to confirm (StatusPending).
+	Authorize(req *AuthorizeRequest) (*ProviderPayment, error)
+	Capture(paymentID string, amount int64, idempotencyKey string) error
+	// Refund returns the provider's ID of the refund.
+	Refund(paymentID string, amount int64, idempotencyKey string) (string, error)
+	Void(paymentID string, idempotencyKey string) error
+
+	// SignatureHeader names the header that carries a webhook's signature.
+	SignatureHeader() string
+	// ParseWebhook decodes the body of a webhook whose signature has been
+	// verified.
+	ParseWebhook(body []byte) (*WebhookEvent, error)
+}
+
+// NewProvider creates the provider selected by cfg.Provider.
+func NewProvider(cfg config.PaymentsConfig) (PaymentProvider, error) {
+	switch cfg.Provider {
+	case "stripe":
+		if cfg.StripeSecretKey == "" {
+			return nil, fmt.Errorf("STRIPE_SECRET_KEY is required for the stripe payment provider")
+		}
+		return NewStripeProvider(cfg.StripeAPIURL, cfg.StripeSecretKey), nil
+	case "fake", "":
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
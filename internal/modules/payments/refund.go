@@ -0,0 +1,232 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yourcompany/saas-platform/internal/modules/auth"
+	"github.com/yourcompany/saas-platform/internal/modules/orders"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/money"
+)
+
+// Refunds are issued by platform admins and the restaurant's managers.
+var refundRoles = []string{restaurants.MemberRoleOwner, restaurants.MemberRoleManager}
+
+// Refund returns money from the captured payment of an order: the given
+// quantities of order lines at the price they were ordered at, an amount,
+// or everything not refunded yet. A request repeated with the same
+// idempotency key returns the first refund.
+func (s *Service) Refund(actor *restaurants.Actor, orderID int64, req *RefundRequest, idempotencyKey string) (*Refund, error) {
+	order, err := s.orders.Lookup(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restaurants.Authorize(actor, order.RestaurantID, auth.PermissionRestaurantsWrite, refundRoles...); err != nil {
+		return nil, err
+	}
+
+	if len(req.Lines) > 0 && req.Amount != nil {
+		return nil, errors.New("refund either lines or an amount")
+	}
+	if len(idempotencyKey) > 255 {
+		return nil, errors.New("idempotency key is too long")
+	}
+
+	payment, err := s.repo.GetOpenByOrder(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil || payment.Status != StatusCaptured {
+		return nil, errors.New("order has no captured payment to refund")
+	}
+
+	refund := &Refund{
+		OrderID:    order.ID,
+		PaymentID:  payment.ID,
+		Currency:   payment.Currency,
+		ReasonCode: req.ReasonCode,
+		Note:       req.Note,
+		Status:     RefundPending,
+		CreatedBy:  &actor.UserID,
+		Lines:      []*RefundLine{},
+	}
+	if idempotencyKey != "" {
+		refund.IdempotencyKey = &idempotencyKey
+	}
+
+	if len(req.Lines) > 0 {
+		lines, total, err := refundLines(order, req.Lines)
+		if err != nil {
+			return nil, err
+		}
+		// Lines are priced in the order's currency, which is the payment's
+		if !total.SameCurrency(payment.Money()) {
+			return nil, fmt.Errorf("order is in %s, the payment in %s", total.Currency, payment.Currency)
+		}
+		refund.Lines = lines
+		refund.Amount = total.Amount
+	} else if req.Amount != nil {
+		refund.Amount = *req.Amount
+	}
+
+	created, err := s.repo.CreateRefund(refund)
+	if err != nil || !created {
+		return refund, err
+	}
+
+	key := refundKey(refund)
+	reference, err := s.provider.Refund(payment.ProviderPaymentID, refund.Amount, key)
+	if err != nil {
+		if err := s.repo.FailRefund(refund, err.Error()); err != nil {
+			log.Printf("Failed to mark refund %d failed: %v", refund.ID, err)
+		}
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	err = s.repo.CompleteRefund(refund, &LedgerEntry{
+		OrderID:           order.ID,
+		EntryType:         EntryRefund,
+		Amount:            refund.Amount,
+		Currency:          refund.Currency,
+		ProviderReference: &reference,
+		IdempotencyKey:    key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func refundKey(refund *Refund) string {
+	return fmt.Sprintf("refund-%d", refund.ID)
+}
+
+// refundLines prices the requested quantities of order lines, with the tax
+// charged on top of them if any, and returns them with their total.
+func refundLines(order *orders.Order, requested []RefundLineRequest) ([]*RefundLine, money.Money, error) {
+	byID := make(map[int64]*orders.Line, len(order.Lines))
+	for _, line := range order.Lines {
+		byID[line.ID] = line
+	}
+
+	lines := make([]*RefundLine, 0, len(requested))
+	total := money.Zero(order.Currency)
+	seen := make(map[int64]bool, len(requested))
+	for _, req := range requested {
+		line, ok := byID[req.LineID]
+		if !ok {
+			return nil, money.Money{}, fmt.Errorf("line %d is not in the order", req.LineID)
+		}
+		if seen[req.LineID] {
+			return nil, money.Money{}, fmt.Errorf("line %d is listed twice", req.LineID)
+		}
+		seen[req.LineID] = true
+
+		if req.Quantity > line.Quantity {
+			return nil, money.Money{}, fmt.Errorf("line %d has only %d ordered", req.LineID, line.Quantity)
+		}
+
+		amount, err := money.Money{Amount: line.UnitPrice, Currency: order.Currency}.Mul(int64(req.Quantity))
+		if err != nil {
+			return nil, money.Money{}, err
+		}
+		tax, err := order.Breakdown.LineTax(amount, line.TaxRateBP)
+		if err != nil {
+			return nil, money.Money{}, err
+		}
+		if amount, err = amount.Add(tax); err != nil {
+			return nil, money.Money{}, err
+		}
+		if total, err = total.Add(amount); err != nil {
+			return nil, money.Money{}, err
+		}
+
+		lines = append(lines, &RefundLine{
+			OrderLineID: line.ID,
+			Quantity:    req.Quantity,
+			Amount:      amount.Amount,
+		})
+	}
+
+	return lines, total, nil
+}
+
+// GetFinancials returns the payments, refunds and ledger of an order with
+// their totals, for the same people who may refund it.
+func (s *Service) GetFinancials(actor *restaurants.Actor, orderID int64) (*Financials, error) {
+	order, err := s.orders.Lookup(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restaurants.Authorize(actor, order.RestaurantID, auth.PermissionRestaurantsRead, refundRoles...); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.repo.GetByOrder(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.repo.GetRefunds(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.repo.GetLedger(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	financials := &Financials{
+		OrderID:  order.ID,
+		Status:   order.Status,
+		Currency: order.Currency,
+		Total:    order.Total,
+		Payments: payments,
+		Refunds:  refunds,
+		Ledger:   ledger,
+	}
+
+	for _, payment := range payments {
+		payment.ClientSecret = nil
+	}
+
+	// Amounts in another currency would mean a broken ledger, not a sum
+	captured, refunded := money.Zero(order.Currency), money.Zero(order.Currency)
+	for _, entry := range ledger {
+		var err error
+		switch entry.EntryType {
+		case EntryCapture:
+			captured, err = captured.Add(entry.Money())
+		case EntryRefund:
+			refunded, err = refunded.Add(entry.Money())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ledger entry %d: %w", entry.ID, err)
+		}
+	}
+
+	net, err := captured.Sub(refunded)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refunds still in progress already count against what is left
+	refundable := captured
+	for _, refund := range refunds {
+		if refund.Status == RefundFailed {
+			continue
+		}
+		if refundable, err = refundable.Sub(money.Money{Amount: refund.Amount, Currency: refund.Currency}); err != nil {
+			return nil, fmt.Errorf("refund %d: %w", refund.ID, err)
+		}
+	}
+
+	financials.Captured = captured.Amount
+	financials.Refunded = refunded.Amount
+	financials.Net = net.Amount
+	financials.Refundable = refundable.Amount
+
+	return financials, nil
+}
@@ -0,0 +1,628 @@
+package payments
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrPaymentChanged means a payment's status changed while an update to it
+// was being prepared.
+var ErrPaymentChanged = errors.New("payment changed concurrently")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const paymentColumns = `id, order_id, provider, provider_payment_id, status, amount, captured_amount, refunded_amount, currency, client_secret, created_at, updated_at`
+
+func scanPayment(row rowScanner) (*Payment, error) {
+	payment := &Payment{}
+	var clientSecret sql.NullString
+
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Provider,
+		&payment.ProviderPaymentID,
+		&payment.Status,
+		&payment.Amount,
+		&payment.CapturedAmount,
+		&payment.RefundedAmount,
+		&payment.Currency,
+		&clientSecret,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientSecret.Valid {
+		payment.ClientSecret = &clientSecret.String
+	}
+
+	return payment, nil
+}
+
+// Create stores a payment and, if it is already authorized, its first ledger
+// entry.
+func (r *Repository) Create(payment *Payment, entry *LedgerEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO payments (order_id, provider, provider_payment_id, status, amount, currency, client_secret)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`,
+		payment.OrderID,
+		payment.Provider,
+		payment.ProviderPaymentID,
+		payment.Status,
+		payment.Amount,
+		payment.Currency,
+		payment.ClientSecret,
+	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	if entry != nil {
+		entry.PaymentID = payment.ID
+		if _, err := insertEntry(tx, entry); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpenByOrder returns the payment of an order that is in progress or done,
+// or nil if there is none.
+func (r *Repository) GetOpenByOrder(orderID int64) (*Payment, error) {
+	payment, err := scanPayment(r.db.QueryRow(
+		`SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 AND status IN ('pending', 'authorized', 'captured')`,
+		orderID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return payment, nil
+}
+
+// CountByOrder returns how many payments have been tried for an order.
+func (r *Repository) CountByOrder(orderID int64) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM payments WHERE order_id = $1`, orderID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count payments: %w", err)
+	}
+	return count, nil
+}
+
+// GetByProviderPayment returns a payment by the provider's ID for it.
+func (r *Repository) GetByProviderPayment(provider, providerPaymentID string) (*Payment, error) {
+	payment, err := scanPayment(r.db.QueryRow(
+		`SELECT `+paymentColumns+` FROM payments WHERE provider = $1 AND provider_payment_id = $2`,
+		provider, providerPaymentID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("payment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return payment, nil
+}
+
+// Record stores the payment's new status and amounts, provided it is still in
+// status from, together with a ledger entry, if given. It reports false,
+// changing nothing, if an entry with the same idempotency key was recorded
+// before, and fails with ErrPaymentChanged if the payment has left status
+// from.
+func (r *Repository) Record(payment *Payment, from string, entry *LedgerEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if entry != nil {
+		entry.PaymentID = payment.ID
+		inserted, err := insertEntry(tx, entry)
+		if err != nil || !inserted {
+			return false, err
+		}
+	}
+
+	err = tx.QueryRow(`
+		UPDATE payments
+		SET status = $1, captured_amount = $2, refunded_amount = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = $5
+		RETURNING updated_at
+	`, payment.Status, payment.CapturedAmount, payment.RefundedAmount, payment.ID, from,
+	).Scan(&payment.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, ErrPaymentChanged
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetLedger returns the ledger entries of an order, oldest first.
+func (r *Repository) GetLedger(orderID int64) ([]*LedgerEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, payment_id, order_id, entry_type, amount, currency, provider_reference, idempotency_key, created_at
+		FROM payment_ledger WHERE order_id = $1 ORDER BY id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*LedgerEntry{}
+	for rows.Next() {
+		entry := &LedgerEntry{}
+		var reference sql.NullString
+		err := rows.Scan(
+			&entry.ID,
+			&entry.PaymentID,
+			&entry.OrderID,
+			&entry.EntryType,
+			&entry.Amount,
+			&entry.Currency,
+			&reference,
+			&entry.IdempotencyKey,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		if reference.Valid {
+			entry.ProviderReference = &reference.String
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func insertEntry(tx *sql.Tx, entry *LedgerEntry) (bool, error) {
+	err := tx.QueryRow(`
+		INSERT INTO payment_ledger (payment_id, order_id, entry_type, amount, currency, provider_reference, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING id, created_at
+	`,
+		entry.PaymentID,
+		entry.OrderID,
+		entry.EntryType,
+		entry.Amount,
+		entry.Currency,
+		entry.ProviderReference,
+		entry.IdempotencyKey,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record ledger entry: %w", err)
+	}
+
+	return true, nil
+}
+
+// SaveWebhook stores a received webhook. It reports false, storing nothing,
+// if the provider delivered the same event before.
+func (r *Repository) SaveWebhook(webhook *InboundWebhook) (bool, error) {
+	err := r.db.QueryRow(`
+		INSERT INTO inbound_webhooks (provider, event_id, event_type, payload, status)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, event_id) DO NOTHING
+		RETURNING id
+	`, webhook.Provider, webhook.EventID, webhook.EventType, webhook.Payload, webhook.Status).Scan(&webhook.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save webhook: %w", err)
+	}
+
+	return true, nil
+}
+
+// ClaimWebhooks takes up to limit pending webhooks that are due and counts
+// an attempt for each. Until lease passes no other claim returns them, so a
+// replica that dies while handling one leaves it to the next.
+func (r *Repository) ClaimWebhooks(limit int, lease time.Duration) ([]*InboundWebhook, error) {
+	rows, err := r.db.Query(`
+		UPDATE inbound_webhooks
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM inbound_webhooks
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, provider, event_id, event_type, payload, status, attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*InboundWebhook{}
+	for rows.Next() {
+		webhook := &InboundWebhook{}
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.Provider,
+			&webhook.EventID,
+			&webhook.EventType,
+			&webhook.Payload,
+			&webhook.Status,
+			&webhook.Attempts,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// FinishWebhook records the final status of a webhook and, for a failed one,
+// why.
+func (r *Repository) FinishWebhook(id int64, status string, lastError *string) error {
+	_, err := r.db.Exec(`
+		UPDATE inbound_webhooks SET status = $1, last_error = $2, processed_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`, status, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+// RetryWebhook makes a webhook due again after delay.
+func (r *Repository) RetryWebhook(id int64, lastError string, delay time.Duration) error {
+	_, err := r.db.Exec(`
+		UPDATE inbound_webhooks SET last_error = $1, next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+		WHERE id = $3
+	`, lastError, delay.Seconds(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+// GetByOrder returns every payment tried for an order, oldest first.
+func (r *Repository) GetByOrder(orderID int64) ([]*Payment, error) {
+	rows, err := r.db.Query(`SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []*Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+const refundColumns = `id, order_id, payment_id, amount, currency, reason_code, note, status, provider_refund_id, failure_reason, idempotency_key, created_by, created_at, updated_at`
+
+func scanRefund(row rowScanner) (*Refund, error) {
+	refund := &Refund{Lines: []*RefundLine{}}
+	var note, providerRefundID, failureReason, idempotencyKey sql.NullString
+	var createdBy sql.NullInt64
+
+	err := row.Scan(
+		&refund.ID,
+		&refund.OrderID,
+		&refund.PaymentID,
+		&refund.Amount,
+		&refund.Currency,
+		&refund.ReasonCode,
+		&note,
+		&refund.Status,
+		&providerRefundID,
+		&failureReason,
+		&idempotencyKey,
+		&createdBy,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if note.Valid {
+		refund.Note = &note.String
+	}
+	if providerRefundID.Valid {
+		refund.ProviderRefundID = &providerRefundID.String
+	}
+	if failureReason.Valid {
+		refund.FailureReason = &failureReason.String
+	}
+	if idempotencyKey.Valid {
+		refund.IdempotencyKey = &idempotencyKey.String
+	}
+	if createdBy.Valid {
+		refund.CreatedBy = &createdBy.Int64
+	}
+
+	return refund, nil
+}
+
+// CreateRefund stores a pending refund after checking, with the payment
+// locked, that it fits in what is left of the capture and of each line. An
+// Amount of 0 takes everything that is left. With an idempotency key used
+// before for the order it returns that refund instead and reports false.
+func (r *Repository) CreateRefund(refund *Refund) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var captured int64
+	err = tx.QueryRow(`SELECT captured_amount FROM payments WHERE id = $1 FOR UPDATE`, refund.PaymentID).Scan(&captured)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock payment: %w", err)
+	}
+
+	// Checked under the lock, so that a repeated request waits for the
+	// first one and finds its refund
+	if refund.IdempotencyKey != nil {
+		existing, err := scanRefund(tx.QueryRow(
+			`SELECT `+refundColumns+` FROM refunds WHERE order_id = $1 AND idempotency_key = $2`,
+			refund.OrderID, *refund.IdempotencyKey,
+		))
+		if err == nil {
+			if err := loadRefundLines(tx, []*Refund{existing}); err != nil {
+				return false, err
+			}
+			*refund = *existing
+			return false, nil
+		}
+		if err != sql.ErrNoRows {
+			return false, fmt.Errorf("failed to get refund: %w", err)
+		}
+	}
+
+	var reserved int64
+	err = tx.QueryRow(
+		`SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE payment_id = $1 AND status <> 'failed'`,
+		refund.PaymentID,
+	).Scan(&reserved)
+	if err != nil {
+		return false, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+
+	left := captured - reserved
+	if refund.Amount == 0 {
+		refund.Amount = left
+	}
+	if left <= 0 {
+		return false, fmt.Errorf("nothing is left to refund")
+	}
+	if refund.Amount > left {
+		return false, fmt.Errorf("only %d is left to refund", left)
+	}
+
+	for _, line := range refund.Lines {
+		var lineLeft int
+		err := tx.QueryRow(`
+			SELECT ol.quantity - COALESCE((
+				SELECT SUM(rl.quantity) FROM refund_lines rl
+				JOIN refunds rf ON rf.id = rl.refund_id
+				WHERE rl.order_line_id = ol.id AND rf.status <> 'failed'
+			), 0)
+			FROM order_lines ol WHERE ol.id = $1 AND ol.order_id = $2
+		`, line.OrderLineID, refund.OrderID).Scan(&lineLeft)
+		if err == sql.ErrNoRows {
+			return false, fmt.Errorf("line %d is not in the order", line.OrderLineID)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to check line: %w", err)
+		}
+		if line.Quantity > lineLeft {
+			return false, fmt.Errorf("only %d of line %d is left to refund", lineLeft, line.OrderLineID)
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO refunds (order_id, payment_id, amount, currency, reason_code, note, status, idempotency_key, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`,
+		refund.OrderID,
+		refund.PaymentID,
+		refund.Amount,
+		refund.Currency,
+		refund.ReasonCode,
+		refund.Note,
+		refund.Status,
+		refund.IdempotencyKey,
+		refund.CreatedBy,
+	).Scan(&refund.ID, &refund.CreatedAt, &refund.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	for _, line := range refund.Lines {
+		line.RefundID = refund.ID
+		err := tx.QueryRow(`
+			INSERT INTO refund_lines (refund_id, order_line_id, quantity, amount)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, line.RefundID, line.OrderLineID, line.Quantity, line.Amount).Scan(&line.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to create refund line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// CompleteRefund marks a refund succeeded and adds it to the ledger and to
+// the payment's refunded amount. Completing it again changes nothing.
+func (r *Repository) CompleteRefund(refund *Refund, entry *LedgerEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry.PaymentID = refund.PaymentID
+	inserted, err := insertEntry(tx, entry)
+	if err != nil || !inserted {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		UPDATE refunds SET status = 'succeeded', provider_refund_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING status, updated_at
+	`, entry.ProviderReference, refund.ID).Scan(&refund.Status, &refund.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	refund.ProviderRefundID = entry.ProviderReference
+
+	_, err = tx.Exec(`
+		UPDATE payments SET refunded_amount = refunded_amount + $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, refund.Amount, refund.PaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// FailRefund marks a pending refund failed, which frees its amount and lines
+// for another refund.
+func (r *Repository) FailRefund(refund *Refund, reason string) error {
+	err := r.db.QueryRow(`
+		UPDATE refunds SET status = 'failed', failure_reason = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = 'pending'
+		RETURNING status, updated_at
+	`, reason, refund.ID).Scan(&refund.Status, &refund.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	refund.FailureReason = &reason
+	return nil
+}
+
+// GetRefunds returns the refunds of an order with their lines, oldest first.
+func (r *Repository) GetRefunds(orderID int64) ([]*Refund, error) {
+	rows, err := r.db.Query(`SELECT `+refundColumns+` FROM refunds WHERE order_id = $1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := []*Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadRefundLines(r.db, refunds); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadRefundLines(db queryer, refunds []*Refund) error {
+	if len(refunds) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Refund, len(refunds))
+	ids := make([]int64, 0, len(refunds))
+	for _, refund := range refunds {
+		byID[refund.ID] = refund
+		ids = append(ids, refund.ID)
+	}
+
+	rows, err := db.Query(`
+		SELECT id, refund_id, order_line_id, quantity, amount
+		FROM refund_lines WHERE refund_id = ANY($1) ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get refund lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := &RefundLine{}
+		if err := rows.Scan(&line.ID, &line.RefundID, &line.OrderLineID, &line.Quantity, &line.Amount); err != nil {
+			return fmt.Errorf("failed to scan refund line: %w", err)
+		}
+		byID[line.RefundID].Lines = append(byID[line.RefundID].Lines, line)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,255 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/modules/orders"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/money"
+)
+
+type Service struct {
+	repo        *Repository
+	restaurants *restaurants.Service
+	orders      *orders.Service
+	provider    PaymentProvider
+	cfg         config.PaymentsConfig
+
+	webhookHandlers map[string]func(*WebhookEvent) error
+	wake            chan struct{} // Signals that a webhook has arrived
+}
+
+func NewService(repo *Repository, restaurantsService *restaurants.Service, ordersService *orders.Service, provider PaymentProvider, cfg config.PaymentsConfig) *Service {
+	s := &Service{
+		repo:        repo,
+		restaurants: restaurantsService,
+		orders:      ordersService,
+		provider:    provider,
+		cfg:         cfg,
+		wake:        make(chan struct{}, 1),
+	}
+	s.webhookHandlers = map[string]func(*WebhookEvent) error{
+		EventPaymentAuthorized: s.paymentAuthorized,
+		EventPaymentCaptured:   s.paymentCaptured,
+		EventPaymentVoided:     s.paymentVoided,
+		EventPaymentFailed:     s.paymentFailed,
+	}
+	return s
+}
+
+// Pay starts paying for an order of the current user and returns the
+// payment, with the client secret when the customer has to confirm it. An
+// order being paid for already returns that payment.
+func (s *Service) Pay(actor *restaurants.Actor, orderID int64) (*Payment, error) {
+	order, err := s.orders.GetMyOrder(actor, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == orders.StatusRejected || order.Status == orders.StatusCancelled {
+		return nil, fmt.Errorf("order is %s", order.Status)
+	}
+	if order.Total <= 0 {
+		return nil, errors.New("order has nothing to pay")
+	}
+	if _, err := money.NormalizeCurrency(order.Currency); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.repo.GetOpenByOrder(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if payment != nil {
+		return payment, nil
+	}
+	// Delivery is what captures a payment, so one started afterwards would
+	// only ever be held
+	if order.Status == orders.StatusDelivered {
+		return nil, fmt.Errorf("order is %s", order.Status)
+	}
+
+	// Each attempt gets its own key, so that a declined payment can be
+	// tried again while a retried request reuses the attempt it started
+	attempts, err := s.repo.CountByOrder(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("order-%d-payment-%d", order.ID, attempts+1)
+
+	result, err := s.provider.Authorize(&AuthorizeRequest{
+		Amount:         order.Total,
+		Currency:       order.Currency,
+		IdempotencyKey: key,
+		Metadata:       map[string]string{"order_id": strconv.FormatInt(order.ID, 10)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start payment: %w", err)
+	}
+
+	payment = &Payment{
+		OrderID:           order.ID,
+		Provider:          s.provider.Name(),
+		ProviderPaymentID: result.ID,
+		Status:            result.Status,
+		Amount:            order.Total,
+		Currency:          order.Currency,
+	}
+	if result.ClientSecret != "" {
+		payment.ClientSecret = &result.ClientSecret
+	}
+
+	var entry *LedgerEntry
+	if payment.Status == StatusAuthorized {
+		entry = &LedgerEntry{
+			OrderID:        order.ID,
+			EntryType:      EntryAuthorization,
+			Amount:         payment.Amount,
+			Currency:       payment.Currency,
+			IdempotencyKey: authorizationKey(payment),
+		}
+	}
+
+	if err := s.repo.Create(payment, entry); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// GetPayment returns the payment of an order of the current user.
+func (s *Service) GetPayment(actor *restaurants.Actor, orderID int64) (*Payment, error) {
+	order, err := s.orders.GetMyOrder(actor, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openPayment(order.ID)
+}
+
+// GetRestaurantPayment returns the payment of a restaurant's order, without
+// the customer's client secret.
+func (s *Service) GetRestaurantPayment(actor *restaurants.Actor, restaurantID, orderID int64) (*Payment, error) {
+	order, err := s.orders.GetRestaurantOrder(actor, restaurantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.openPayment(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	payment.ClientSecret = nil
+
+	return payment, nil
+}
+
+func (s *Service) openPayment(orderID int64) (*Payment, error) {
+	payment, err := s.repo.GetOpenByOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, errors.New("payment not found")
+	}
+	return payment, nil
+}
+
+// OrderStatusChanged captures the payment of a delivered order and voids
+// that of a rejected or cancelled one.
+func (s *Service) OrderStatusChanged(order *orders.Order, from string) {
+	var err error
+	switch order.Status {
+	case orders.StatusDelivered:
+		err = s.capture(order)
+	case orders.StatusRejected, orders.StatusCancelled:
+		err = s.void(order)
+	}
+	if err != nil {
+		log.Printf("Failed to settle payment of order %d (%s -> %s): %v", order.ID, from, order.Status, err)
+	}
+}
+
+// The ledger keys of the money movements of a payment. Whether a movement
+// is seen in a provider's response or in its webhook, it is recorded once.
+func authorizationKey(payment *Payment) string {
+	return fmt.Sprintf("payment-%s-%s-authorization", payment.Provider, payment.ProviderPaymentID)
+}
+
+func captureKey(payment *Payment) string {
+	return fmt.Sprintf("payment-%d-capture", payment.ID)
+}
+
+func voidKey(payment *Payment) string {
+	return fmt.Sprintf("payment-%d-void", payment.ID)
+}
+
+// capture takes the authorized amount. It can run any number of times: the
+// provider call and the ledger entry share an idempotency key, and a
+// captured payment is left alone. A payment still pending is captured when
+// its authorization arrives.
+func (s *Service) capture(order *orders.Order) error {
+	payment, err := s.openPayment(order.ID)
+	if err != nil {
+		return err
+	}
+
+	if payment.Status == StatusCaptured || payment.Status == StatusPending {
+		return nil
+	}
+	if payment.Status != StatusAuthorized {
+		return fmt.Errorf("payment %d is %s, not authorized", payment.ID, payment.Status)
+	}
+	// The ledger has to reconcile with the order snapshot
+	if !payment.Money().Equal(order.TotalMoney()) {
+		return fmt.Errorf("payment %d of %s does not match the order total of %s", payment.ID, payment.Money(), order.TotalMoney())
+	}
+
+	key := captureKey(payment)
+	if err := s.provider.Capture(payment.ProviderPaymentID, payment.Amount, key); err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	payment.Status = StatusCaptured
+	payment.CapturedAmount = payment.Amount
+	_, err = s.repo.Record(payment, StatusAuthorized, &LedgerEntry{
+		OrderID:        order.ID,
+		EntryType:      EntryCapture,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		IdempotencyKey: key,
+	})
+	return err
+}
+
+// void releases the money held for an order that will not be delivered.
+func (s *Service) void(order *orders.Order) error {
+	payment, err := s.repo.GetOpenByOrder(order.ID)
+	if err != nil || payment == nil {
+		return err
+	}
+
+	if payment.Status == StatusCaptured {
+		return fmt.Errorf("payment %d is already captured and has to be refunded", payment.ID)
+	}
+
+	key := voidKey(payment)
+	if err := s.provider.Void(payment.ProviderPaymentID, key); err != nil {
+		return fmt.Errorf("failed to void payment: %w", err)
+	}
+
+	from := payment.Status
+	payment.Status = StatusVoided
+	_, err = s.repo.Record(payment, from, &LedgerEntry{
+		OrderID:        order.ID,
+		EntryType:      EntryVoid,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		IdempotencyKey: key,
+	})
+	return err
+}
@@ -0,0 +1,287 @@
+package payments
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/database/dbtest"
+	"github.com/yourcompany/saas-platform/internal/mailer"
+	"github.com/yourcompany/saas-platform/internal/modules/carts"
+	"github.com/yourcompany/saas-platform/internal/modules/menus"
+	"github.com/yourcompany/saas-platform/internal/modules/orders"
+	"github.com/yourcompany/saas-platform/internal/modules/restaurants"
+)
+
+type testEnv struct {
+	db       *sql.DB
+	service  *Service
+	orders   *orders.Service
+	provider *FakeProvider
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	db := dbtest.Open(t)
+
+	mail := mailer.NewLogMailer(t.TempDir(), "test@example.com")
+	restaurantsService := restaurants.NewService(restaurants.NewRepository(db), mail, "")
+	menusService := menus.NewService(menus.NewRepository(db), restaurantsService)
+	cartsService := carts.NewService(carts.NewRepository(db), restaurantsService, menusService)
+	ordersService := orders.NewService(orders.NewRepository(db), restaurantsService, menusService, cartsService)
+
+	provider := NewFakeProvider()
+	service := NewService(NewRepository(db), restaurantsService, ordersService, provider, config.PaymentsConfig{
+		Provider:         provider.Name(),
+		WebhookSecret:    testWebhookSecret,
+		WebhookTolerance: 5 * time.Minute,
+	})
+
+	return &testEnv{db: db, service: service, orders: ordersService, provider: provider}
+}
+
+// createOrder stores an order of a new customer that is out for delivery.
+func (env *testEnv) createOrder(t *testing.T, total int64) (*restaurants.Actor, int64) {
+	t.Helper()
+
+	actor := &restaurants.Actor{Email: dbtest.Unique("customer") + "@example.com"}
+	if err := env.db.QueryRow(
+		"INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id", actor.Email,
+	).Scan(&actor.UserID); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	var restaurantID int64
+	if err := env.db.QueryRow(
+		"INSERT INTO restaurants (name, slug, currency) VALUES ('Test', $1, 'EUR') RETURNING id", dbtest.Unique("test"),
+	).Scan(&restaurantID); err != nil {
+		t.Fatalf("failed to create restaurant: %v", err)
+	}
+
+	var orderID int64
+	if err := env.db.QueryRow(
+		"INSERT INTO orders (user_id, restaurant_id, status, currency, subtotal, total) VALUES ($1, $2, $3, 'EUR', $4, $4) RETURNING id",
+		actor.UserID, restaurantID, orders.StatusPickedUp, total,
+	).Scan(&orderID); err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	return actor, orderID
+}
+
+// deliver tells the payments module that the order was delivered, as the
+// orders module does.
+func (env *testEnv) deliver(t *testing.T, orderID int64) {
+	t.Helper()
+
+	order, err := env.orders.Lookup(orderID)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	order.Status = orders.StatusDelivered
+	env.service.OrderStatusChanged(order, orders.StatusPickedUp)
+}
+
+// receive delivers a signed webhook and handles it, reporting whether it was
+// a duplicate.
+func (env *testEnv) receive(t *testing.T, eventID, eventType, paymentID string, amount int64) bool {
+	t.Helper()
+
+	body := []byte(fmt.Sprintf(`{"id":%q,"type":%q,"payment_id":%q,"amount":%d}`, eventID, eventType, paymentID, amount))
+	now := time.Now().Unix()
+	header := http.Header{}
+	header.Set(env.provider.SignatureHeader(), fmt.Sprintf("t=%d,v1=%s", now, sign(testWebhookSecret, now, body)))
+
+	duplicate, err := env.service.ReceiveWebhook(env.provider.Name(), header, body)
+	if err != nil {
+		t.Fatalf("failed to receive webhook: %v", err)
+	}
+	env.service.dispatchWebhooks()
+
+	var status string
+	var lastError sql.NullString
+	if err := env.db.QueryRow(
+		"SELECT status, last_error FROM inbound_webhooks WHERE provider = $1 AND event_id = $2",
+		env.provider.Name(), eventID,
+	).Scan(&status, &lastError); err != nil {
+		t.Fatalf("failed to get webhook: %v", err)
+	}
+	if status != WebhookProcessed {
+		t.Fatalf("webhook %s is %s (%s), want processed", eventID, status, lastError.String)
+	}
+
+	return duplicate
+}
+
+func (env *testEnv) ledger(t *testing.T, orderID int64) map[string][]*LedgerEntry {
+	t.Helper()
+
+	entries, err := env.service.repo.GetLedger(orderID)
+	if err != nil {
+		t.Fatalf("failed to get ledger: %v", err)
+	}
+
+	byType := make(map[string][]*LedgerEntry)
+	for _, entry := range entries {
+		byType[entry.EntryType] = append(byType[entry.EntryType], entry)
+	}
+	return byType
+}
+
+func (env *testEnv) assertCapturedOnce(t *testing.T, orderID int64, amount int64) {
+	t.Helper()
+
+	ledger := env.ledger(t, orderID)
+	if len(ledger[EntryAuthorization]) != 1 {
+		t.Errorf("got %d authorization entries, want 1", len(ledger[EntryAuthorization]))
+	}
+	if len(ledger[EntryCapture]) != 1 {
+		t.Fatalf("got %d capture entries, want 1", len(ledger[EntryCapture]))
+	}
+	if capture := ledger[EntryCapture][0]; capture.Amount != amount || capture.Currency != "EUR" {
+		t.Errorf("capture entry: got %d %s, want %d EUR", capture.Amount, capture.Currency, amount)
+	}
+
+	payment, err := env.service.openPayment(orderID)
+	if err != nil {
+		t.Fatalf("failed to get payment: %v", err)
+	}
+	if payment.Status != StatusCaptured || payment.CapturedAmount != amount {
+		t.Errorf("payment: got %s with %d captured, want captured with %d", payment.Status, payment.CapturedAmount, amount)
+	}
+}
+
+func TestCaptureOnDeliveryThenReplayedWebhook(t *testing.T) {
+	env := newTestEnv(t)
+	actor, orderID := env.createOrder(t, 2500)
+
+	payment, err := env.service.Pay(actor, orderID)
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+	if payment.Status != StatusAuthorized {
+		t.Fatalf("payment is %s, want authorized", payment.Status)
+	}
+
+	// Paying again returns the same payment
+	again, err := env.service.Pay(actor, orderID)
+	if err != nil || again.ID != payment.ID {
+		t.Fatalf("Pay again: got payment %v, %v; want payment %d", again, err, payment.ID)
+	}
+
+	env.deliver(t, orderID)
+	env.assertCapturedOnce(t, orderID, 2500)
+
+	// Delivery reported twice captures nothing more
+	env.deliver(t, orderID)
+	env.assertCapturedOnce(t, orderID, 2500)
+
+	// The provider's own event for our capture, then the same event again
+	eventID := dbtest.Unique("evt")
+	if env.receive(t, eventID, EventPaymentCaptured, payment.ProviderPaymentID, 2500) {
+		t.Error("first delivery of the event reported as a duplicate")
+	}
+	if !env.receive(t, eventID, EventPaymentCaptured, payment.ProviderPaymentID, 2500) {
+		t.Error("replayed event not reported as a duplicate")
+	}
+	// And the same capture under a new event ID
+	env.receive(t, dbtest.Unique("evt"), EventPaymentCaptured, payment.ProviderPaymentID, 2500)
+
+	env.assertCapturedOnce(t, orderID, 2500)
+}
+
+func TestCaptureWebhookBeforeDelivery(t *testing.T) {
+	env := newTestEnv(t)
+	actor, orderID := env.createOrder(t, 1800)
+
+	payment, err := env.service.Pay(actor, orderID)
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+
+	env.receive(t, dbtest.Unique("evt"), EventPaymentCaptured, payment.ProviderPaymentID, 1800)
+	env.assertCapturedOnce(t, orderID, 1800)
+
+	env.deliver(t, orderID)
+	env.assertCapturedOnce(t, orderID, 1800)
+}
+
+// A webhook handler that read the payment before our capture committed
+// records under the same key, which changes nothing.
+func TestRecordExistingEntryChangesNothing(t *testing.T) {
+	env := newTestEnv(t)
+	actor, orderID := env.createOrder(t, 1200)
+
+	payment, err := env.service.Pay(actor, orderID)
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+	stale, err := env.service.openPayment(orderID)
+	if err != nil {
+		t.Fatalf("failed to get payment: %v", err)
+	}
+
+	env.deliver(t, orderID)
+
+	stale.Status = StatusCaptured
+	stale.CapturedAmount = 700
+	recorded, err := env.service.repo.Record(stale, StatusAuthorized, &LedgerEntry{
+		OrderID:        orderID,
+		EntryType:      EntryCapture,
+		Amount:         700,
+		Currency:       stale.Currency,
+		IdempotencyKey: captureKey(payment),
+	})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if recorded {
+		t.Error("Record reported a duplicate entry as recorded")
+	}
+
+	env.assertCapturedOnce(t, orderID, 1200)
+}
+
+// An order delivered before the customer approved the payment is captured
+// when the approval arrives.
+func TestDeliveredBeforeAuthorization(t *testing.T) {
+	env := newTestEnv(t)
+	env.provider.Confirm = func(int64) bool { return true }
+	actor, orderID := env.createOrder(t, 3100)
+
+	payment, err := env.service.Pay(actor, orderID)
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+	if payment.Status != StatusPending {
+		t.Fatalf("payment is %s, want pending", payment.Status)
+	}
+
+	if _, err := env.db.Exec("UPDATE orders SET status = $1 WHERE id = $2", orders.StatusDelivered, orderID); err != nil {
+		t.Fatalf("failed to deliver order: %v", err)
+	}
+	env.deliver(t, orderID)
+	if ledger := env.ledger(t, orderID); len(ledger[EntryCapture]) != 0 {
+		t.Fatal("captured a pending payment")
+	}
+
+	if err := env.provider.Authenticate(payment.ProviderPaymentID); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	env.receive(t, dbtest.Unique("evt"), EventPaymentAuthorized, payment.ProviderPaymentID, 3100)
+	env.assertCapturedOnce(t, orderID, 3100)
+}
+
+func TestPayForDeliveredOrder(t *testing.T) {
+	env := newTestEnv(t)
+	actor, orderID := env.createOrder(t, 900)
+
+	if _, err := env.db.Exec("UPDATE orders SET status = $1 WHERE id = $2", orders.StatusDelivered, orderID); err != nil {
+		t.Fatalf("failed to deliver order: %v", err)
+	}
+	if _, err := env.service.Pay(actor, orderID); err == nil {
+		t.Error("paid for a delivered order")
+	}
+}
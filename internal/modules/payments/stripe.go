@@ -0,0 +1,191 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeProvider talks to the Stripe API, or to any service compatible with
+// it, through manually captured PaymentIntents.
+type StripeProvider struct {
+	apiURL    string
+	secretKey string
+	client    *http.Client
+}
+
+func NewStripeProvider(apiURL, secretKey string) *StripeProvider {
+	return &StripeProvider{
+		apiURL:    strings.TrimRight(apiURL, "/"),
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+type stripeObject struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"client_secret"`
+}
+
+func (p *StripeProvider) Authorize(req *AuthorizeRequest) (*ProviderPayment, error) {
+	form := url.Values{
+		"amount":                             {strconv.FormatInt(req.Amount, 10)},
+		"currency":                           {strings.ToLower(req.Currency)},
+		"capture_method":                     {"manual"},
+		"automatic_payment_methods[enabled]": {"true"},
+	}
+	for key, value := range req.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	var intent stripeObject
+	if err := p.post("/v1/payment_intents", form, req.IdempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+
+	return &ProviderPayment{
+		ID:           intent.ID,
+		Status:       StripeStatus(intent.Status),
+		ClientSecret: intent.ClientSecret,
+	}, nil
+}
+
+func (p *StripeProvider) Capture(paymentID string, amount int64, idempotencyKey string) error {
+	form := url.Values{"amount_to_capture": {strconv.FormatInt(amount, 10)}}
+	return p.post("/v1/payment_intents/"+url.PathEscape(paymentID)+"/capture", form, idempotencyKey, nil)
+}
+
+func (p *StripeProvider) Refund(paymentID string, amount int64, idempotencyKey string) (string, error) {
+	form := url.Values{
+		"payment_intent": {paymentID},
+		"amount":         {strconv.FormatInt(amount, 10)},
+	}
+
+	var refund stripeObject
+	if err := p.post("/v1/refunds", form, idempotencyKey, &refund); err != nil {
+		return "", err
+	}
+
+	return refund.ID, nil
+}
+
+func (p *StripeProvider) Void(paymentID string, idempotencyKey string) error {
+	return p.post("/v1/payment_intents/"+url.PathEscape(paymentID)+"/cancel", url.Values{}, idempotencyKey, nil)
+}
+
+func (p *StripeProvider) SignatureHeader() string {
+	return "Stripe-Signature"
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID               string `json:"id"`
+			AmountCapturable int64  `json:"amount_capturable"`
+			AmountReceived   int64  `json:"amount_received"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// stripeEventTypes maps the PaymentIntent events the module handles.
+var stripeEventTypes = map[string]string{
+	"payment_intent.amount_capturable_updated": EventPaymentAuthorized,
+	"payment_intent.succeeded":                 EventPaymentCaptured,
+	"payment_intent.canceled":                  EventPaymentVoided,
+	"payment_intent.payment_failed":            EventPaymentFailed,
+}
+
+func (p *StripeProvider) ParseWebhook(body []byte) (*WebhookEvent, error) {
+	var event stripeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode stripe event: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, fmt.Errorf("stripe event has no id or type")
+	}
+
+	result := &WebhookEvent{ID: event.ID, Type: event.Type}
+	if eventType, ok := stripeEventTypes[event.Type]; ok {
+		result.Type = eventType
+		result.PaymentID = event.Data.Object.ID
+		switch eventType {
+		case EventPaymentAuthorized:
+			result.Amount = event.Data.Object.AmountCapturable
+		case EventPaymentCaptured:
+			result.Amount = event.Data.Object.AmountReceived
+		}
+	}
+
+	return result, nil
+}
+
+// StripeStatus maps a PaymentIntent status to a payment status.
+func StripeStatus(status string) string {
+	switch status {
+	case "requires_capture":
+		return StatusAuthorized
+	case "succeeded":
+		return StatusCaptured
+	case "canceled":
+		return StatusVoided
+	default:
+		// requires_payment_method, requires_confirmation, requires_action,
+		// processing
+		return StatusPending
+	}
+}
+
+// post sends a form-encoded request and decodes the response into out, if
+// given.
+func (p *StripeProvider) post(path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, p.apiURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create stripe request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read stripe response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &failure) == nil && failure.Error.Message != "" {
+			return fmt.Errorf("stripe: %s", failure.Error.Message)
+		}
+		return fmt.Errorf("stripe: unexpected status %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to decode stripe response: %w", err)
+		}
+	}
+
+	return nil
+}
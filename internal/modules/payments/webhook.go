@@ -0,0 +1,319 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/modules/orders"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+const (
+	webhookBatch       = 50
+	webhookLease       = 5 * time.Minute // Longer than handling a webhook can take
+	webhookMaxAttempts = 10
+	webhookMaxDelay    = time.Hour
+)
+
+// VerifySignature checks a signature header of the form
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">", which is how Stripe
+// signs webhooks and how the fake provider expects them to be signed. The
+// header may carry several v1 values while a secret is being rolled.
+// Signing the time stops an old webhook from being replayed once it is more
+// than tolerance away from now.
+func VerifySignature(header string, body []byte, secret string, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("%w: no webhook secret is configured", ErrInvalidSignature)
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// ReceiveWebhook verifies a webhook from provider and stores it to be handled
+// in the background. It reports whether the event had been received before,
+// in which case it is not handled again.
+func (s *Service) ReceiveWebhook(provider string, header http.Header, body []byte) (bool, error) {
+	if provider != s.provider.Name() {
+		return false, ErrUnknownProvider
+	}
+
+	signature := header.Get(s.provider.SignatureHeader())
+	if err := VerifySignature(signature, body, s.cfg.WebhookSecret, s.cfg.WebhookTolerance, time.Now()); err != nil {
+		return false, err
+	}
+
+	event, err := s.provider.ParseWebhook(body)
+	if err != nil {
+		return false, err
+	}
+
+	webhook := &InboundWebhook{
+		Provider:  provider,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   string(body),
+		Status:    WebhookPending,
+	}
+	if _, ok := s.webhookHandlers[event.Type]; !ok {
+		webhook.Status = WebhookIgnored
+	}
+
+	saved, err := s.repo.SaveWebhook(webhook)
+	if err != nil {
+		return false, err
+	}
+
+	if saved && webhook.Status == WebhookPending {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return !saved, nil
+}
+
+// RunWebhooks handles stored webhooks until stop is closed: as soon as they
+// arrive, and otherwise every interval, which is when failed ones are retried.
+func (s *Service) RunWebhooks(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatchWebhooks()
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Service) dispatchWebhooks() {
+	for {
+		webhooks, err := s.repo.ClaimWebhooks(webhookBatch, webhookLease)
+		if err != nil {
+			log.Printf("Failed to claim webhooks: %v", err)
+			return
+		}
+
+		for _, webhook := range webhooks {
+			s.dispatchWebhook(webhook)
+		}
+
+		if len(webhooks) < webhookBatch {
+			return
+		}
+	}
+}
+
+// dispatchWebhook hands a webhook to the handler of its type. A failed one
+// is tried again with a doubling delay, up to webhookMaxAttempts times; an
+// event the module cannot act on yet, such as one about a payment whose
+// creation has not been committed, then gets another chance.
+func (s *Service) dispatchWebhook(webhook *InboundWebhook) {
+	err := s.handleWebhook(webhook)
+	if err == nil {
+		if err := s.repo.FinishWebhook(webhook.ID, WebhookProcessed, nil); err != nil {
+			log.Printf("Failed to finish webhook %d: %v", webhook.ID, err)
+		}
+		return
+	}
+
+	message := err.Error()
+	if webhook.Attempts >= webhookMaxAttempts {
+		log.Printf("Giving up on webhook %d (%s %s) after %d attempts: %v", webhook.ID, webhook.Provider, webhook.EventID, webhook.Attempts, err)
+		if err := s.repo.FinishWebhook(webhook.ID, WebhookFailed, &message); err != nil {
+			log.Printf("Failed to finish webhook %d: %v", webhook.ID, err)
+		}
+		return
+	}
+
+	if err := s.repo.RetryWebhook(webhook.ID, message, webhookDelay(webhook.Attempts)); err != nil {
+		log.Printf("Failed to reschedule webhook %d: %v", webhook.ID, err)
+	}
+}
+
+// webhookDelay is the wait before attempt+1: 30s, 1m, 2m, ... up to an hour.
+func webhookDelay(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		return webhookMaxDelay
+	}
+	return delay
+}
+
+func (s *Service) handleWebhook(webhook *InboundWebhook) error {
+	if webhook.Provider != s.provider.Name() {
+		return fmt.Errorf("webhook is from %s, not from the configured provider", webhook.Provider)
+	}
+
+	event, err := s.provider.ParseWebhook([]byte(webhook.Payload))
+	if err != nil {
+		return err
+	}
+
+	handler, ok := s.webhookHandlers[event.Type]
+	if !ok {
+		return nil
+	}
+	return handler(event)
+}
+
+// The handlers below bring a payment to the status the provider reports.
+// They never call the provider: an event only records what already
+// happened there, under the same ledger key as the call that made it
+// happen, so an event that is replayed or that follows our own call records
+// nothing more. An event that arrives after the payment has moved on is
+// ignored.
+
+func (s *Service) paymentAuthorized(event *WebhookEvent) error {
+	payment, err := s.repo.GetByProviderPayment(s.provider.Name(), event.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	if payment.Status == StatusPending {
+		payment.Status = StatusAuthorized
+		if _, err := s.repo.Record(payment, StatusPending, &LedgerEntry{
+			OrderID:        payment.OrderID,
+			EntryType:      EntryAuthorization,
+			Amount:         payment.Amount,
+			Currency:       payment.Currency,
+			IdempotencyKey: authorizationKey(payment),
+		}); err != nil {
+			return err
+		}
+	}
+	if payment.Status != StatusAuthorized {
+		return nil
+	}
+
+	// An order delivered while its payment was pending could not be captured
+	// then, so it is captured now. A failed capture fails the webhook, which
+	// is retried.
+	order, err := s.orders.Lookup(payment.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != orders.StatusDelivered {
+		return nil
+	}
+	return s.capture(order)
+}
+
+func (s *Service) paymentCaptured(event *WebhookEvent) error {
+	payment, err := s.repo.GetByProviderPayment(s.provider.Name(), event.PaymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != StatusPending && payment.Status != StatusAuthorized {
+		return nil
+	}
+
+	amount := event.Amount
+	if amount <= 0 || amount > payment.Amount {
+		amount = payment.Amount
+	}
+
+	from := payment.Status
+	payment.Status = StatusCaptured
+	payment.CapturedAmount = amount
+	_, err = s.repo.Record(payment, from, &LedgerEntry{
+		OrderID:        payment.OrderID,
+		EntryType:      EntryCapture,
+		Amount:         amount,
+		Currency:       payment.Currency,
+		IdempotencyKey: captureKey(payment),
+	})
+	return err
+}
+
+func (s *Service) paymentVoided(event *WebhookEvent) error {
+	payment, err := s.repo.GetByProviderPayment(s.provider.Name(), event.PaymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != StatusPending && payment.Status != StatusAuthorized {
+		return nil
+	}
+
+	from := payment.Status
+	payment.Status = StatusVoided
+	_, err = s.repo.Record(payment, from, &LedgerEntry{
+		OrderID:        payment.OrderID,
+		EntryType:      EntryVoid,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		IdempotencyKey: voidKey(payment),
+	})
+	return err
+}
+
+func (s *Service) paymentFailed(event *WebhookEvent) error {
+	payment, err := s.repo.GetByProviderPayment(s.provider.Name(), event.PaymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != StatusPending {
+		return nil
+	}
+
+	payment.Status = StatusFailed
+	_, err = s.repo.Record(payment, StatusPending, nil)
+	return err
+}
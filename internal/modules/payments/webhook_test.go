@@ -0,0 +1,102 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_test"
+
+func sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"payment.captured"}`)
+	now := time.Unix(1700000000, 0)
+	tolerance := 5 * time.Minute
+	ts := now.Unix()
+	t0 := strconv.FormatInt(ts, 10)
+	valid := sign(testWebhookSecret, ts, body)
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		secret  string
+		wantErr bool
+	}{
+		{"valid", "t=" + t0 + ",v1=" + valid, body, testWebhookSecret, false},
+		{"spaces around parts", "t=" + t0 + ", v1=" + valid, body, testWebhookSecret, false},
+		{"unknown parts ignored", "t=" + t0 + ",v0=abc,v1=" + valid, body, testWebhookSecret, false},
+		{"second of several v1 matches", "t=" + t0 + ",v1=" + sign("old", ts, body) + ",v1=" + valid, body, testWebhookSecret, false},
+		{"first of several v1 matches", "t=" + t0 + ",v1=" + valid + ",v1=deadbeef", body, testWebhookSecret, false},
+		{"at the edge of the past tolerance", "t=" + strconv.FormatInt(ts-300, 10) + ",v1=" + sign(testWebhookSecret, ts-300, body), body, testWebhookSecret, false},
+		{"at the edge of the future tolerance", "t=" + strconv.FormatInt(ts+300, 10) + ",v1=" + sign(testWebhookSecret, ts+300, body), body, testWebhookSecret, false},
+		{"too old", "t=" + strconv.FormatInt(ts-301, 10) + ",v1=" + sign(testWebhookSecret, ts-301, body), body, testWebhookSecret, true},
+		{"too far in the future", "t=" + strconv.FormatInt(ts+301, 10) + ",v1=" + sign(testWebhookSecret, ts+301, body), body, testWebhookSecret, true},
+		{"no v1 matches", "t=" + t0 + ",v1=" + sign("old", ts, body) + ",v1=deadbeef", body, testWebhookSecret, true},
+		{"wrong secret", "t=" + t0 + ",v1=" + valid, body, "whsec_other", true},
+		{"body changed", "t=" + t0 + ",v1=" + valid, []byte(`{"id":"evt_2"}`), testWebhookSecret, true},
+		{"timestamp changed", "t=" + strconv.FormatInt(ts+1, 10) + ",v1=" + valid, body, testWebhookSecret, true},
+		{"no secret configured", "t=" + t0 + ",v1=" + valid, body, "", true},
+		{"empty header", "", body, testWebhookSecret, true},
+		{"no timestamp", "v1=" + valid, body, testWebhookSecret, true},
+		{"no signature", "t=" + t0, body, testWebhookSecret, true},
+		{"no separators", "t" + t0 + "v1" + valid, body, testWebhookSecret, true},
+		{"timestamp not a number", "t=yesterday,v1=" + valid, body, testWebhookSecret, true},
+		{"signature not hex", "t=" + t0 + ",v1=zz" + valid[2:], body, testWebhookSecret, true},
+		{"truncated signature", "t=" + t0 + ",v1=" + valid[:32], body, testWebhookSecret, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.header, tt.body, tt.secret, tolerance, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("got %v, want ErrInvalidSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, webhookMaxDelay},
+		{webhookMaxAttempts, webhookMaxDelay},
+		{64, webhookMaxDelay},
+		{1000, webhookMaxDelay},
+		{math.MaxInt, webhookMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			if got := webhookDelay(tt.attempt); got != tt.want {
+				t.Errorf("webhookDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
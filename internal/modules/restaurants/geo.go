@@ -0,0 +1,147 @@
+package restaurants
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	earthRadiusM = 6371008.8
+
+	// Limits for delivery areas and "near me" searches
+	maxDeliveryRadiusM = 100_000
+	maxSearchRadiusM   = 100_000
+	maxPolygonVertices = 500
+)
+
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+func (p GeoPoint) valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// distanceMeters is the haversine distance between two points. The
+// geo_distance_m SQL function from migration 016 computes the same.
+func distanceMeters(a, b GeoPoint) float64 {
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(a.Lat*math.Pi/180)*math.Cos(b.Lat*math.Pi/180)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadiusM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// DistanceTo returns how far point is from the restaurant, for delivery
+// fees.
+func (l *Location) DistanceTo(point GeoPoint) (float64, error) {
+	if !point.valid() {
+		return 0, errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	if l.Latitude == nil || l.Longitude == nil {
+		return 0, errors.New("the restaurant has no location to deliver from")
+	}
+	return distanceMeters(GeoPoint{Lat: *l.Latitude, Lng: *l.Longitude}, point), nil
+}
+
+// boundingBox returns the south-west and north-east corners of a box that
+// contains every point within radius of center. Near the poles or the
+// antimeridian it widens to the full longitude range instead of wrapping.
+func boundingBox(center GeoPoint, radiusM float64) (GeoPoint, GeoPoint) {
+	angle := radiusM / earthRadiusM
+	dLat := angle * 180 / math.Pi
+	sw := GeoPoint{Lat: math.Max(center.Lat-dLat, -90), Lng: -180}
+	ne := GeoPoint{Lat: math.Min(center.Lat+dLat, 90), Lng: 180}
+
+	if ratio := math.Sin(angle) / math.Cos(center.Lat*math.Pi/180); sw.Lat > -90 && ne.Lat < 90 && ratio < 1 {
+		dLng := math.Asin(ratio) * 180 / math.Pi
+		if center.Lng-dLng >= -180 && center.Lng+dLng <= 180 {
+			sw.Lng = center.Lng - dLng
+			ne.Lng = center.Lng + dLng
+		}
+	}
+
+	return sw, ne
+}
+
+// updateLocation applies the fields set in req. A delivery_radius_m of 0 or an
+// empty delivery_polygon removes them.
+func updateLocation(l, req *Location) {
+	if req.Street != nil {
+		l.Street = req.Street
+	}
+	if req.City != nil {
+		l.City = req.City
+	}
+	if req.Postcode != nil {
+		l.Postcode = req.Postcode
+	}
+	if req.Country != nil {
+		l.Country = req.Country
+	}
+	if req.Latitude != nil {
+		l.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		l.Longitude = req.Longitude
+	}
+	if req.DeliveryRadiusM != nil {
+		l.DeliveryRadiusM = req.DeliveryRadiusM
+		if *req.DeliveryRadiusM == 0 {
+			l.DeliveryRadiusM = nil
+		}
+	}
+	if req.DeliveryPolygon != nil {
+		l.DeliveryPolygon = req.DeliveryPolygon
+	}
+}
+
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// validateLocation checks the coordinates and delivery area of a restaurant
+// and upper-cases the country code.
+func validateLocation(r *Location) error {
+	if r.Country != nil {
+		country := strings.ToUpper(*r.Country)
+		if !countryPattern.MatchString(country) {
+			return errors.New("country must be an ISO 3166-1 alpha-2 code")
+		}
+		r.Country = &country
+	}
+
+	if (r.Latitude == nil) != (r.Longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	hasLocation := r.Latitude != nil
+	if hasLocation && !(GeoPoint{Lat: *r.Latitude, Lng: *r.Longitude}).valid() {
+		return errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+
+	if r.DeliveryRadiusM != nil {
+		if *r.DeliveryRadiusM < 1 || *r.DeliveryRadiusM > maxDeliveryRadiusM {
+			return fmt.Errorf("delivery_radius_m must be between 1 and %d", maxDeliveryRadiusM)
+		}
+	}
+
+	if len(r.DeliveryPolygon) > 0 {
+		if len(r.DeliveryPolygon) < 3 || len(r.DeliveryPolygon) > maxPolygonVertices {
+			return fmt.Errorf("delivery_polygon must have between 3 and %d vertices", maxPolygonVertices)
+		}
+		for _, vertex := range r.DeliveryPolygon {
+			if !vertex.valid() {
+				return errors.New("delivery_polygon has a vertex out of range")
+			}
+		}
+	}
+
+	// Distances to customers are measured from the restaurant
+	if !hasLocation && (r.DeliveryRadiusM != nil || len(r.DeliveryPolygon) > 0) {
+		return errors.New("a delivery area requires latitude and longitude")
+	}
+
+	return nil
+}
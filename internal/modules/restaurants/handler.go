@@ -1,10 +1,19 @@
 package restaurants
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/yourcompany/saas-platform/internal/httpcache"
+	"github.com/yourcompany/saas-platform/internal/modules/auth"
+	"github.com/yourcompany/saas-platform/internal/pagination"
+	"github.com/yourcompany/saas-platform/internal/pricing"
 )
 
 type Handler struct {
@@ -38,9 +47,9 @@ func (h *Handler) GetByID(c *gin.Context) {
 		return
 	}
 
-	restaurant, err := h.service.GetByID(id)
+	restaurant, err := h.service.GetByID(ActorFromContext(c), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -48,21 +57,19 @@ func (h *Handler) GetByID(c *gin.Context) {
 }
 
 func (h *Handler) GetAll(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	query, err := ParseListQuery(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	restaurants, total, err := h.service.GetAll(page, pageSize)
+	page, err := h.service.GetAll(ActorFromContext(c), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":       restaurants,
-		"total":      total,
-		"page":       page,
-		"page_size":  pageSize,
-	})
+	respondList(c, query, page)
 }
 
 func (h *Handler) Update(c *gin.Context) {
@@ -78,9 +85,9 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
-	restaurant, err := h.service.Update(id, &req)
+	restaurant, err := h.service.Update(ActorFromContext(c), id, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -95,9 +102,363 @@ func (h *Handler) Delete(c *gin.Context) {
 	}
 
 	if err := h.service.Delete(id); err != nil {
+		if errors.Is(err, ErrHasOrders) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "restaurant deleted successfully"})
 }
+
+// PublicGetAll lists active restaurants for the customer-facing app. No
+// authentication is required.
+func (h *Handler) PublicGetAll(c *gin.Context) {
+	query, err := ParseListQuery(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.GetPublic(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get restaurants"})
+		return
+	}
+
+	// The ETag covers every restaurant on the page, the next cursor and the
+	// total, so edits, additions and removals all change it. There is no
+	// Last-Modified: a removal would not move it forward.
+	hash := sha256.New()
+	fmt.Fprint(hash, c.Request.URL.RawQuery)
+	if page.NextCursor != nil {
+		fmt.Fprintf(hash, "|next:%s", *page.NextCursor)
+	}
+	if page.Total != nil {
+		fmt.Fprintf(hash, "|total:%d", *page.Total)
+	}
+	for _, restaurant := range page.Data {
+		fmt.Fprintf(hash, "|%s:%d:%d", restaurant.Slug, restaurant.UpdatedAt.UnixNano(), restaurant.changedAt.UnixNano())
+	}
+	if httpcache.NotModified(c, fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16]), time.Time{}) {
+		return
+	}
+
+	respondList(c, query, page)
+}
+
+// respondList writes a cursor page as is and keeps the page and page_size
+// fields for offset pages.
+func respondList[T any](c *gin.Context, query *ListQuery, page *pagination.Page[T]) {
+	if query.Keyset() {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	response := gin.H{
+		"data":      page.Data,
+		"page":      query.Page,
+		"page_size": query.PageSize,
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+		if page.TotalEstimated {
+			response["total_estimated"] = true
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) PublicGetBySlug(c *gin.Context) {
+	restaurant, err := h.service.GetPublicBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Opening and closing change is_open_now without an edit, so they count
+	// as modifications too
+	lastModified := restaurant.UpdatedAt
+	if restaurant.changedAt.After(lastModified) {
+		lastModified = restaurant.changedAt
+	}
+	etag := fmt.Sprintf(`"%x"`, lastModified.UnixNano())
+	if httpcache.NotModified(c, etag, lastModified) {
+		return
+	}
+
+	c.JSON(http.StatusOK, restaurant)
+}
+
+func (h *Handler) PublicGetOpeningHours(c *gin.Context) {
+	hours, err := h.service.GetPublicOpeningHours(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	httpcache.SetPublic(c)
+	c.JSON(http.StatusOK, hours)
+}
+
+func (h *Handler) GetOpeningHours(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	hours, err := h.service.GetOpeningHours(ActorFromContext(c), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hours)
+}
+
+func (h *Handler) SetOpeningHours(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req OpeningHours
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hours, err := h.service.SetOpeningHours(ActorFromContext(c), id, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hours)
+}
+
+func (h *Handler) GetPricing(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rules, err := h.service.GetPricing(ActorFromContext(c), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *Handler) SetPricing(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req pricing.Rules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rules, err := h.service.SetPricing(ActorFromContext(c), id, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *Handler) GetMembers(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	members, err := h.service.GetMembers(ActorFromContext(c), id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (h *Handler) UpdateMember(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.UpdateMember(ActorFromContext(c), id, userID, req.Role); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member updated successfully"})
+}
+
+func (h *Handler) RemoveMember(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.service.RemoveMember(ActorFromContext(c), id, userID); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed successfully"})
+}
+
+func (h *Handler) Invite(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.service.Invite(ActorFromContext(c), id, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *Handler) GetInvitations(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	invitations, err := h.service.GetInvitations(ActorFromContext(c), id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (h *Handler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	invitationID, err := strconv.ParseInt(c.Param("invitationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation id"})
+		return
+	}
+
+	if err := h.service.RevokeInvitation(ActorFromContext(c), id, invitationID); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation revoked successfully"})
+}
+
+func (h *Handler) GetMyInvitations(c *gin.Context) {
+	invitations, err := h.service.GetMyInvitations(ActorFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.service.AcceptInvitation(ActorFromContext(c), id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation accepted"})
+}
+
+func (h *Handler) DeclineInvitation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.service.DeclineInvitation(ActorFromContext(c), id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation declined"})
+}
+
+// ActorFromContext builds the Actor from the values set by AuthMiddleware.
+func ActorFromContext(c *gin.Context) *Actor {
+	permissions, _ := c.Get("user_permissions")
+	granted, _ := permissions.(auth.Permissions)
+
+	return &Actor{
+		UserID:        c.GetInt64("user_id"),
+		Email:         c.GetString("user_email"),
+		EmailVerified: c.GetBool("email_verified"),
+		Permissions:   granted,
+	}
+}
+
+// respondError answers ErrForbidden with 403 and anything else with status.
+func respondError(c *gin.Context, status int, err error) {
+	if errors.Is(err, ErrForbidden) {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
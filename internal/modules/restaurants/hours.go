@@ -0,0 +1,197 @@
+package restaurants
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	// Restaurant time zones must resolve on hosts without zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	// How far ahead next_opening_at is looked for
+	nextOpeningHorizonDays = 31
+
+	maxWeeklyIntervals  = 50
+	maxHourExceptions   = 400
+	exceptionDateLayout = "2006-01-02"
+)
+
+// clockRange is an interval in minutes since local midnight. A range whose
+// closes is not after opens ends on the next day.
+type clockRange struct {
+	opens, closes int
+}
+
+// schedule is the parsed form of OpeningHours. The restaurant_open_at SQL
+// function from migration 017 follows the same rules.
+type schedule struct {
+	location   *time.Location
+	weekly     [7][]clockRange
+	exceptions map[string][]clockRange // An empty list closes the day
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// newSchedule validates hours and prepares them for computing.
+func newSchedule(hours *OpeningHours) (*schedule, error) {
+	location, err := time.LoadLocation(hours.TimeZone)
+	if err != nil || hours.TimeZone == "" || hours.TimeZone == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", hours.TimeZone)
+	}
+	if len(hours.Weekly) > maxWeeklyIntervals {
+		return nil, fmt.Errorf("at most %d weekly intervals are allowed", maxWeeklyIntervals)
+	}
+	if len(hours.Exceptions) > maxHourExceptions {
+		return nil, fmt.Errorf("at most %d exceptions are allowed", maxHourExceptions)
+	}
+
+	s := &schedule{location: location, exceptions: make(map[string][]clockRange)}
+
+	for _, interval := range hours.Weekly {
+		if interval.Weekday < 0 || interval.Weekday > 6 {
+			return nil, errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		r, err := parseRange(interval.Opens, interval.Closes)
+		if err != nil {
+			return nil, err
+		}
+		s.weekly[interval.Weekday] = append(s.weekly[interval.Weekday], r)
+	}
+
+	closed := make(map[string]bool)
+	for _, exception := range hours.Exceptions {
+		if _, err := time.Parse(exceptionDateLayout, exception.Date); err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", exception.Date)
+		}
+		if (exception.Opens == nil) != (exception.Closes == nil) {
+			return nil, errors.New("exception opens and closes must be set together")
+		}
+
+		ranges := s.exceptions[exception.Date]
+		if exception.Opens == nil {
+			if len(ranges) > 0 {
+				return nil, fmt.Errorf("%s is both closed and has hours", exception.Date)
+			}
+			closed[exception.Date] = true
+			s.exceptions[exception.Date] = []clockRange{}
+			continue
+		}
+		if closed[exception.Date] {
+			return nil, fmt.Errorf("%s is both closed and has hours", exception.Date)
+		}
+
+		r, err := parseRange(*exception.Opens, *exception.Closes)
+		if err != nil {
+			return nil, err
+		}
+		s.exceptions[exception.Date] = append(ranges, r)
+	}
+
+	for i := range s.weekly {
+		sortRanges(s.weekly[i])
+	}
+	for _, ranges := range s.exceptions {
+		sortRanges(ranges)
+	}
+
+	return s, nil
+}
+
+func parseRange(opens, closes string) (clockRange, error) {
+	var r clockRange
+	var err error
+	if r.opens, err = parseClock(opens); err != nil {
+		return r, err
+	}
+	if r.closes, err = parseClock(closes); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+func sortRanges(ranges []clockRange) {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].opens < ranges[j].opens })
+}
+
+// rangesOn returns the ranges starting on the local day of midnight.
+func (s *schedule) rangesOn(midnight time.Time) []clockRange {
+	if ranges, ok := s.exceptions[midnight.Format(exceptionDateLayout)]; ok {
+		return ranges
+	}
+	return s.weekly[midnight.Weekday()]
+}
+
+// intervals calls fn with the start and end of every interval starting on
+// the local days from..to relative to the day of t, in order, until fn
+// returns false.
+func (s *schedule) intervals(t time.Time, from, to int, fn func(start, end time.Time) bool) {
+	local := t.In(s.location)
+	for i := from; i <= to; i++ {
+		midnight := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, s.location)
+		for _, r := range s.rangesOn(midnight) {
+			// Wall clock times, so a range keeps its hours across DST changes
+			endDay := midnight.Day()
+			if r.closes <= r.opens {
+				endDay++
+			}
+			start := time.Date(midnight.Year(), midnight.Month(), midnight.Day(), r.opens/60, r.opens%60, 0, 0, s.location)
+			end := time.Date(midnight.Year(), midnight.Month(), endDay, r.closes/60, r.closes%60, 0, 0, s.location)
+			if !fn(start, end) {
+				return
+			}
+		}
+	}
+}
+
+func (s *schedule) isOpen(t time.Time) bool {
+	open := false
+	s.intervals(t, -1, 0, func(start, end time.Time) bool {
+		open = !t.Before(start) && t.Before(end)
+		return !open
+	})
+	return open
+}
+
+// nextOpening returns when a closed restaurant opens next, or nil if it is
+// open at t or stays closed for the next nextOpeningHorizonDays.
+func (s *schedule) nextOpening(t time.Time) *time.Time {
+	if s.isOpen(t) {
+		return nil
+	}
+
+	var next *time.Time
+	s.intervals(t, 0, nextOpeningHorizonDays, func(start, end time.Time) bool {
+		if start.After(t) {
+			start = start.UTC()
+			next = &start
+			return false
+		}
+		return true
+	})
+	return next
+}
+
+// lastChange returns the latest opening or closing at or before t within the
+// past week, or the zero time. It bounds Last-Modified of responses that
+// carry is_open_now.
+func (s *schedule) lastChange(t time.Time) time.Time {
+	var last time.Time
+	s.intervals(t, -7, 0, func(start, end time.Time) bool {
+		for _, change := range []time.Time{start, end} {
+			if !change.After(t) && change.After(last) {
+				last = change
+			}
+		}
+		return true
+	})
+	return last.UTC()
+}
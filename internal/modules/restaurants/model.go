@@ -5,32 +5,185 @@ import "time"
 type Restaurant struct {
 	ID          int64     `json:"id"`
 	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
 	Description *string   `json:"description,omitempty"`
 	Address     *string   `json:"address,omitempty"`
 	Phone       *string   `json:"phone,omitempty"`
 	Email       *string   `json:"email,omitempty"`
 	ImageURL    *string   `json:"image_url,omitempty"`
+	Cuisines    []string  `json:"cuisines"`
+	Currency    string    `json:"currency"` // ISO 4217; every price of the restaurant is in it
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DistanceM   *float64  `json:"distance_m,omitempty"` // Set on "near me" lists
+
+	Location
+	OpenStatus
+}
+
+// OpenStatus is computed from the opening hours when a restaurant is read.
+// NextOpeningAt is set only while the restaurant is closed.
+type OpenStatus struct {
+	TimeZone      string     `json:"time_zone"`
+	IsOpenNow     bool       `json:"is_open_now"`
+	NextOpeningAt *time.Time `json:"next_opening_at"`
+
+	changedAt time.Time // Last opening or closing, for Last-Modified
+}
+
+// OpeningInterval is a weekly interval; Weekday 0 is Sunday. Times are local
+// HH:MM, and a Closes not after Opens ends on the next day.
+type OpeningInterval struct {
+	Weekday int    `json:"weekday"`
+	Opens   string `json:"opens" binding:"required"`
+	Closes  string `json:"closes" binding:"required"`
+}
+
+// HourException replaces the weekly hours on Date (YYYY-MM-DD). Without
+// Opens and Closes the restaurant is closed that day; several exceptions on
+// one date give several intervals.
+type HourException struct {
+	Date   string  `json:"date" binding:"required"`
+	Opens  *string `json:"opens,omitempty"`
+	Closes *string `json:"closes,omitempty"`
+	Note   *string `json:"note,omitempty"`
+}
+
+// OpeningHours is the whole schedule of a restaurant. Setting it replaces
+// the previous one.
+type OpeningHours struct {
+	TimeZone   string            `json:"time_zone" binding:"required"`
+	Weekly     []OpeningInterval `json:"weekly" binding:"dive"`
+	Exceptions []HourException   `json:"exceptions" binding:"dive"`
+}
+
+// Location is the structured address, coordinates and delivery area of a
+// restaurant. Address stays as the free-text line shown to customers.
+type Location struct {
+	Street    *string  `json:"street,omitempty"`
+	City      *string  `json:"city,omitempty"`
+	Postcode  *string  `json:"postcode,omitempty"`
+	Country   *string  `json:"country,omitempty"` // ISO 3166-1 alpha-2
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// Customers are served within DeliveryPolygon if set, else within
+	// DeliveryRadiusM of the coordinates
+	DeliveryRadiusM *int       `json:"delivery_radius_m,omitempty"`
+	DeliveryPolygon []GeoPoint `json:"delivery_polygon,omitempty"`
 }
 
 type CreateRestaurantRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description *string `json:"description"`
-	Address     *string `json:"address"`
-	Phone       *string `json:"phone"`
-	Email       *string `json:"email" binding:"omitempty,email"`
-	ImageURL    *string `json:"image_url"`
-	IsActive    *bool   `json:"is_active"`
+	Name        string   `json:"name" binding:"required"`
+	Description *string  `json:"description"`
+	Address     *string  `json:"address"`
+	Phone       *string  `json:"phone"`
+	Email       *string  `json:"email" binding:"omitempty,email"`
+	ImageURL    *string  `json:"image_url"`
+	Cuisines    []string `json:"cuisines"`
+	Currency    *string  `json:"currency"`
+	IsActive    *bool    `json:"is_active"`
+
+	Location
 }
 
+// UpdateRestaurantRequest does not touch the slug on rename, so public URLs
+// stay stable; it changes only when set explicitly. Location fields are
+// replaced only when present; an empty delivery_polygon removes it. The
+// currency can change only while no dish is priced in the old one.
 type UpdateRestaurantRequest struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	Address     *string `json:"address"`
-	Phone       *string `json:"phone"`
-	Email       *string `json:"email" binding:"omitempty,email"`
-	ImageURL    *string `json:"image_url"`
-	IsActive    *bool   `json:"is_active"`
+	Name        *string  `json:"name"`
+	Slug        *string  `json:"slug"`
+	Description *string  `json:"description"`
+	Address     *string  `json:"address"`
+	Phone       *string  `json:"phone"`
+	Email       *string  `json:"email" binding:"omitempty,email"`
+	ImageURL    *string  `json:"image_url"`
+	Cuisines    []string `json:"cuisines"`
+	Currency    *string  `json:"currency"`
+	IsActive    *bool    `json:"is_active"`
+
+	Location
+}
+
+// PublicRestaurant is what the customer-facing API exposes about a restaurant.
+type PublicRestaurant struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	Address     *string   `json:"address,omitempty"`
+	Phone       *string   `json:"phone,omitempty"`
+	ImageURL    *string   `json:"image_url,omitempty"`
+	Cuisines    []string  `json:"cuisines"`
+	Currency    string    `json:"currency"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	DistanceM   *float64  `json:"distance_m,omitempty"`
+
+	Location
+	OpenStatus
+}
+
+func (r *Restaurant) Public() *PublicRestaurant {
+	return &PublicRestaurant{
+		Slug:        r.Slug,
+		Name:        r.Name,
+		Description: r.Description,
+		Address:     r.Address,
+		Phone:       r.Phone,
+		ImageURL:    r.ImageURL,
+		Cuisines:    r.Cuisines,
+		Currency:    r.Currency,
+		Location:    r.Location,
+		OpenStatus:  r.OpenStatus,
+		UpdatedAt:   r.UpdatedAt,
+		DistanceM:   r.DistanceM,
+	}
+}
+
+// Roles of a user within one restaurant. Owners manage everything including
+// members, managers edit the restaurant and manage staff, staff have read
+// access.
+const (
+	MemberRoleOwner   = "owner"
+	MemberRoleManager = "manager"
+	MemberRoleStaff   = "staff"
+)
+
+type Member struct {
+	RestaurantID int64     `json:"restaurant_id"`
+	UserID       int64     `json:"user_id"`
+	Email        string    `json:"email"`
+	Name         *string   `json:"name,omitempty"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+type Invitation struct {
+	ID             int64      `json:"id"`
+	RestaurantID   int64      `json:"restaurant_id"`
+	RestaurantName string     `json:"restaurant_name,omitempty"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	Status         string     `json:"status"`
+	InvitedBy      *int64     `json:"invited_by,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	RespondedAt    *time.Time `json:"responded_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner manager staff"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner manager staff"`
 }
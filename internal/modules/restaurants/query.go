@@ -0,0 +1,201 @@
+package restaurants
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/pagination"
+)
+
+// Sorts computed per request rather than listed in listSorts
+const (
+	SortRelevance = "relevance"
+	SortDistance  = "distance"
+)
+
+// listSorts maps the accepted "sort" values to ORDER BY clauses. A leading
+// "-" sorts descending; id breaks ties so that pages are stable.
+var listSorts = map[string]string{
+	"name":        "name ASC, id ASC",
+	"-name":       "name DESC, id DESC",
+	"created_at":  "created_at ASC, id ASC",
+	"-created_at": "created_at DESC, id DESC",
+	"updated_at":  "updated_at ASC, id ASC",
+	"-updated_at": "updated_at DESC, id DESC",
+}
+
+// ListQuery describes a restaurant list request. Handlers build it with
+// ParseListQuery and the repository turns it into SQL. Lists page by cursor
+// when Params.Keyset reports so, and by Page and PageSize otherwise.
+type ListQuery struct {
+	pagination.Params
+
+	Search      string // Full-text query in web search syntax ("quoted phrase", -word, or)
+	IsActive    *bool
+	OpenNow     *bool    // Compared with the opening hours at the time of the query
+	Cuisines    []string // Matches restaurants with any of them
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time // Exclusive
+	Sort        string     // A key of listSorts or SortRelevance; "" for the default
+	MemberID    int64      // Only restaurants this user is a member of, if set
+	Near        *GeoPoint  // Only restaurants delivering to this point
+	RadiusM     int        // With Near, only restaurants this close to it; 0 for any
+	Page        int
+	PageSize    int // At most pagination.MaxLimit
+}
+
+// ParseListQuery reads the list parameters q, is_active, open_now, cuisine
+// (comma separated), created_from, created_to (RFC 3339 or YYYY-MM-DD, the
+// latter covering the whole day), lat, lng and radius (meters), sort, the
+// cursor parameters (cursor, limit, total) and the older page and page_size.
+func ParseListQuery(values url.Values) (*ListQuery, error) {
+	params, err := pagination.ParseParams(values)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &ListQuery{
+		Params: *params,
+		Search: strings.TrimSpace(values.Get("q")),
+		Sort:   values.Get("sort"),
+	}
+
+	if value := values.Get("is_active"); value != "" {
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid is_active %q", value)
+		}
+		query.IsActive = &isActive
+	}
+
+	if value := values.Get("open_now"); value != "" {
+		openNow, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid open_now %q", value)
+		}
+		query.OpenNow = &openNow
+	}
+
+	if value := values.Get("cuisine"); value != "" {
+		cuisines, err := normalizeCuisines(strings.Split(value, ","))
+		if err != nil {
+			return nil, err
+		}
+		query.Cuisines = cuisines
+	}
+
+	if query.CreatedFrom, err = parseTimeParam(values.Get("created_from"), false); err != nil {
+		return nil, fmt.Errorf("invalid created_from: %w", err)
+	}
+	if query.CreatedTo, err = parseTimeParam(values.Get("created_to"), true); err != nil {
+		return nil, fmt.Errorf("invalid created_to: %w", err)
+	}
+
+	if err := parseNear(values, query); err != nil {
+		return nil, err
+	}
+
+	if _, ok := listSorts[query.Sort]; !ok && query.Sort != "" && query.Sort != SortRelevance && query.Sort != SortDistance {
+		return nil, fmt.Errorf("invalid sort %q", query.Sort)
+	}
+	if query.Sort == SortRelevance && query.Search == "" {
+		return nil, fmt.Errorf("sort=relevance requires q")
+	}
+	if query.Sort == SortDistance && query.Near == nil {
+		return nil, fmt.Errorf("sort=distance requires lat and lng")
+	}
+
+	query.Page, _ = strconv.Atoi(values.Get("page"))
+	query.PageSize, _ = strconv.Atoi(values.Get("page_size"))
+	query.PageSize = min(query.PageSize, pagination.MaxLimit)
+
+	// Cursors are keyed on (created_at, id), so other orders page by offset
+	if query.Keyset() {
+		if values.Has("page") || values.Has("page_size") {
+			return nil, fmt.Errorf("cursor and limit cannot be combined with page and page_size")
+		}
+		switch query.Sort {
+		case "":
+			query.Sort = "-created_at"
+		case "created_at", "-created_at":
+		default:
+			return nil, fmt.Errorf("cursor pagination supports only sort=created_at or sort=-created_at")
+		}
+	}
+
+	return query, nil
+}
+
+func parseNear(values url.Values, query *ListQuery) error {
+	lat, lng, radius := values.Get("lat"), values.Get("lng"), values.Get("radius")
+	if lat == "" && lng == "" {
+		if radius != "" {
+			return fmt.Errorf("radius requires lat and lng")
+		}
+		return nil
+	}
+
+	point := GeoPoint{}
+	var errLat, errLng error
+	point.Lat, errLat = strconv.ParseFloat(lat, 64)
+	point.Lng, errLng = strconv.ParseFloat(lng, 64)
+	if errLat != nil || errLng != nil || !point.valid() {
+		return fmt.Errorf("lat and lng must be valid coordinates")
+	}
+	query.Near = &point
+
+	if radius != "" {
+		radiusM, err := strconv.Atoi(radius)
+		if err != nil || radiusM < 1 || radiusM > maxSearchRadiusM {
+			return fmt.Errorf("radius must be between 1 and %d meters", maxSearchRadiusM)
+		}
+		query.RadiusM = radiusM
+	}
+
+	return nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates. A date used as
+// an upper bound moves to the start of the next day.
+func parseTimeParam(value string, upperBound bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD, got %q", value)
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// normalizeCuisines lowercases and deduplicates cuisine names.
+func normalizeCuisines(values []string) ([]string, error) {
+	cuisines := []string{}
+	seen := make(map[string]bool)
+
+	for _, value := range values {
+		cuisine := strings.ToLower(strings.TrimSpace(value))
+		if cuisine == "" || seen[cuisine] {
+			continue
+		}
+		if len(cuisine) > 50 {
+			return nil, fmt.Errorf("cuisine %q is too long", value)
+		}
+		seen[cuisine] = true
+		cuisines = append(cuisines, cuisine)
+	}
+
+	return cuisines, nil
+}
@@ -2,7 +2,16 @@ package restaurants
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/yourcompany/saas-platform/internal/pagination"
+	"github.com/yourcompany/saas-platform/internal/pricing"
 )
 
 type Repository struct {
@@ -15,25 +24,42 @@ func NewRepository(db *sql.DB) *Repository {
 
 func (r *Repository) Create(restaurant *Restaurant) error {
 	query := `
-		INSERT INTO restaurants (name, description, address, phone, email, image_url, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO restaurants (name, slug, description, address, phone, email, image_url, cuisines, is_active,
+			street, city, postcode, country, latitude, longitude, delivery_radius_m, delivery_polygon, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at, updated_at
 	`
 
+	polygon, err := polygonValue(restaurant.DeliveryPolygon)
+	if err != nil {
+		return err
+	}
+
 	var isActive bool = true
 	if restaurant.IsActive == false {
 		isActive = false
 	}
 
-	err := r.db.QueryRow(
+	err = r.db.QueryRow(
 		query,
 		restaurant.Name,
+		restaurant.Slug,
 		restaurant.Description,
 		restaurant.Address,
 		restaurant.Phone,
 		restaurant.Email,
 		restaurant.ImageURL,
+		pq.Array(restaurant.Cuisines),
 		isActive,
+		restaurant.Street,
+		restaurant.City,
+		restaurant.Postcode,
+		restaurant.Country,
+		restaurant.Latitude,
+		restaurant.Longitude,
+		restaurant.DeliveryRadiusM,
+		polygon,
+		restaurant.Currency,
 	).Scan(&restaurant.ID, &restaurant.CreatedAt, &restaurant.UpdatedAt)
 
 	if err != nil {
@@ -44,34 +70,47 @@ func (r *Repository) Create(restaurant *Restaurant) error {
 	return nil
 }
 
-func (r *Repository) GetByID(id int64) (*Restaurant, error) {
-	query := `
-		SELECT id, name, description, address, phone, email, image_url, is_active, created_at, updated_at
-		FROM restaurants
-		WHERE id = $1
-	`
+const restaurantColumns = `id, name, slug, description, address, phone, email, image_url, cuisines, is_active, created_at, updated_at,
+	street, city, postcode, country, latitude, longitude, delivery_radius_m, delivery_polygon, time_zone, currency`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func scanRestaurant(row rowScanner) (*Restaurant, error) {
 	restaurant := &Restaurant{}
 	var description, address, phone, email, imageURL sql.NullString
+	var street, city, postcode, country sql.NullString
+	var latitude, longitude sql.NullFloat64
+	var deliveryRadius sql.NullInt64
+	var polygon []byte
 
-	err := r.db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&restaurant.ID,
 		&restaurant.Name,
+		&restaurant.Slug,
 		&description,
 		&address,
 		&phone,
 		&email,
 		&imageURL,
+		pq.Array(&restaurant.Cuisines),
 		&restaurant.IsActive,
 		&restaurant.CreatedAt,
 		&restaurant.UpdatedAt,
+		&street,
+		&city,
+		&postcode,
+		&country,
+		&latitude,
+		&longitude,
+		&deliveryRadius,
+		&polygon,
+		&restaurant.TimeZone,
+		&restaurant.Currency,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("restaurant not found")
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get restaurant: %w", err)
+		return nil, err
 	}
 
 	if description.Valid {
@@ -89,99 +128,280 @@ func (r *Repository) GetByID(id int64) (*Restaurant, error) {
 	if imageURL.Valid {
 		restaurant.ImageURL = &imageURL.String
 	}
+	if street.Valid {
+		restaurant.Street = &street.String
+	}
+	if city.Valid {
+		restaurant.City = &city.String
+	}
+	if postcode.Valid {
+		restaurant.Postcode = &postcode.String
+	}
+	if country.Valid {
+		restaurant.Country = &country.String
+	}
+	if latitude.Valid && longitude.Valid {
+		restaurant.Latitude = &latitude.Float64
+		restaurant.Longitude = &longitude.Float64
+	}
+	if deliveryRadius.Valid {
+		radius := int(deliveryRadius.Int64)
+		restaurant.DeliveryRadiusM = &radius
+	}
+	if polygon != nil {
+		if err := json.Unmarshal(polygon, &restaurant.DeliveryPolygon); err != nil {
+			return nil, fmt.Errorf("invalid delivery polygon: %w", err)
+		}
+	}
+
+	return restaurant, nil
+}
+
+// polygonValue stores an empty polygon as NULL.
+func polygonValue(polygon []GeoPoint) (interface{}, error) {
+	if len(polygon) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(polygon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode delivery polygon: %w", err)
+	}
+	return string(data), nil
+}
+
+func (r *Repository) GetByID(id int64) (*Restaurant, error) {
+	query := `SELECT ` + restaurantColumns + ` FROM restaurants WHERE id = $1`
+
+	restaurant, err := scanRestaurant(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("restaurant not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restaurant: %w", err)
+	}
+
+	return restaurant, nil
+}
+
+func (r *Repository) GetActiveBySlug(slug string) (*Restaurant, error) {
+	query := `SELECT ` + restaurantColumns + ` FROM restaurants WHERE slug = $1 AND is_active = true`
+
+	restaurant, err := scanRestaurant(r.db.QueryRow(query, slug))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("restaurant not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restaurant: %w", err)
+	}
 
 	return restaurant, nil
 }
 
-func (r *Repository) GetAll(limit, offset int) ([]*Restaurant, int, error) {
-	// Get total count
+// listFilter holds the WHERE clause of a list query and its arguments.
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+	tsquery    string
+	distance   string
+}
+
+func newListFilter(query *ListQuery) *listFilter {
+	f := &listFilter{}
+
+	// Parsed with both configurations the search vector is built with
+	if query.Search != "" {
+		search := f.arg(query.Search)
+		f.tsquery = fmt.Sprintf("(websearch_to_tsquery('russian', %s) || websearch_to_tsquery('english', %s))", search, search)
+		f.conditions = append(f.conditions, "search_vector @@ "+f.tsquery)
+	}
+	if query.IsActive != nil {
+		f.conditions = append(f.conditions, "is_active = "+f.arg(*query.IsActive))
+	}
+	if len(query.Cuisines) > 0 {
+		f.conditions = append(f.conditions, "cuisines && "+f.arg(pq.Array(query.Cuisines)))
+	}
+	if query.CreatedFrom != nil {
+		f.conditions = append(f.conditions, "created_at >= "+f.arg(*query.CreatedFrom))
+	}
+	if query.CreatedTo != nil {
+		f.conditions = append(f.conditions, "created_at < "+f.arg(*query.CreatedTo))
+	}
+	if query.MemberID != 0 {
+		f.conditions = append(f.conditions, "id IN (SELECT restaurant_id FROM restaurant_members WHERE user_id = "+f.arg(query.MemberID)+")")
+	}
+	if query.OpenNow != nil {
+		open := "(is_active AND restaurant_open_at(id, time_zone, CURRENT_TIMESTAMP))"
+		if !*query.OpenNow {
+			open = "NOT " + open
+		}
+		f.conditions = append(f.conditions, open)
+	}
+	if query.Near != nil {
+		lat, lng := f.arg(query.Near.Lat), f.arg(query.Near.Lng)
+		f.distance = fmt.Sprintf("geo_distance_m(latitude, longitude, %s, %s)", lat, lng)
+		f.conditions = append(f.conditions, "latitude IS NOT NULL")
+
+		if query.RadiusM > 0 {
+			// The box lets the location index skip far away rows
+			sw, ne := boundingBox(*query.Near, float64(query.RadiusM))
+			f.conditions = append(f.conditions,
+				"latitude BETWEEN "+f.arg(sw.Lat)+" AND "+f.arg(ne.Lat),
+				"longitude BETWEEN "+f.arg(sw.Lng)+" AND "+f.arg(ne.Lng),
+				f.distance+" <= "+f.arg(query.RadiusM),
+			)
+		}
+
+		f.conditions = append(f.conditions, fmt.Sprintf(
+			"CASE WHEN delivery_polygon IS NOT NULL THEN geo_polygon_contains(delivery_polygon, %s, %s) "+
+				"ELSE coalesce(%s <= delivery_radius_m, false) END",
+			lat, lng, f.distance,
+		))
+	}
+
+	return f
+}
+
+func (f *listFilter) arg(value interface{}) string {
+	f.args = append(f.args, value)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+func (f *listFilter) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// GetAll returns one page of restaurants matching query. With a cursor or
+// limit it returns up to Limit+1 rows after the cursor, so the caller can tell
+// whether another page follows; otherwise it pages by offset.
+func (r *Repository) GetAll(query *ListQuery) ([]*Restaurant, error) {
+	f := newListFilter(query)
+
+	orderBy := listSorts[query.Sort]
+	switch query.Sort {
+	case SortRelevance:
+		orderBy = "ts_rank(search_vector, " + f.tsquery + ") DESC, id DESC"
+	case SortDistance:
+		orderBy = f.distance + " ASC, id ASC"
+	}
+
+	var limit string
+	if query.Keyset() {
+		if query.Cursor != nil {
+			f.conditions = append(f.conditions, query.Cursor.After(query.Sort == "-created_at", f.arg))
+		}
+		limit = ` LIMIT ` + f.arg(query.Limit+1)
+	} else {
+		limit = ` LIMIT ` + f.arg(query.PageSize) + ` OFFSET ` + f.arg((query.Page-1)*query.PageSize)
+	}
+
+	return r.queryRestaurants(`SELECT `+restaurantColumns+` FROM restaurants`+f.where()+` ORDER BY `+orderBy+limit, f.args...)
+}
+
+// Count returns the number of restaurants matching query, or the planner's
+// estimate of it.
+func (r *Repository) Count(query *ListQuery, estimate bool) (int, error) {
+	f := newListFilter(query)
+
+	if estimate {
+		return pagination.EstimateCount(r.db, "SELECT id FROM restaurants"+f.where(), f.args...)
+	}
+
 	var total int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM restaurants").Scan(&total)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM restaurants"+f.where(), f.args...).Scan(&total)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count restaurants: %w", err)
+		return 0, fmt.Errorf("failed to count restaurants: %w", err)
 	}
 
-	// Get restaurants
-	query := `
-		SELECT id, name, description, address, phone, email, image_url, is_active, created_at, updated_at
-		FROM restaurants
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+	return total, nil
+}
+
+// SlugExists reports whether another restaurant than excludeID uses slug.
+func (r *Repository) SlugExists(slug string, excludeID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM restaurants WHERE slug = $1 AND id <> $2)",
+		slug, excludeID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check slug: %w", err)
+	}
+
+	return exists, nil
+}
 
-	rows, err := r.db.Query(query, limit, offset)
+func (r *Repository) queryRestaurants(query string, args ...interface{}) ([]*Restaurant, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get restaurants: %w", err)
+		return nil, fmt.Errorf("failed to get restaurants: %w", err)
 	}
 	defer rows.Close()
 
 	var restaurants []*Restaurant
 	for rows.Next() {
-		restaurant := &Restaurant{}
-		var description, address, phone, email, imageURL sql.NullString
-
-		err := rows.Scan(
-			&restaurant.ID,
-			&restaurant.Name,
-			&description,
-			&address,
-			&phone,
-			&email,
-			&imageURL,
-			&restaurant.IsActive,
-			&restaurant.CreatedAt,
-			&restaurant.UpdatedAt,
-		)
+		restaurant, err := scanRestaurant(rows)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan restaurant: %w", err)
-		}
-
-		if description.Valid {
-			restaurant.Description = &description.String
-		}
-		if address.Valid {
-			restaurant.Address = &address.String
-		}
-		if phone.Valid {
-			restaurant.Phone = &phone.String
+			return nil, fmt.Errorf("failed to scan restaurant: %w", err)
 		}
-		if email.Valid {
-			restaurant.Email = &email.String
-		}
-		if imageURL.Valid {
-			restaurant.ImageURL = &imageURL.String
-		}
-
 		restaurants = append(restaurants, restaurant)
 	}
 
-	return restaurants, total, nil
+	return restaurants, rows.Err()
 }
 
 func (r *Repository) Update(id int64, restaurant *Restaurant) error {
 	query := `
 		UPDATE restaurants
 		SET name = $1,
-			description = $2,
-			address = $3,
-			phone = $4,
-			email = $5,
-			image_url = $6,
-			is_active = $7,
+			slug = $2,
+			description = $3,
+			address = $4,
+			phone = $5,
+			email = $6,
+			image_url = $7,
+			cuisines = $8,
+			is_active = $9,
+			street = $10,
+			city = $11,
+			postcode = $12,
+			country = $13,
+			latitude = $14,
+			longitude = $15,
+			delivery_radius_m = $16,
+			delivery_polygon = $17,
+			currency = $18,
 			updated_at = CURRENT_TIMESTAMP
-		WHERE id = $8
+		WHERE id = $19
 		RETURNING updated_at
 	`
 
-	err := r.db.QueryRow(
+	polygon, err := polygonValue(restaurant.DeliveryPolygon)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRow(
 		query,
 		restaurant.Name,
+		restaurant.Slug,
 		restaurant.Description,
 		restaurant.Address,
 		restaurant.Phone,
 		restaurant.Email,
 		restaurant.ImageURL,
+		pq.Array(restaurant.Cuisines),
 		restaurant.IsActive,
+		restaurant.Street,
+		restaurant.City,
+		restaurant.Postcode,
+		restaurant.Country,
+		restaurant.Latitude,
+		restaurant.Longitude,
+		restaurant.DeliveryRadiusM,
+		polygon,
+		restaurant.Currency,
 		id,
 	).Scan(&restaurant.UpdatedAt)
 
@@ -195,9 +415,38 @@ func (r *Repository) Update(id int64, restaurant *Restaurant) error {
 	return nil
 }
 
+// HasPricesInOtherCurrency reports whether any dish of a restaurant is
+// priced in a currency other than currency.
+func (r *Repository) HasPricesInOtherCurrency(id int64, currency string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM menu_items WHERE restaurant_id = $1 AND currency <> $2)`,
+		id, currency,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check menu prices: %w", err)
+	}
+	return exists, nil
+}
+
+// Delete removes a restaurant with everything it owns, except that one with
+// orders cannot be deleted: ErrHasOrders.
 func (r *Repository) Delete(id int64) error {
+	var hasOrders bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM orders WHERE restaurant_id = $1)`, id).Scan(&hasOrders); err != nil {
+		return fmt.Errorf("failed to check orders: %w", err)
+	}
+	if hasOrders {
+		return ErrHasOrders
+	}
+
 	query := `DELETE FROM restaurants WHERE id = $1`
 	result, err := r.db.Exec(query, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		// An order was placed since the check above
+		return ErrHasOrders
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete restaurant: %w", err)
 	}
@@ -213,3 +462,501 @@ func (r *Repository) Delete(id int64) error {
 
 	return nil
 }
+
+// GetMemberRole returns the user's role in the restaurant, or "" if they are
+// not a member.
+func (r *Repository) GetMemberRole(restaurantID, userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRow(
+		"SELECT role FROM restaurant_members WHERE restaurant_id = $1 AND user_id = $2",
+		restaurantID, userID,
+	).Scan(&role)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get member role: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r *Repository) GetMembers(restaurantID int64) ([]*Member, error) {
+	query := `
+		SELECT m.restaurant_id, m.user_id, u.email, u.name, m.role, m.created_at, m.updated_at
+		FROM restaurant_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.restaurant_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := r.db.Query(query, restaurantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		member := &Member{}
+		var name sql.NullString
+
+		if err := rows.Scan(
+			&member.RestaurantID,
+			&member.UserID,
+			&member.Email,
+			&name,
+			&member.Role,
+			&member.CreatedAt,
+			&member.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+
+		if name.Valid {
+			member.Name = &name.String
+		}
+
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// ChangeMember sets the role of a member, or removes them when role is "".
+// The restaurant row is locked so that concurrent changes cannot leave it
+// without an owner.
+func (r *Repository) ChangeMember(restaurantID, userID int64, role string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT id FROM restaurants WHERE id = $1 FOR UPDATE", restaurantID); err != nil {
+		return fmt.Errorf("failed to lock restaurant: %w", err)
+	}
+
+	var current string
+	err = tx.QueryRow(
+		"SELECT role FROM restaurant_members WHERE restaurant_id = $1 AND user_id = $2",
+		restaurantID, userID,
+	).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("member not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get member: %w", err)
+	}
+
+	if current == MemberRoleOwner && role != MemberRoleOwner {
+		var owners int
+		if err := tx.QueryRow(
+			"SELECT COUNT(*) FROM restaurant_members WHERE restaurant_id = $1 AND role = $2",
+			restaurantID, MemberRoleOwner,
+		).Scan(&owners); err != nil {
+			return fmt.Errorf("failed to count owners: %w", err)
+		}
+		if owners <= 1 {
+			return fmt.Errorf("a restaurant must keep at least one owner")
+		}
+	}
+
+	if role == "" {
+		_, err = tx.Exec("DELETE FROM restaurant_members WHERE restaurant_id = $1 AND user_id = $2", restaurantID, userID)
+	} else {
+		_, err = tx.Exec(
+			"UPDATE restaurant_members SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE restaurant_id = $2 AND user_id = $3",
+			role, restaurantID, userID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const invitationColumns = `i.id, i.restaurant_id, r.name, i.email, i.role, i.status, i.invited_by, i.expires_at, i.responded_at, i.created_at`
+
+func scanInvitation(row rowScanner) (*Invitation, error) {
+	invitation := &Invitation{}
+	var invitedBy sql.NullInt64
+	var respondedAt sql.NullTime
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.RestaurantID,
+		&invitation.RestaurantName,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.Status,
+		&invitedBy,
+		&invitation.ExpiresAt,
+		&respondedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitedBy.Valid {
+		invitation.InvitedBy = &invitedBy.Int64
+	}
+	if respondedAt.Valid {
+		invitation.RespondedAt = &respondedAt.Time
+	}
+
+	return invitation, nil
+}
+
+func (r *Repository) queryInvitations(query string, args ...interface{}) ([]*Invitation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// CreateInvitation replaces any pending invitation of the same email to the
+// restaurant.
+func (r *Repository) CreateInvitation(invitation *Invitation) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE restaurant_invitations SET status = $1, responded_at = CURRENT_TIMESTAMP WHERE restaurant_id = $2 AND email = $3 AND status = $4",
+		InvitationRevoked, invitation.RestaurantID, invitation.Email, InvitationPending,
+	); err != nil {
+		return fmt.Errorf("failed to revoke previous invitation: %w", err)
+	}
+
+	query := `
+		INSERT INTO restaurant_invitations (restaurant_id, email, role, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+
+	err = tx.QueryRow(
+		query,
+		invitation.RestaurantID,
+		invitation.Email,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.Status, &invitation.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetInvitation(id int64) (*Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM restaurant_invitations i
+		JOIN restaurants r ON r.id = i.restaurant_id
+		WHERE i.id = $1
+	`
+
+	invitation, err := scanInvitation(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invitation not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+func (r *Repository) GetPendingInvitations(restaurantID int64, now time.Time) ([]*Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM restaurant_invitations i
+		JOIN restaurants r ON r.id = i.restaurant_id
+		WHERE i.restaurant_id = $1 AND i.status = $2 AND i.expires_at > $3
+		ORDER BY i.created_at DESC
+	`
+
+	return r.queryInvitations(query, restaurantID, InvitationPending, now)
+}
+
+func (r *Repository) GetPendingInvitationsByEmail(email string, now time.Time) ([]*Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM restaurant_invitations i
+		JOIN restaurants r ON r.id = i.restaurant_id
+		WHERE i.email = $1 AND i.status = $2 AND i.expires_at > $3
+		ORDER BY i.created_at DESC
+	`
+
+	return r.queryInvitations(query, email, InvitationPending, now)
+}
+
+// RespondToInvitation moves a pending invitation to status. Accepting it also
+// makes the user a member, keeping the higher of an existing and the invited
+// role.
+func (r *Repository) RespondToInvitation(invitation *Invitation, status string, userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE restaurant_invitations SET status = $1, responded_at = CURRENT_TIMESTAMP WHERE id = $2 AND status = $3",
+		status, invitation.ID, InvitationPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("invitation is no longer pending")
+	}
+
+	if status == InvitationAccepted {
+		query := `
+			INSERT INTO restaurant_members (restaurant_id, user_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (restaurant_id, user_id) DO UPDATE
+			SET role = CASE
+					WHEN restaurant_members.role = 'owner' OR EXCLUDED.role = 'owner' THEN 'owner'
+					WHEN restaurant_members.role = 'manager' OR EXCLUDED.role = 'manager' THEN 'manager'
+					ELSE 'staff'
+				END,
+				updated_at = CURRENT_TIMESTAMP
+		`
+		if _, err := tx.Exec(query, invitation.RestaurantID, userID, invitation.Role); err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpeningHours returns the weekly hours and the exceptions dated since
+// onwards (all of them if since is empty) of the given restaurants. The time
+// zone is left for the caller to fill in.
+func (r *Repository) GetOpeningHours(restaurantIDs []int64, since string) (map[int64]*OpeningHours, error) {
+	hours := make(map[int64]*OpeningHours, len(restaurantIDs))
+	for _, id := range restaurantIDs {
+		hours[id] = &OpeningHours{Weekly: []OpeningInterval{}, Exceptions: []HourException{}}
+	}
+	if len(restaurantIDs) == 0 {
+		return hours, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT restaurant_id, weekday, to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+		FROM restaurant_opening_hours
+		WHERE restaurant_id = ANY($1)
+		ORDER BY restaurant_id, weekday, opens_at
+	`, pq.Array(restaurantIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening hours: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var restaurantID int64
+		var interval OpeningInterval
+		if err := rows.Scan(&restaurantID, &interval.Weekday, &interval.Opens, &interval.Closes); err != nil {
+			return nil, fmt.Errorf("failed to scan opening hours: %w", err)
+		}
+		hours[restaurantID].Weekly = append(hours[restaurantID].Weekly, interval)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var sinceDate interface{}
+	if since != "" {
+		sinceDate = since
+	}
+	rows, err = r.db.Query(`
+		SELECT restaurant_id, to_char(date, 'YYYY-MM-DD'), to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI'), note
+		FROM restaurant_hour_exceptions
+		WHERE restaurant_id = ANY($1) AND ($2::date IS NULL OR date >= $2::date)
+		ORDER BY restaurant_id, date, opens_at NULLS FIRST
+	`, pq.Array(restaurantIDs), sinceDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hour exceptions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var restaurantID int64
+		var exception HourException
+		var opens, closes, note sql.NullString
+		if err := rows.Scan(&restaurantID, &exception.Date, &opens, &closes, &note); err != nil {
+			return nil, fmt.Errorf("failed to scan hour exception: %w", err)
+		}
+		if opens.Valid && closes.Valid {
+			exception.Opens = &opens.String
+			exception.Closes = &closes.String
+		}
+		if note.Valid {
+			exception.Note = &note.String
+		}
+		hours[restaurantID].Exceptions = append(hours[restaurantID].Exceptions, exception)
+	}
+
+	return hours, rows.Err()
+}
+
+// SetOpeningHours replaces the time zone, weekly hours and exceptions of a
+// restaurant.
+func (r *Repository) SetOpeningHours(restaurantID int64, hours *OpeningHours) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE restaurants SET time_zone = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		hours.TimeZone, restaurantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update restaurant: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("restaurant not found")
+	}
+
+	if _, err := tx.Exec("DELETE FROM restaurant_opening_hours WHERE restaurant_id = $1", restaurantID); err != nil {
+		return fmt.Errorf("failed to clear opening hours: %w", err)
+	}
+	for _, interval := range hours.Weekly {
+		if _, err := tx.Exec(
+			"INSERT INTO restaurant_opening_hours (restaurant_id, weekday, opens_at, closes_at) VALUES ($1, $2, $3, $4)",
+			restaurantID, interval.Weekday, interval.Opens, interval.Closes,
+		); err != nil {
+			return fmt.Errorf("failed to save opening hours: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM restaurant_hour_exceptions WHERE restaurant_id = $1", restaurantID); err != nil {
+		return fmt.Errorf("failed to clear hour exceptions: %w", err)
+	}
+	for _, exception := range hours.Exceptions {
+		if _, err := tx.Exec(
+			"INSERT INTO restaurant_hour_exceptions (restaurant_id, date, opens_at, closes_at, note) VALUES ($1, $2, $3, $4, $5)",
+			restaurantID, exception.Date, exception.Opens, exception.Closes, exception.Note,
+		); err != nil {
+			return fmt.Errorf("failed to save hour exceptions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetPricing returns the tax and fee settings of a restaurant. Without any
+// saved, prices include tax and nothing is added to them.
+func (r *Repository) GetPricing(restaurantID int64) (*pricing.Rules, error) {
+	rules := &pricing.Rules{PricesIncludeTax: true, DeliveryTiers: []pricing.DeliveryTier{}}
+	var serviceFeeMax sql.NullInt64
+	var tiers []byte
+
+	err := r.db.QueryRow(`
+		SELECT prices_include_tax, tax_rate_bp, service_fee_bp, service_fee_min, service_fee_max, minimum_order, delivery_tiers
+		FROM restaurant_pricing WHERE restaurant_id = $1
+	`, restaurantID).Scan(
+		&rules.PricesIncludeTax,
+		&rules.TaxRateBP,
+		&rules.ServiceFeeBP,
+		&rules.ServiceFeeMin,
+		&serviceFeeMax,
+		&rules.MinimumOrder,
+		&tiers,
+	)
+	if err == sql.ErrNoRows {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing: %w", err)
+	}
+
+	if serviceFeeMax.Valid {
+		rules.ServiceFeeMax = &serviceFeeMax.Int64
+	}
+	if err := json.Unmarshal(tiers, &rules.DeliveryTiers); err != nil {
+		return nil, fmt.Errorf("failed to decode delivery tiers: %w", err)
+	}
+
+	return rules, nil
+}
+
+// SetPricing saves the tax and fee settings of a restaurant.
+func (r *Repository) SetPricing(restaurantID int64, rules *pricing.Rules) error {
+	tiers, err := json.Marshal(rules.DeliveryTiers)
+	if err != nil {
+		return fmt.Errorf("failed to encode delivery tiers: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO restaurant_pricing (restaurant_id, prices_include_tax, tax_rate_bp, service_fee_bp, service_fee_min, service_fee_max, minimum_order, delivery_tiers)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (restaurant_id) DO UPDATE SET
+			prices_include_tax = EXCLUDED.prices_include_tax,
+			tax_rate_bp = EXCLUDED.tax_rate_bp,
+			service_fee_bp = EXCLUDED.service_fee_bp,
+			service_fee_min = EXCLUDED.service_fee_min,
+			service_fee_max = EXCLUDED.service_fee_max,
+			minimum_order = EXCLUDED.minimum_order,
+			delivery_tiers = EXCLUDED.delivery_tiers,
+			updated_at = CURRENT_TIMESTAMP
+	`,
+		restaurantID,
+		rules.PricesIncludeTax,
+		rules.TaxRateBP,
+		rules.ServiceFeeBP,
+		rules.ServiceFeeMin,
+		rules.ServiceFeeMax,
+		rules.MinimumOrder,
+		tiers,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pricing: %w", err)
+	}
+
+	return nil
+}
@@ -1,22 +1,94 @@
 package restaurants
 
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/yourcompany/saas-platform/internal/mailer"
+	"github.com/yourcompany/saas-platform/internal/modules/auth"
+	"github.com/yourcompany/saas-platform/internal/money"
+	"github.com/yourcompany/saas-platform/internal/pagination"
+	"github.com/yourcompany/saas-platform/internal/pricing"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+// New restaurants use the time zone and currency migrations 017 and 025
+// default to
+const (
+	defaultTimeZone = "UTC"
+	defaultCurrency = "EUR"
+)
+
+var ErrForbidden = errors.New("insufficient permissions")
+
+// ErrHasOrders is returned for deleting a restaurant whose orders have to be
+// kept; deactivate it instead.
+var ErrHasOrders = errors.New("restaurant has orders and cannot be deleted, deactivate it instead")
+
+// Actor is the authenticated user a request is made for. Platform permissions
+// apply to every restaurant; otherwise access comes from membership.
+type Actor struct {
+	UserID        int64
+	Email         string
+	EmailVerified bool
+	Permissions   auth.Permissions
+}
+
 type Service struct {
-	repo *Repository
+	repo   *Repository
+	mailer mailer.Mailer
+	appURL string
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, mail mailer.Mailer, appURL string) *Service {
+	return &Service{
+		repo:   repo,
+		mailer: mail,
+		appURL: strings.TrimRight(appURL, "/"),
+	}
 }
 
 func (s *Service) Create(req *CreateRestaurantRequest) (*Restaurant, error) {
+	cuisines, err := normalizeCuisines(req.Cuisines)
+	if err != nil {
+		return nil, err
+	}
+
+	slug, err := s.uniqueSlug(req.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	restaurant := &Restaurant{
 		Name:        req.Name,
+		Slug:        slug,
 		Description: req.Description,
 		Address:     req.Address,
 		Phone:       req.Phone,
 		Email:       req.Email,
 		ImageURL:    req.ImageURL,
+		Cuisines:    cuisines,
 		IsActive:    true,
+		Location:    req.Location,
+		Currency:    defaultCurrency,
+		OpenStatus:  OpenStatus{TimeZone: defaultTimeZone},
+	}
+
+	if err := validateLocation(&restaurant.Location); err != nil {
+		return nil, err
+	}
+
+	if req.Currency != nil {
+		currency, err := money.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		restaurant.Currency = currency
 	}
 
 	if req.IsActive != nil {
@@ -30,23 +102,110 @@ func (s *Service) Create(req *CreateRestaurantRequest) (*Restaurant, error) {
 	return restaurant, nil
 }
 
-func (s *Service) GetByID(id int64) (*Restaurant, error) {
-	return s.repo.GetByID(id)
+func (s *Service) GetByID(actor *Actor, id int64) (*Restaurant, error) {
+	if err := s.Authorize(actor, id, auth.PermissionRestaurantsRead, MemberRoleOwner, MemberRoleManager, MemberRoleStaff); err != nil {
+		return nil, err
+	}
+
+	restaurant, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyOpenStatus([]*Restaurant{restaurant}); err != nil {
+		return nil, err
+	}
+
+	return restaurant, nil
 }
 
-func (s *Service) GetAll(page, pageSize int) ([]*Restaurant, int, error) {
-	if page < 1 {
-		page = 1
+// GetAll lists every restaurant matching query for users with platform read
+// access, and only the restaurants they are a member of for everyone else.
+func (s *Service) GetAll(actor *Actor, query *ListQuery) (*pagination.Page[*Restaurant], error) {
+	if query.Page < 1 {
+		query.Page = 1
 	}
-	if pageSize < 1 {
-		pageSize = 10
+	if query.PageSize < 1 {
+		query.PageSize = 10
 	}
+	if query.Sort == "" {
+		query.Sort = defaultSort(query, "-created_at")
+	}
+
+	query.MemberID = 0
+	if !actor.Permissions.Has(auth.PermissionRestaurantsRead) {
+		query.MemberID = actor.UserID
+	}
+
+	return s.list(query)
+}
 
-	offset := (page - 1) * pageSize
-	return s.repo.GetAll(pageSize, offset)
+// defaultSort orders searches by relevance and "near me" lists by distance.
+func defaultSort(query *ListQuery, fallback string) string {
+	switch {
+	case query.Search != "":
+		return SortRelevance
+	case query.Near != nil:
+		return SortDistance
+	default:
+		return fallback
+	}
 }
 
-func (s *Service) Update(id int64, req *UpdateRestaurantRequest) (*Restaurant, error) {
+// list fetches one page for query. Offset pages come with an exact total
+// unless another is asked for; cursor pages only when asked.
+func (s *Service) list(query *ListQuery) (*pagination.Page[*Restaurant], error) {
+	if query.Keyset() && query.Limit == 0 {
+		query.Limit = pagination.DefaultLimit
+	}
+
+	restaurants, err := s.repo.GetAll(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyOpenStatus(restaurants); err != nil {
+		return nil, err
+	}
+
+	if query.Near != nil {
+		for _, restaurant := range restaurants {
+			distance := math.Round(distanceMeters(*query.Near, GeoPoint{Lat: *restaurant.Latitude, Lng: *restaurant.Longitude}))
+			restaurant.DistanceM = &distance
+		}
+	}
+
+	var page *pagination.Page[*Restaurant]
+	if query.Keyset() {
+		page = pagination.NewPage(restaurants, query.Limit, func(r *Restaurant) pagination.Cursor {
+			return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+		})
+	} else {
+		page = &pagination.Page[*Restaurant]{Data: restaurants, Limit: query.PageSize}
+	}
+
+	total := query.Total
+	if total == "" && !query.Keyset() {
+		total = pagination.TotalExact
+	}
+	if total == pagination.TotalExact || total == pagination.TotalEstimate {
+		estimate := total == pagination.TotalEstimate
+		count, err := s.repo.Count(query, estimate)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &count
+		page.TotalEstimated = estimate
+	}
+
+	return page, nil
+}
+
+func (s *Service) Update(actor *Actor, id int64, req *UpdateRestaurantRequest) (*Restaurant, error) {
+	if err := s.Authorize(actor, id, auth.PermissionRestaurantsWrite, MemberRoleOwner, MemberRoleManager); err != nil {
+		return nil, err
+	}
+
 	restaurant, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
@@ -56,6 +215,19 @@ func (s *Service) Update(id int64, req *UpdateRestaurantRequest) (*Restaurant, e
 	if req.Name != nil {
 		restaurant.Name = *req.Name
 	}
+	if req.Slug != nil && *req.Slug != restaurant.Slug {
+		if !slugPattern.MatchString(*req.Slug) || len(*req.Slug) > maxSlugBase {
+			return nil, errors.New("slug may only contain lowercase letters, digits and single dashes")
+		}
+		exists, err := s.repo.SlugExists(*req.Slug, restaurant.ID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, errors.New("slug is already taken")
+		}
+		restaurant.Slug = *req.Slug
+	}
 	if req.Description != nil {
 		restaurant.Description = req.Description
 	}
@@ -71,17 +243,450 @@ func (s *Service) Update(id int64, req *UpdateRestaurantRequest) (*Restaurant, e
 	if req.ImageURL != nil {
 		restaurant.ImageURL = req.ImageURL
 	}
+	if req.Cuisines != nil {
+		cuisines, err := normalizeCuisines(req.Cuisines)
+		if err != nil {
+			return nil, err
+		}
+		restaurant.Cuisines = cuisines
+	}
+	if req.Currency != nil {
+		currency, err := money.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if currency != restaurant.Currency {
+			// Prices cannot be converted, so they have to be changed first
+			mixed, err := s.repo.HasPricesInOtherCurrency(id, currency)
+			if err != nil {
+				return nil, err
+			}
+			if mixed {
+				return nil, fmt.Errorf("the menu has dishes priced in %s; reprice them before changing the currency", restaurant.Currency)
+			}
+			restaurant.Currency = currency
+		}
+	}
 	if req.IsActive != nil {
 		restaurant.IsActive = *req.IsActive
 	}
+	updateLocation(&restaurant.Location, &req.Location)
+	if err := validateLocation(&restaurant.Location); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Update(id, restaurant); err != nil {
 		return nil, err
 	}
 
+	if err := s.applyOpenStatus([]*Restaurant{restaurant}); err != nil {
+		return nil, err
+	}
+
 	return restaurant, nil
 }
 
 func (s *Service) Delete(id int64) error {
 	return s.repo.Delete(id)
 }
+
+// GetPublic lists active restaurants matching query for the customer-facing
+// API.
+func (s *Service) GetPublic(query *ListQuery) (*pagination.Page[*PublicRestaurant], error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+	if query.Sort == "" {
+		query.Sort = defaultSort(query, "name")
+	}
+
+	isActive := true
+	query.IsActive = &isActive
+	query.MemberID = 0
+
+	page, err := s.list(query)
+	if err != nil {
+		return nil, err
+	}
+
+	public := make([]*PublicRestaurant, 0, len(page.Data))
+	for _, restaurant := range page.Data {
+		public = append(public, restaurant.Public())
+	}
+
+	return &pagination.Page[*PublicRestaurant]{
+		Data:           public,
+		NextCursor:     page.NextCursor,
+		Limit:          page.Limit,
+		Total:          page.Total,
+		TotalEstimated: page.TotalEstimated,
+	}, nil
+}
+
+func (s *Service) GetPublicBySlug(slug string) (*PublicRestaurant, error) {
+	restaurant, err := s.repo.GetActiveBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyOpenStatus([]*Restaurant{restaurant}); err != nil {
+		return nil, err
+	}
+
+	return restaurant.Public(), nil
+}
+
+// GetActiveBySlug returns an active restaurant for public endpoints of other
+// modules.
+func (s *Service) GetActiveBySlug(slug string) (*Restaurant, error) {
+	return s.repo.GetActiveBySlug(slug)
+}
+
+// Lookup returns a restaurant, active or not, without checking access. It
+// is for other modules acting on a customer's behalf.
+func (s *Service) Lookup(id int64) (*Restaurant, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetPublicOpeningHours returns the hours of an active restaurant with the
+// exceptions that have not passed yet.
+func (s *Service) GetPublicOpeningHours(slug string) (*OpeningHours, error) {
+	restaurant, err := s.repo.GetActiveBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openingHours(restaurant, recentDate(time.Now()))
+}
+
+func (s *Service) GetOpeningHours(actor *Actor, id int64) (*OpeningHours, error) {
+	if err := s.Authorize(actor, id, auth.PermissionRestaurantsRead, MemberRoleOwner, MemberRoleManager, MemberRoleStaff); err != nil {
+		return nil, err
+	}
+
+	restaurant, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openingHours(restaurant, "")
+}
+
+// SetOpeningHours replaces the whole schedule of a restaurant.
+func (s *Service) SetOpeningHours(actor *Actor, id int64, hours *OpeningHours) (*OpeningHours, error) {
+	if err := s.Authorize(actor, id, auth.PermissionRestaurantsWrite, MemberRoleOwner, MemberRoleManager); err != nil {
+		return nil, err
+	}
+
+	if _, err := newSchedule(hours); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetOpeningHours(id, hours); err != nil {
+		return nil, err
+	}
+
+	return s.GetOpeningHours(actor, id)
+}
+
+// GetPricing returns the tax and fee settings of a restaurant to any of its
+// members.
+func (s *Service) GetPricing(actor *Actor, id int64) (*pricing.Rules, error) {
+	if err := s.Authorize(actor, id, auth.PermissionRestaurantsRead, MemberRoleOwner, MemberRoleManager, MemberRoleStaff); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPricing(id)
+}
+
+// SetPricing replaces the tax and fee settings of a restaurant. They apply
+// to carts from then on; placed orders keep theirs.
+func (s *Service) SetPricing(actor *Actor, id int64, rules *pricing.Rules) (*pricing.Rules, error) {
+	if err := s.Authorize(actor, id, auth.PermissionRestaurantsWrite, MemberRoleOwner, MemberRoleManager); err != nil {
+		return nil, err
+	}
+
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPricing(id, rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// LookupPricing returns the tax and fee settings of a restaurant without
+// checking access, for pricing carts and orders.
+func (s *Service) LookupPricing(id int64) (*pricing.Rules, error) {
+	return s.repo.GetPricing(id)
+}
+
+func (s *Service) openingHours(restaurant *Restaurant, since string) (*OpeningHours, error) {
+	hours, err := s.repo.GetOpeningHours([]int64{restaurant.ID}, since)
+	if err != nil {
+		return nil, err
+	}
+
+	hours[restaurant.ID].TimeZone = restaurant.TimeZone
+	return hours[restaurant.ID], nil
+}
+
+// applyOpenStatus computes is_open_now and next_opening_at. Inactive
+// restaurants are always closed.
+func (s *Service) applyOpenStatus(restaurants []*Restaurant) error {
+	now := time.Now()
+
+	ids := make([]int64, 0, len(restaurants))
+	for _, restaurant := range restaurants {
+		ids = append(ids, restaurant.ID)
+	}
+
+	hours, err := s.repo.GetOpeningHours(ids, recentDate(now))
+	if err != nil {
+		return err
+	}
+
+	for _, restaurant := range restaurants {
+		restaurant.IsOpenNow = false
+		restaurant.NextOpeningAt = nil
+		restaurant.changedAt = time.Time{}
+		if !restaurant.IsActive {
+			continue
+		}
+
+		hours[restaurant.ID].TimeZone = restaurant.TimeZone
+		schedule, err := newSchedule(hours[restaurant.ID])
+		if err != nil {
+			log.Printf("Invalid opening hours of restaurant %d: %v", restaurant.ID, err)
+			continue
+		}
+
+		restaurant.IsOpenNow = schedule.isOpen(now)
+		restaurant.NextOpeningAt = schedule.nextOpening(now)
+		restaurant.changedAt = schedule.lastChange(now)
+	}
+
+	return nil
+}
+
+// recentDate is a date early enough to include the exceptions for today and
+// yesterday in any time zone.
+func recentDate(now time.Time) string {
+	return now.UTC().AddDate(0, 0, -2).Format(exceptionDateLayout)
+}
+
+// uniqueSlug derives a slug from name, adding the lowest free "-N" suffix if
+// another restaurant already uses it.
+func (s *Service) uniqueSlug(name string, excludeID int64) (string, error) {
+	base := slugify(name)
+	if base == "" {
+		base = "restaurant"
+	}
+
+	slug := base
+	for n := 2; ; n++ {
+		exists, err := s.repo.SlugExists(slug, excludeID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (s *Service) GetMembers(actor *Actor, restaurantID int64) ([]*Member, error) {
+	if err := s.Authorize(actor, restaurantID, auth.PermissionRestaurantsRead, MemberRoleOwner, MemberRoleManager, MemberRoleStaff); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetMembers(restaurantID)
+}
+
+// UpdateMember changes the role of a member. Only owners (and platform
+// writers) can do this.
+func (s *Service) UpdateMember(actor *Actor, restaurantID, userID int64, role string) error {
+	if err := s.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite, MemberRoleOwner); err != nil {
+		return err
+	}
+
+	return s.repo.ChangeMember(restaurantID, userID, role)
+}
+
+// RemoveMember removes a member. Owners can remove anyone, managers can remove
+// staff, and every member can leave.
+func (s *Service) RemoveMember(actor *Actor, restaurantID, userID int64) error {
+	if actor.UserID != userID {
+		role, err := s.repo.GetMemberRole(restaurantID, userID)
+		if err != nil {
+			return err
+		}
+
+		allowed := []string{MemberRoleOwner}
+		if role == MemberRoleStaff {
+			allowed = append(allowed, MemberRoleManager)
+		}
+		if err := s.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite, allowed...); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.ChangeMember(restaurantID, userID, "")
+}
+
+// Invite emails an invitation to join the restaurant. Managers may only
+// invite staff.
+func (s *Service) Invite(actor *Actor, restaurantID int64, req *InviteMemberRequest) (*Invitation, error) {
+	allowed := []string{MemberRoleOwner}
+	if req.Role == MemberRoleStaff {
+		allowed = append(allowed, MemberRoleManager)
+	}
+	if err := s.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite, allowed...); err != nil {
+		return nil, err
+	}
+
+	restaurant, err := s.repo.GetByID(restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &Invitation{
+		RestaurantID:   restaurant.ID,
+		RestaurantName: restaurant.Name,
+		Email:          strings.ToLower(req.Email),
+		Role:           req.Role,
+		InvitedBy:      &actor.UserID,
+		ExpiresAt:      time.Now().UTC().Add(invitationTTL),
+	}
+	if err := s.repo.CreateInvitation(invitation); err != nil {
+		return nil, err
+	}
+
+	if err := s.mailer.Send(&mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to %s", restaurant.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s as %s.\n\n"+
+				"Sign in or create an account with this email address, then accept the invitation:\n%s/invitations\n\n"+
+				"The invitation expires in %s.\n",
+			restaurant.Name, invitation.Role, s.appURL, invitationTTL,
+		),
+	}); err != nil {
+		// Nobody was told about it, so it must not stay open to be accepted
+		if revokeErr := s.repo.RespondToInvitation(invitation, InvitationRevoked, 0); revokeErr != nil {
+			return nil, fmt.Errorf("failed to send invitation: %w (and failed to revoke it: %v)", err, revokeErr)
+		}
+		return nil, fmt.Errorf("failed to send invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+func (s *Service) GetInvitations(actor *Actor, restaurantID int64) ([]*Invitation, error) {
+	if err := s.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite, MemberRoleOwner, MemberRoleManager); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPendingInvitations(restaurantID, time.Now().UTC())
+}
+
+func (s *Service) RevokeInvitation(actor *Actor, restaurantID, invitationID int64) error {
+	invitation, err := s.repo.GetInvitation(invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation.RestaurantID != restaurantID {
+		return fmt.Errorf("invitation not found")
+	}
+
+	allowed := []string{MemberRoleOwner}
+	if invitation.Role == MemberRoleStaff {
+		allowed = append(allowed, MemberRoleManager)
+	}
+	if err := s.Authorize(actor, restaurantID, auth.PermissionRestaurantsWrite, allowed...); err != nil {
+		return err
+	}
+
+	return s.repo.RespondToInvitation(invitation, InvitationRevoked, 0)
+}
+
+// GetMyInvitations lists pending invitations addressed to the actor's email.
+func (s *Service) GetMyInvitations(actor *Actor) ([]*Invitation, error) {
+	return s.repo.GetPendingInvitationsByEmail(strings.ToLower(actor.Email), time.Now().UTC())
+}
+
+// AcceptInvitation makes the actor a member. The invitation only proves that
+// someone knew the address, so the account must have verified it.
+func (s *Service) AcceptInvitation(actor *Actor, invitationID int64) error {
+	if !actor.EmailVerified {
+		return errors.New("verify your email address before accepting invitations")
+	}
+
+	invitation, err := s.myPendingInvitation(actor, invitationID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RespondToInvitation(invitation, InvitationAccepted, actor.UserID)
+}
+
+func (s *Service) DeclineInvitation(actor *Actor, invitationID int64) error {
+	invitation, err := s.myPendingInvitation(actor, invitationID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RespondToInvitation(invitation, InvitationDeclined, actor.UserID)
+}
+
+func (s *Service) myPendingInvitation(actor *Actor, invitationID int64) (*Invitation, error) {
+	invitation, err := s.repo.GetInvitation(invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Someone else's invitation is reported as missing
+	if invitation.Email != strings.ToLower(actor.Email) {
+		return nil, fmt.Errorf("invitation not found")
+	}
+	if invitation.Status != InvitationPending {
+		return nil, errors.New("invitation is no longer pending")
+	}
+	if time.Now().UTC().After(invitation.ExpiresAt) {
+		return nil, errors.New("invitation has expired")
+	}
+
+	return invitation, nil
+}
+
+// Authorize allows the actor if they hold the platform permission or one of
+// the member roles in the restaurant. Other modules use it for data that
+// belongs to a restaurant.
+func (s *Service) Authorize(actor *Actor, restaurantID int64, permission string, roles ...string) error {
+	if actor.Permissions.Has(permission) {
+		return nil
+	}
+
+	role, err := s.repo.GetMemberRole(restaurantID, actor.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, allowed := range roles {
+		if role == allowed {
+			return nil
+		}
+	}
+
+	return ErrForbidden
+}
@@ -0,0 +1,57 @@
+package restaurants
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Generated slugs are cut to this length, leaving room for a "-N" suffix.
+const maxSlugBase = 200
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// slugify turns a name into a URL slug. Russian letters are transliterated,
+// any other run of characters outside [a-z0-9] becomes a single dash. The
+// 013_restaurant_slugs migration applies the same rules in SQL.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		var part string
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			part = string(r)
+		default:
+			latin, ok := cyrillicToLatin[r]
+			if !ok {
+				dash = b.Len() > 0
+				continue
+			}
+			part = latin
+		}
+
+		if part == "" {
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugBase {
+		slug = strings.TrimRight(slug[:maxSlugBase], "-")
+	}
+	return slug
+}
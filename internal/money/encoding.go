@@ -0,0 +1,64 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalJSON reads {"amount": 1250, "currency": "EUR"} and rejects an
+// unknown currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	value, err := New(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
+
+// Value stores the amount in one text column as "<minor units> <currency>",
+// "1250 EUR". Tables that query amounts keep them in separate amount and
+// currency columns instead.
+func (m Money) Value() (driver.Value, error) {
+	return strconv.FormatInt(m.Amount, 10) + " " + m.Currency, nil
+}
+
+// Scan reads what Value stores.
+func (m *Money) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money", src)
+	}
+
+	amount, currency, ok := strings.Cut(strings.TrimSpace(text), " ")
+	if !ok {
+		return fmt.Errorf("invalid money %q", text)
+	}
+	units, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money %q", text)
+	}
+
+	value, err := New(units, currency)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
@@ -0,0 +1,270 @@
+// Package money represents amounts as integers in the minor unit of their
+// ISO 4217 currency (cents, kopecks, yen), so that prices never pass
+// through floating point. Amounts in different currencies never mix:
+// arithmetic on them fails with ErrCurrencyMismatch.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrOverflow         = errors.New("amount is out of range")
+)
+
+type Money struct {
+	Amount   int64  `json:"amount"` // In minor units of Currency
+	Currency string `json:"currency"`
+}
+
+// New returns an amount of minor units, with the currency code normalized.
+func New(amount int64, currency string) (Money, error) {
+	code, err := NormalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+// Zero returns nothing in currency, to start a sum from.
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// SameCurrency reports whether m and other can be added or compared.
+func (m Money) SameCurrency(other Money) bool {
+	return m.Currency == other.Currency
+}
+
+func (m Money) check(other Money) error {
+	if !m.SameCurrency(other) {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.check(other); err != nil {
+		return Money{}, err
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Mul multiplies by a whole number, such as a quantity.
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount == 0 || n == 0 {
+		return Money{Currency: m.Currency}, nil
+	}
+	product := m.Amount * n
+	if product/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Cmp compares two amounts in the same currency: -1 if m is less than
+// other, 0 if they are equal and 1 if m is more.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.check(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Equal reports whether m and other are the same amount in the same
+// currency.
+func (m Money) Equal(other Money) bool {
+	return m == other
+}
+
+// Rounding says what to do with a fraction of a minor unit.
+type Rounding int
+
+const (
+	RoundHalfUp   Rounding = iota // Halves away from zero, as on a receipt
+	RoundHalfEven                 // Halves to the even neighbour, bankers' rounding
+	RoundDown                     // Toward zero
+	RoundUp                       // Away from zero
+)
+
+// MulRat multiplies by numerator/denominator and rounds the result to a whole
+// minor unit. A rate in basis points is MulRat(bp, 10000, ...).
+func (m Money) MulRat(numerator, denominator int64, rounding Rounding) (Money, error) {
+	if denominator == 0 {
+		return Money{}, errors.New("division by zero")
+	}
+
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(numerator))
+	d := big.NewInt(denominator)
+	if d.Sign() < 0 {
+		product.Neg(product)
+		d.Neg(d)
+	}
+
+	quotient, remainder := new(big.Int).QuoRem(product, d, new(big.Int))
+	if remainder.Sign() != 0 {
+		away := false
+		switch rounding {
+		case RoundUp:
+			away = true
+		case RoundHalfUp, RoundHalfEven:
+			twice := new(big.Int).Abs(remainder)
+			twice.Lsh(twice, 1)
+			switch twice.Cmp(d) {
+			case 1:
+				away = true
+			case 0:
+				away = rounding == RoundHalfUp || quotient.Bit(0) == 1
+			}
+		}
+		if away {
+			quotient.Add(quotient, big.NewInt(int64(product.Sign())))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: quotient.Int64(), Currency: m.Currency}, nil
+}
+
+// Allocate splits m in proportion to ratios without losing a minor unit:
+// what rounding leaves over goes, one unit each, to the first parts.
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	var total int64
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, errors.New("ratios must not be negative")
+		}
+		total += ratio
+		if total < 0 {
+			return nil, ErrOverflow
+		}
+	}
+	if total == 0 {
+		return nil, errors.New("ratios must not all be zero")
+	}
+
+	parts := make([]Money, len(ratios))
+	left := m.Amount
+	for i, ratio := range ratios {
+		part, err := m.MulRat(ratio, total, RoundDown)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = part
+		left -= part.Amount
+	}
+
+	unit := int64(1)
+	if left < 0 {
+		unit = -1
+	}
+	for i := 0; left != 0; i++ {
+		if ratios[i%len(ratios)] == 0 {
+			continue
+		}
+		parts[i%len(ratios)].Amount += unit
+		left -= unit
+	}
+
+	return parts, nil
+}
+
+// Sum adds amounts in currency; it fails if any of them is in another one.
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// Parse reads a decimal amount in major units, such as "12.50", exactly. It
+// rejects more decimal places than the currency has.
+func Parse(amount, currency string) (Money, error) {
+	code, err := NormalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	digits, _ := MinorUnits(code)
+
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(amount, "-"), ".")
+	if whole == "" || len(fraction) > digits || strings.ContainsAny(whole+fraction, "+-") {
+		return Money{}, fmt.Errorf("invalid amount %q for %s", amount, code)
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+
+	units, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q for %s", amount, code)
+	}
+	if negative {
+		units = -units
+	}
+
+	return Money{Amount: units, Currency: code}, nil
+}
+
+// Major formats the amount in major units, "12.50" for 1250 cents.
+func (m Money) Major() string {
+	digits, ok := MinorUnits(m.Currency)
+	if !ok {
+		digits = 2
+	}
+
+	units := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if strings.HasPrefix(units, "-") {
+		sign, units = "-", units[1:]
+	}
+	if digits == 0 {
+		return sign + units
+	}
+	if len(units) <= digits {
+		units = strings.Repeat("0", digits-len(units)+1) + units
+	}
+	return sign + units[:len(units)-digits] + "." + units[len(units)-digits:]
+}
+
+// String formats the amount for logs and messages, "12.50 EUR".
+func (m Money) String() string {
+	return m.Major() + " " + m.Currency
+}
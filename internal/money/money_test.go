@@ -0,0 +1,329 @@
+package money
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMulRat(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		rounding Rounding
+		want     int64
+	}{
+		{"exact", 1000, 2000, 10000, RoundHalfUp, 200},
+		{"half up rounds half away", 25, 1, 10, RoundHalfUp, 3},
+		{"half up negative", -25, 1, 10, RoundHalfUp, -3},
+		{"half up below half", 24, 1, 10, RoundHalfUp, 2},
+		{"half even to even", 25, 1, 10, RoundHalfEven, 2},
+		{"half even odd rounds up", 35, 1, 10, RoundHalfEven, 4},
+		{"half even negative", -25, 1, 10, RoundHalfEven, -2},
+		{"half even above half", 26, 1, 10, RoundHalfEven, 3},
+		{"down", 29, 1, 10, RoundDown, 2},
+		{"down negative", -29, 1, 10, RoundDown, -2},
+		{"up", 21, 1, 10, RoundUp, 3},
+		{"up negative", -21, 1, 10, RoundUp, -3},
+		{"negative denominator", 25, 1, -10, RoundHalfUp, -3},
+		{"inclusive tax share", 1200, 2000, 12000, RoundHalfUp, 200},
+		{"large intermediate product", math.MaxInt64, 10000, 10000, RoundHalfUp, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Money{Amount: tt.amount, Currency: "EUR"}.MulRat(tt.num, tt.den, tt.rounding)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Amount != tt.want || got.Currency != "EUR" {
+				t.Errorf("got %d %s, want %d EUR", got.Amount, got.Currency, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulRatErrors(t *testing.T) {
+	m := Money{Amount: math.MaxInt64, Currency: "EUR"}
+	if _, err := m.MulRat(2, 1, RoundHalfUp); !errors.Is(err, ErrOverflow) {
+		t.Errorf("overflow: got %v, want ErrOverflow", err)
+	}
+	if _, err := m.MulRat(1, 0, RoundHalfUp); err == nil {
+		t.Error("division by zero: expected an error")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		ratios []int64
+		want   []int64
+	}{
+		{"even", 900, []int64{1, 1, 1}, []int64{300, 300, 300}},
+		{"remainder to first parts", 1000, []int64{1, 1, 1}, []int64{334, 333, 333}},
+		{"proportional", 1000, []int64{70, 30}, []int64{700, 300}},
+		{"proportional remainder", 101, []int64{1, 2}, []int64{34, 67}},
+		{"zero ratio gets nothing", 10, []int64{0, 1, 1}, []int64{0, 5, 5}},
+		{"remainder skips zero ratio", 11, []int64{0, 1, 1}, []int64{0, 6, 5}},
+		{"negative amount", -1000, []int64{1, 1, 1}, []int64{-334, -333, -333}},
+		{"one part", 1234, []int64{5}, []int64{1234}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := Money{Amount: tt.amount, Currency: "USD"}.Allocate(tt.ratios...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got %d parts, want %d", len(parts), len(tt.want))
+			}
+			var sum int64
+			for i, part := range parts {
+				if part.Amount != tt.want[i] {
+					t.Errorf("part %d: got %d, want %d", i, part.Amount, tt.want[i])
+				}
+				sum += part.Amount
+			}
+			if sum != tt.amount {
+				t.Errorf("parts add up to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestAllocateErrors(t *testing.T) {
+	m := Money{Amount: 100, Currency: "USD"}
+	tests := []struct {
+		name   string
+		ratios []int64
+	}{
+		{"no ratios", nil},
+		{"all zero", []int64{0, 0}},
+		{"negative", []int64{1, -1}},
+		{"ratio sum overflows", []int64{math.MaxInt64, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Allocate(tt.ratios...); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	max := Money{Amount: math.MaxInt64, Currency: "EUR"}
+	min := Money{Amount: math.MinInt64, Currency: "EUR"}
+	one := Money{Amount: 1, Currency: "EUR"}
+
+	tests := []struct {
+		name string
+		op   func() (Money, error)
+	}{
+		{"add past max", func() (Money, error) { return max.Add(one) }},
+		{"add past min", func() (Money, error) { return min.Add(Money{Amount: -1, Currency: "EUR"}) }},
+		{"sub past min", func() (Money, error) { return min.Sub(one) }},
+		{"sub min", func() (Money, error) { return Zero("EUR").Sub(min) }},
+		{"mul", func() (Money, error) { return max.Mul(2) }},
+		{"mul min by -1", func() (Money, error) { return min.Mul(-1) }},
+		{"mul -1 by min", func() (Money, error) { return Money{Amount: -1, Currency: "EUR"}.Mul(math.MinInt64) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.op(); !errors.Is(err, ErrOverflow) {
+				t.Errorf("got %v, want ErrOverflow", err)
+			}
+		})
+	}
+
+	if got, err := max.Add(Money{Amount: -1, Currency: "EUR"}); err != nil || got.Amount != math.MaxInt64-1 {
+		t.Errorf("add within range: got %v, %v", got, err)
+	}
+	if got, err := (Money{Amount: 250, Currency: "EUR"}).Mul(3); err != nil || got.Amount != 750 {
+		t.Errorf("mul within range: got %v, %v", got, err)
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	eur := Money{Amount: 100, Currency: "EUR"}
+	usd := Money{Amount: 100, Currency: "USD"}
+	if _, err := eur.Add(usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("add: got %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := Sum("EUR", eur, usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("sum: got %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"12.50", "EUR", Money{1250, "EUR"}, false},
+		{"12.5", "eur", Money{1250, "EUR"}, false},
+		{"12", "EUR", Money{1200, "EUR"}, false},
+		{"0.05", "USD", Money{5, "USD"}, false},
+		{" -3.10 ", "USD", Money{-310, "USD"}, false},
+		{"1500", "JPY", Money{1500, "JPY"}, false},
+		{"1.234", "KWD", Money{1234, "KWD"}, false},
+		{"12.", "EUR", Money{1200, "EUR"}, false},
+		{"12.505", "EUR", Money{}, true},
+		{"15.5", "JPY", Money{}, true},
+		{"", "EUR", Money{}, true},
+		{".50", "EUR", Money{}, true},
+		{"+12", "EUR", Money{}, true},
+		{"--12", "EUR", Money{}, true},
+		{"1-2", "EUR", Money{}, true},
+		{"12,50", "EUR", Money{}, true},
+		{"abc", "EUR", Money{}, true},
+		{"99999999999999999999", "EUR", Money{}, true},
+		{"12.50", "XXX", Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := Parse(tt.amount, tt.currency)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMajor(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{Money{1250, "EUR"}, "12.50"},
+		{Money{5, "EUR"}, "0.05"},
+		{Money{-5, "EUR"}, "-0.05"},
+		{Money{1500, "JPY"}, "1500"},
+		{Money{1234, "KWD"}, "1.234"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.Major(); got != tt.want {
+			t.Errorf("%d %s: got %q, want %q", tt.m.Amount, tt.m.Currency, got, tt.want)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	var _ driver.Valuer = Money{}
+	var _ sql.Scanner = &Money{}
+
+	for _, m := range []Money{{1250, "EUR"}, {-5, "USD"}, {0, "JPY"}, {math.MaxInt64, "KWD"}} {
+		value, err := m.Value()
+		if err != nil {
+			t.Fatalf("Value(%v): %v", m, err)
+		}
+
+		var scanned Money
+		if err := scanned.Scan(value); err != nil {
+			t.Fatalf("Scan(%v): %v", value, err)
+		}
+		if scanned != m {
+			t.Errorf("round trip of %v: got %v", m, scanned)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Money
+		wantErr bool
+	}{
+		{"string", "1250 EUR", Money{1250, "EUR"}, false},
+		{"bytes", []byte("-300 usd"), Money{-300, "USD"}, false},
+		{"padded", " 1500 JPY ", Money{1500, "JPY"}, false},
+		{"unknown currency", "1250 XXX", Money{}, true},
+		{"no currency", "1250", Money{}, true},
+		{"amount not a number", "12.50 EUR", Money{}, true},
+		{"out of range", "99999999999999999999 EUR", Money{}, true},
+		{"empty", "", Money{}, true},
+		{"null", nil, Money{}, true},
+		{"wrong type", int64(1250), Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.Scan(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(Money{1250, "EUR"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `{"amount":1250,"currency":"EUR"}` {
+		t.Errorf("got %s", data)
+	}
+
+	tests := []struct {
+		data    string
+		want    Money
+		wantErr bool
+	}{
+		{`{"amount":1250,"currency":"EUR"}`, Money{1250, "EUR"}, false},
+		{`{"amount":-5,"currency":"usd"}`, Money{-5, "USD"}, false},
+		{`{"amount":1250,"currency":"XXX"}`, Money{}, true},
+		{`{"amount":1250}`, Money{}, true},
+		{`{"amount":"1250","currency":"EUR"}`, Money{}, true},
+		{`"1250 EUR"`, Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package pagination
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Values of the "total" parameter. Exact totals run a COUNT over every match;
+// estimates come from the query planner and cost about as much as a lookup.
+const (
+	TotalNone     = "none"
+	TotalExact    = "exact"
+	TotalEstimate = "estimate"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last row of a page in (created_at, id) order. Clients
+// get it encoded as next_cursor and should treat it as opaque.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// After returns a condition selecting the rows that follow the cursor. arg
+// adds a query argument and returns its placeholder.
+func (c *Cursor) After(descending bool, arg func(interface{}) string) string {
+	op := ">"
+	if descending {
+		op = "<"
+	}
+	return fmt.Sprintf("(created_at, id) %s (%s, %s)", op, arg(c.CreatedAt), arg(c.ID))
+}
+
+// Params are the pagination parameters of a list request.
+type Params struct {
+	Cursor *Cursor
+	Limit  int
+	Total  string // One of the Total constants; "" lets the endpoint decide
+}
+
+// ParseParams reads cursor, limit and total. Limits above MaxLimit are
+// lowered to it; a missing limit is left at 0.
+func ParseParams(values url.Values) (*Params, error) {
+	params := &Params{Total: values.Get("total")}
+
+	if value := values.Get("cursor"); value != "" {
+		cursor, err := DecodeCursor(value)
+		if err != nil {
+			return nil, err
+		}
+		params.Cursor = cursor
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid limit %q", value)
+		}
+		params.Limit = min(limit, MaxLimit)
+	}
+
+	switch params.Total {
+	case "", TotalNone, TotalExact, TotalEstimate:
+	default:
+		return nil, fmt.Errorf("invalid total %q", params.Total)
+	}
+
+	return params, nil
+}
+
+// Keyset reports whether the request asked for cursor pagination.
+func (p *Params) Keyset() bool {
+	return p.Cursor != nil || p.Limit > 0
+}
+
+// Page is the response of a cursor-paginated list. NextCursor is nil on the
+// last page; Total is set only when requested.
+type Page[T any] struct {
+	Data           []T     `json:"data"`
+	NextCursor     *string `json:"next_cursor"`
+	Limit          int     `json:"limit"`
+	Total          *int    `json:"total,omitempty"`
+	TotalEstimated bool    `json:"total_estimated,omitempty"`
+}
+
+// NewPage builds a page from rows fetched with limit+1, using the extra row
+// only to tell whether there is a next page.
+func NewPage[T any](rows []T, limit int, cursor func(T) Cursor) *Page[T] {
+	page := &Page[T]{Data: rows, Limit: limit}
+	if len(rows) > limit {
+		page.Data = rows[:limit]
+		next := cursor(page.Data[limit-1]).Encode()
+		page.NextCursor = &next
+	}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	return page
+}
+
+// EstimateCount returns the planner's row estimate for query, which should
+// select the matching rows without LIMIT.
+func EstimateCount(db *sql.DB, query string, args ...interface{}) (int, error) {
+	var plan []byte
+	if err := db.QueryRow("EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("failed to estimate count: %w", err)
+	}
+
+	var result []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("failed to parse query plan: empty plan")
+	}
+
+	return int(result[0].Plan.Rows), nil
+}
@@ -0,0 +1,149 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC), ID: 42}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID {
+		t.Errorf("got %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := map[string]string{
+		"not base64":     "!!!",
+		"padded base64":  base64.URLEncoding.EncodeToString([]byte(`{"t":"2026-03-01T00:00:00Z","i":1}`)),
+		"not JSON":       encode("42"),
+		"no ID":          encode(`{"t":"2026-03-01T00:00:00Z"}`),
+		"negative ID":    encode(`{"t":"2026-03-01T00:00:00Z","i":-1}`),
+		"malformed time": encode(`{"t":"yesterday","i":1}`),
+	}
+
+	for name, value := range tests {
+		if _, err := DecodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), ID: 7}
+
+	tests := []struct {
+		query      string
+		wantLimit  int
+		wantCursor bool
+		wantTotal  string
+		wantErr    bool
+	}{
+		{query: ""},
+		{query: "limit=1", wantLimit: 1},
+		{query: "limit=100", wantLimit: MaxLimit},
+		{query: "limit=101", wantLimit: MaxLimit},
+		{query: "limit=1000000", wantLimit: MaxLimit},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=-5", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+		{query: "cursor=" + cursor.Encode(), wantCursor: true},
+		{query: "cursor=bogus", wantErr: true},
+		{query: "total=exact", wantTotal: TotalExact},
+		{query: "total=estimate", wantTotal: TotalEstimate},
+		{query: "total=none", wantTotal: TotalNone},
+		{query: "total=all", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+
+		params, err := ParseParams(values)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: no error", tt.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+
+		if params.Limit != tt.wantLimit || (params.Cursor != nil) != tt.wantCursor || params.Total != tt.wantTotal {
+			t.Errorf("%q: got limit %d, cursor %v, total %q", tt.query, params.Limit, params.Cursor, params.Total)
+		}
+		if params.Keyset() != (tt.wantLimit > 0 || tt.wantCursor) {
+			t.Errorf("%q: Keyset() = %v", tt.query, params.Keyset())
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	cursor := &Cursor{CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), ID: 7}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if got := cursor.After(true, arg); got != "(created_at, id) < ($1, $2)" {
+		t.Errorf("descending: got %q", got)
+	}
+	if got := cursor.After(false, arg); got != "(created_at, id) > ($3, $4)" {
+		t.Errorf("ascending: got %q", got)
+	}
+	if len(args) != 4 || args[1] != int64(7) || !args[0].(time.Time).Equal(cursor.CreatedAt) {
+		t.Errorf("got arguments %v", args)
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	type row struct {
+		id int64
+	}
+	cursorOf := func(r row) Cursor {
+		return Cursor{CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), ID: r.id}
+	}
+
+	// A row more than the limit means there is a next page, starting after
+	// the last row shown
+	page := NewPage([]row{{1}, {2}, {3}}, 2, cursorOf)
+	if len(page.Data) != 2 || page.Limit != 2 {
+		t.Fatalf("got %d rows with limit %d, want 2 with limit 2", len(page.Data), page.Limit)
+	}
+	if page.NextCursor == nil {
+		t.Fatal("no next cursor")
+	}
+	next, err := DecodeCursor(*page.NextCursor)
+	if err != nil || next.ID != 2 {
+		t.Errorf("next cursor points at %+v (%v), want row 2", next, err)
+	}
+
+	page = NewPage([]row{{1}, {2}}, 2, cursorOf)
+	if len(page.Data) != 2 || page.NextCursor != nil {
+		t.Errorf("last page: got %d rows, next cursor %v", len(page.Data), page.NextCursor)
+	}
+
+	page = NewPage[row](nil, 2, cursorOf)
+	if page.Data == nil || len(page.Data) != 0 || page.NextCursor != nil {
+		t.Errorf("empty page: got data %v, next cursor %v", page.Data, page.NextCursor)
+	}
+}
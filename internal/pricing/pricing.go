@@ -0,0 +1,300 @@
+// Package pricing works out what a customer pays for an order: the dishes,
+// the tax on them and the platform's fees. It only calculates; the rules
+// come from the restaurant and the dishes from the cart.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/yourcompany/saas-platform/internal/money"
+)
+
+// Rates are in basis points: 2000 is 20%.
+const (
+	maxRateBP        = 10000
+	maxDeliveryTiers = 20
+)
+
+// Fee types
+const (
+	FeeDelivery   = "delivery"
+	FeeService    = "service"
+	FeeSmallOrder = "small_order"
+)
+
+var (
+	ErrLocationRequired = errors.New("a delivery location is required to work out the delivery fee")
+	ErrTooFar           = errors.New("the delivery location is too far from the restaurant")
+)
+
+// Rules are a restaurant's tax and fee settings. Amounts are in minor units
+// of the restaurant's currency.
+type Rules struct {
+	// Whether menu prices already include tax (as VAT usually does) or tax
+	// is added on top of them
+	PricesIncludeTax bool `json:"prices_include_tax"`
+	// Tax rate of dishes whose category has no rate of its own
+	TaxRateBP int `json:"tax_rate_bp" binding:"min=0,max=10000"`
+
+	// Service fee on the dishes, kept within ServiceFeeMin and
+	// ServiceFeeMax when those are set
+	ServiceFeeBP  int    `json:"service_fee_bp" binding:"min=0,max=10000"`
+	ServiceFeeMin int64  `json:"service_fee_min" binding:"min=0"`
+	ServiceFeeMax *int64 `json:"service_fee_max,omitempty" binding:"omitempty,min=0"`
+
+	// Below MinimumOrder the difference is charged as a small-order
+	// surcharge
+	MinimumOrder int64 `json:"minimum_order" binding:"min=0"`
+
+	// The fee of the first tier that reaches the delivery location. Without
+	// tiers delivery is free; beyond the last one there is none.
+	DeliveryTiers []DeliveryTier `json:"delivery_tiers" binding:"dive"`
+}
+
+type DeliveryTier struct {
+	UpToM int   `json:"up_to_m" binding:"min=1"`
+	Fee   int64 `json:"fee" binding:"min=0"`
+}
+
+// Validate checks the rules and sorts the delivery tiers by distance.
+func (r *Rules) Validate() error {
+	if r.TaxRateBP < 0 || r.TaxRateBP > maxRateBP || r.ServiceFeeBP < 0 || r.ServiceFeeBP > maxRateBP {
+		return fmt.Errorf("rates must be between 0 and %d basis points", maxRateBP)
+	}
+	if r.ServiceFeeMin < 0 || r.MinimumOrder < 0 || (r.ServiceFeeMax != nil && *r.ServiceFeeMax < 0) {
+		return errors.New("amounts must not be negative")
+	}
+	if r.ServiceFeeMax != nil && *r.ServiceFeeMax < r.ServiceFeeMin {
+		return errors.New("service_fee_max must not be less than service_fee_min")
+	}
+
+	if len(r.DeliveryTiers) > maxDeliveryTiers {
+		return fmt.Errorf("there can be at most %d delivery tiers", maxDeliveryTiers)
+	}
+	if r.DeliveryTiers == nil {
+		r.DeliveryTiers = []DeliveryTier{}
+	}
+	sort.Slice(r.DeliveryTiers, func(i, j int) bool { return r.DeliveryTiers[i].UpToM < r.DeliveryTiers[j].UpToM })
+	for i, tier := range r.DeliveryTiers {
+		if tier.UpToM < 1 || tier.Fee < 0 {
+			return errors.New("delivery tiers need a positive up_to_m and a fee of at least 0")
+		}
+		if i > 0 && tier.UpToM == r.DeliveryTiers[i-1].UpToM {
+			return fmt.Errorf("two delivery tiers end at %d m", tier.UpToM)
+		}
+	}
+
+	return nil
+}
+
+// Line is a priced line of a cart. TaxRateBP overrides the restaurant's
+// rate, as a category's rate does.
+type Line struct {
+	Amount    money.Money
+	TaxRateBP *int
+}
+
+type Input struct {
+	Currency  string
+	Lines     []Line
+	DistanceM *float64 // From the restaurant to the delivery location
+}
+
+// Breakdown is the itemised price of an order. Total is what the customer
+// pays: Subtotal, plus tax when prices do not include it, plus the fees.
+// Fees are not taxed.
+type Breakdown struct {
+	Currency         string `json:"currency"`
+	Subtotal         int64  `json:"subtotal"` // The dishes at menu prices
+	PricesIncludeTax bool   `json:"prices_include_tax"`
+	Taxes            []*Tax `json:"taxes"`
+	TaxTotal         int64  `json:"tax_total"`
+	Fees             []*Fee `json:"fees"`
+	FeeTotal         int64  `json:"fee_total"`
+	Total            int64  `json:"total"`
+	DistanceM        *int   `json:"distance_m,omitempty"`
+}
+
+// Tax is the tax at one rate. Base is the amount it is charged on, without
+// the tax.
+type Tax struct {
+	RateBP int   `json:"rate_bp"`
+	Base   int64 `json:"base"`
+	Amount int64 `json:"amount"`
+}
+
+type Fee struct {
+	Type   string `json:"type"`
+	Amount int64  `json:"amount"`
+}
+
+// TaxRate returns the rate a line is taxed at.
+func (r *Rules) TaxRate(line Line) int {
+	if line.TaxRateBP != nil {
+		return *line.TaxRateBP
+	}
+	return r.TaxRateBP
+}
+
+// Calculate prices the lines under rules. Tax is worked out per rate on the
+// sum of the lines at that rate, rounding half up once per rate.
+func Calculate(rules *Rules, input *Input) (*Breakdown, error) {
+	subtotal := money.Zero(input.Currency)
+	byRate := make(map[int]money.Money)
+	for _, line := range input.Lines {
+		var err error
+		if subtotal, err = subtotal.Add(line.Amount); err != nil {
+			return nil, err
+		}
+
+		rate := rules.TaxRate(line)
+		sum, ok := byRate[rate]
+		if !ok {
+			sum = money.Zero(input.Currency)
+		}
+		if byRate[rate], err = sum.Add(line.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	breakdown := &Breakdown{
+		Currency:         input.Currency,
+		Subtotal:         subtotal.Amount,
+		PricesIncludeTax: rules.PricesIncludeTax,
+		Taxes:            []*Tax{},
+		Fees:             []*Fee{},
+	}
+
+	total := subtotal
+	taxTotal := money.Zero(input.Currency)
+
+	rates := make([]int, 0, len(byRate))
+	for rate := range byRate {
+		rates = append(rates, rate)
+	}
+	sort.Ints(rates)
+	for _, rate := range rates {
+		if rate == 0 {
+			continue
+		}
+		tax, base, err := taxOn(byRate[rate], rate, rules.PricesIncludeTax)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.Taxes = append(breakdown.Taxes, &Tax{RateBP: rate, Base: base.Amount, Amount: tax.Amount})
+		if taxTotal, err = taxTotal.Add(tax); err != nil {
+			return nil, err
+		}
+		if !rules.PricesIncludeTax {
+			if total, err = total.Add(tax); err != nil {
+				return nil, err
+			}
+		}
+	}
+	breakdown.TaxTotal = taxTotal.Amount
+
+	charged, err := fees(rules, subtotal, input.DistanceM)
+	if err != nil {
+		return nil, err
+	}
+	feeTotal := money.Zero(input.Currency)
+	for _, f := range charged {
+		breakdown.Fees = append(breakdown.Fees, &Fee{Type: f.kind, Amount: f.amount.Amount})
+		if feeTotal, err = feeTotal.Add(f.amount); err != nil {
+			return nil, err
+		}
+	}
+	breakdown.FeeTotal = feeTotal.Amount
+
+	if total, err = total.Add(feeTotal); err != nil {
+		return nil, err
+	}
+	breakdown.Total = total.Amount
+
+	if input.DistanceM != nil {
+		distance := int(math.Round(*input.DistanceM))
+		breakdown.DistanceM = &distance
+	}
+
+	return breakdown, nil
+}
+
+// taxOn returns the tax on amount and the amount without tax. An amount
+// that includes tax at rate r holds r/(1+r) of it as tax.
+func taxOn(amount money.Money, rateBP int, inclusive bool) (money.Money, money.Money, error) {
+	if !inclusive {
+		tax, err := amount.MulRat(int64(rateBP), maxRateBP, money.RoundHalfUp)
+		return tax, amount, err
+	}
+
+	tax, err := amount.MulRat(int64(rateBP), int64(maxRateBP+rateBP), money.RoundHalfUp)
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	base, err := amount.Sub(tax)
+	return tax, base, err
+}
+
+// LineTax returns the tax added on top of part of the dishes at rateBP,
+// which is none when prices include it or for an order placed without a
+// breakdown. Refunds of single lines use it.
+func (b *Breakdown) LineTax(amount money.Money, rateBP int) (money.Money, error) {
+	if b == nil || b.PricesIncludeTax || rateBP == 0 {
+		return money.Zero(amount.Currency), nil
+	}
+	return amount.MulRat(int64(rateBP), maxRateBP, money.RoundHalfUp)
+}
+
+type fee struct {
+	kind   string
+	amount money.Money
+}
+
+func fees(rules *Rules, subtotal money.Money, distanceM *float64) ([]fee, error) {
+	var result []fee
+
+	if len(rules.DeliveryTiers) > 0 {
+		if distanceM == nil {
+			return nil, ErrLocationRequired
+		}
+		var delivery *DeliveryTier
+		for i := range rules.DeliveryTiers {
+			if *distanceM <= float64(rules.DeliveryTiers[i].UpToM) {
+				delivery = &rules.DeliveryTiers[i]
+				break
+			}
+		}
+		if delivery == nil {
+			return nil, ErrTooFar
+		}
+		if delivery.Fee > 0 {
+			result = append(result, fee{FeeDelivery, money.Money{Amount: delivery.Fee, Currency: subtotal.Currency}})
+		}
+	}
+
+	if rules.ServiceFeeBP > 0 {
+		service, err := subtotal.MulRat(int64(rules.ServiceFeeBP), maxRateBP, money.RoundHalfUp)
+		if err != nil {
+			return nil, err
+		}
+		if service.Amount < rules.ServiceFeeMin {
+			service.Amount = rules.ServiceFeeMin
+		}
+		if rules.ServiceFeeMax != nil && service.Amount > *rules.ServiceFeeMax {
+			service.Amount = *rules.ServiceFeeMax
+		}
+		if service.Amount > 0 {
+			result = append(result, fee{FeeService, service})
+		}
+	}
+
+	if subtotal.Amount < rules.MinimumOrder {
+		surcharge := money.Money{Amount: rules.MinimumOrder - subtotal.Amount, Currency: subtotal.Currency}
+		result = append(result, fee{FeeSmallOrder, surcharge})
+	}
+
+	return result, nil
+}
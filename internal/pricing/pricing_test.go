@@ -0,0 +1,411 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yourcompany/saas-platform/internal/money"
+)
+
+func eur(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "EUR"}
+}
+
+func rate(bp int) *int {
+	return &bp
+}
+
+func distance(m float64) *float64 {
+	return &m
+}
+
+func amount(v int64) *int64 {
+	return &v
+}
+
+func TestCalculateTax(t *testing.T) {
+	tests := []struct {
+		name      string
+		inclusive bool
+		rateBP    int
+		lines     []Line
+		wantTaxes []Tax
+		wantTotal int64
+	}{
+		{
+			name:      "inclusive tax is extracted from the price",
+			inclusive: true,
+			rateBP:    2000,
+			lines:     []Line{{Amount: eur(1200)}},
+			wantTaxes: []Tax{{RateBP: 2000, Base: 1000, Amount: 200}},
+			wantTotal: 1200,
+		},
+		{
+			name:      "exclusive tax is added on top",
+			rateBP:    2000,
+			lines:     []Line{{Amount: eur(1000)}},
+			wantTaxes: []Tax{{RateBP: 2000, Base: 1000, Amount: 200}},
+			wantTotal: 1200,
+		},
+		{
+			name:      "inclusive extraction rounds half up",
+			inclusive: true,
+			rateBP:    1000,
+			lines:     []Line{{Amount: eur(1001)}},
+			wantTaxes: []Tax{{RateBP: 1000, Base: 910, Amount: 91}},
+			wantTotal: 1001,
+		},
+		{
+			name:      "exclusive tax is rounded once per rate, not per line",
+			rateBP:    700,
+			lines:     []Line{{Amount: eur(50)}, {Amount: eur(50)}, {Amount: eur(50)}},
+			wantTaxes: []Tax{{RateBP: 700, Base: 150, Amount: 11}},
+			wantTotal: 161,
+		},
+		{
+			name:   "lines are grouped by rate, in rate order",
+			rateBP: 2000,
+			lines: []Line{
+				{Amount: eur(333)},
+				{Amount: eur(1000), TaxRateBP: rate(700)},
+				{Amount: eur(500), TaxRateBP: rate(700)},
+			},
+			wantTaxes: []Tax{
+				{RateBP: 700, Base: 1500, Amount: 105},
+				{RateBP: 2000, Base: 333, Amount: 67},
+			},
+			wantTotal: 2005,
+		},
+		{
+			name:      "inclusive with several rates",
+			inclusive: true,
+			rateBP:    2000,
+			lines:     []Line{{Amount: eur(600)}, {Amount: eur(1070), TaxRateBP: rate(700)}},
+			wantTaxes: []Tax{
+				{RateBP: 700, Base: 1000, Amount: 70},
+				{RateBP: 2000, Base: 500, Amount: 100},
+			},
+			wantTotal: 1670,
+		},
+		{
+			name:      "a zero rate is not listed",
+			rateBP:    2000,
+			lines:     []Line{{Amount: eur(1000), TaxRateBP: rate(0)}},
+			wantTaxes: []Tax{},
+			wantTotal: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &Rules{PricesIncludeTax: tt.inclusive, TaxRateBP: tt.rateBP}
+			breakdown, err := Calculate(rules, &Input{Currency: "EUR", Lines: tt.lines})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(breakdown.Taxes) != len(tt.wantTaxes) {
+				t.Fatalf("got %d taxes, want %d", len(breakdown.Taxes), len(tt.wantTaxes))
+			}
+			var taxTotal int64
+			for i, tax := range breakdown.Taxes {
+				if *tax != tt.wantTaxes[i] {
+					t.Errorf("tax %d: got %+v, want %+v", i, *tax, tt.wantTaxes[i])
+				}
+				taxTotal += tax.Amount
+			}
+			if breakdown.TaxTotal != taxTotal {
+				t.Errorf("tax total: got %d, want %d", breakdown.TaxTotal, taxTotal)
+			}
+			if breakdown.Total != tt.wantTotal {
+				t.Errorf("total: got %d, want %d", breakdown.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestCalculateFees(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		subtotal int64
+		want     []Fee
+	}{
+		{
+			name:     "service fee is a share of the subtotal",
+			rules:    Rules{ServiceFeeBP: 500},
+			subtotal: 2000,
+			want:     []Fee{{FeeService, 100}},
+		},
+		{
+			name:     "service fee rounds half up",
+			rules:    Rules{ServiceFeeBP: 500},
+			subtotal: 1010,
+			want:     []Fee{{FeeService, 51}},
+		},
+		{
+			name:     "service fee is raised to the minimum",
+			rules:    Rules{ServiceFeeBP: 500, ServiceFeeMin: 99},
+			subtotal: 1000,
+			want:     []Fee{{FeeService, 99}},
+		},
+		{
+			name:     "service fee is capped at the maximum",
+			rules:    Rules{ServiceFeeBP: 500, ServiceFeeMax: amount(300)},
+			subtotal: 10000,
+			want:     []Fee{{FeeService, 300}},
+		},
+		{
+			name:     "service fee between the caps",
+			rules:    Rules{ServiceFeeBP: 500, ServiceFeeMin: 50, ServiceFeeMax: amount(300)},
+			subtotal: 2000,
+			want:     []Fee{{FeeService, 100}},
+		},
+		{
+			name:     "a zero maximum waives the service fee",
+			rules:    Rules{ServiceFeeBP: 500, ServiceFeeMax: amount(0)},
+			subtotal: 2000,
+			want:     []Fee{},
+		},
+		{
+			name:     "no service fee rate, no minimum applied",
+			rules:    Rules{ServiceFeeMin: 99},
+			subtotal: 2000,
+			want:     []Fee{},
+		},
+		{
+			name:     "small order pays the difference to the minimum",
+			rules:    Rules{MinimumOrder: 1500},
+			subtotal: 1200,
+			want:     []Fee{{FeeSmallOrder, 300}},
+		},
+		{
+			name:     "no surcharge at exactly the minimum",
+			rules:    Rules{MinimumOrder: 1500},
+			subtotal: 1500,
+			want:     []Fee{},
+		},
+		{
+			name:     "service fee and surcharge together",
+			rules:    Rules{ServiceFeeBP: 500, ServiceFeeMin: 99, MinimumOrder: 1500},
+			subtotal: 1000,
+			want:     []Fee{{FeeService, 99}, {FeeSmallOrder, 500}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown, err := Calculate(&tt.rules, &Input{Currency: "EUR", Lines: []Line{{Amount: eur(tt.subtotal)}}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(breakdown.Fees) != len(tt.want) {
+				t.Fatalf("got fees %+v, want %+v", breakdown.Fees, tt.want)
+			}
+			var feeTotal int64
+			for i, fee := range breakdown.Fees {
+				if *fee != tt.want[i] {
+					t.Errorf("fee %d: got %+v, want %+v", i, *fee, tt.want[i])
+				}
+				feeTotal += fee.Amount
+			}
+			if breakdown.FeeTotal != feeTotal || breakdown.Total != tt.subtotal+feeTotal {
+				t.Errorf("got fee total %d and total %d for fees of %d", breakdown.FeeTotal, breakdown.Total, feeTotal)
+			}
+		})
+	}
+}
+
+func TestCalculateDelivery(t *testing.T) {
+	tiers := []DeliveryTier{{UpToM: 2000, Fee: 199}, {UpToM: 5000, Fee: 399}, {UpToM: 8000, Fee: 0}}
+
+	tests := []struct {
+		name         string
+		tiers        []DeliveryTier
+		distanceM    *float64
+		wantFee      int64
+		wantDistance *int
+		wantErr      error
+	}{
+		{name: "first tier", tiers: tiers, distanceM: distance(500), wantFee: 199},
+		{name: "on the boundary belongs to the nearer tier", tiers: tiers, distanceM: distance(2000), wantFee: 199},
+		{name: "just past the boundary", tiers: tiers, distanceM: distance(2000.1), wantFee: 399},
+		{name: "at the restaurant", tiers: tiers, distanceM: distance(0), wantFee: 199},
+		{name: "free tier adds no fee", tiers: tiers, distanceM: distance(7999), wantFee: 0},
+		{name: "on the last boundary", tiers: tiers, distanceM: distance(8000), wantFee: 0},
+		{name: "past the last tier", tiers: tiers, distanceM: distance(8000.01), wantErr: ErrTooFar},
+		{name: "tiers need a location", tiers: tiers, wantErr: ErrLocationRequired},
+		{name: "no tiers, no location needed", wantFee: 0},
+		{name: "no tiers, any distance", distanceM: distance(100000), wantFee: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &Rules{DeliveryTiers: tt.tiers}
+			if err := rules.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			breakdown, err := Calculate(rules, &Input{Currency: "EUR", Lines: []Line{{Amount: eur(1000)}}, DistanceM: tt.distanceM})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var fee int64
+			for _, f := range breakdown.Fees {
+				if f.Type == FeeDelivery {
+					fee = f.Amount
+				}
+			}
+			if fee != tt.wantFee {
+				t.Errorf("delivery fee: got %d, want %d", fee, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestCalculateDistanceIsRounded(t *testing.T) {
+	tests := []struct {
+		distanceM float64
+		want      int
+	}{
+		{2500.4, 2500},
+		{2500.5, 2501},
+		{0.2, 0},
+	}
+
+	for _, tt := range tests {
+		rules := &Rules{DeliveryTiers: []DeliveryTier{{UpToM: 5000, Fee: 399}}}
+		breakdown, err := Calculate(rules, &Input{Currency: "EUR", Lines: []Line{{Amount: eur(1000)}}, DistanceM: distance(tt.distanceM)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if breakdown.DistanceM == nil || *breakdown.DistanceM != tt.want {
+			t.Errorf("distance %v: got %v, want %d", tt.distanceM, breakdown.DistanceM, tt.want)
+		}
+	}
+}
+
+func TestCalculateBreakdown(t *testing.T) {
+	rules := &Rules{
+		TaxRateBP:     2000,
+		ServiceFeeBP:  500,
+		ServiceFeeMin: 50,
+		MinimumOrder:  1500,
+		DeliveryTiers: []DeliveryTier{{UpToM: 2000, Fee: 199}, {UpToM: 5000, Fee: 399}},
+	}
+	input := &Input{
+		Currency:  "EUR",
+		Lines:     []Line{{Amount: eur(1000), TaxRateBP: rate(700)}, {Amount: eur(333)}},
+		DistanceM: distance(2500.4),
+	}
+
+	breakdown, err := Calculate(rules, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if breakdown.Subtotal != 1333 || breakdown.TaxTotal != 137 || breakdown.FeeTotal != 633 || breakdown.Total != 2103 {
+		t.Errorf("got subtotal %d, tax %d, fees %d, total %d; want 1333, 137, 633, 2103",
+			breakdown.Subtotal, breakdown.TaxTotal, breakdown.FeeTotal, breakdown.Total)
+	}
+
+	wantFees := []Fee{{FeeDelivery, 399}, {FeeService, 67}, {FeeSmallOrder, 167}}
+	for i, fee := range breakdown.Fees {
+		if i >= len(wantFees) || *fee != wantFees[i] {
+			t.Errorf("fees: got %+v, want %+v", breakdown.Fees, wantFees)
+			break
+		}
+	}
+}
+
+func TestCalculateCurrencyMismatch(t *testing.T) {
+	lines := []Line{{Amount: eur(1000)}, {Amount: money.Money{Amount: 1000, Currency: "USD"}}}
+	if _, err := Calculate(&Rules{}, &Input{Currency: "EUR", Lines: lines}); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("got %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestLineTax(t *testing.T) {
+	tests := []struct {
+		name      string
+		breakdown *Breakdown
+		rateBP    int
+		want      int64
+	}{
+		{"exclusive", &Breakdown{}, 2000, 67},
+		{"inclusive adds nothing", &Breakdown{PricesIncludeTax: true}, 2000, 0},
+		{"zero rate", &Breakdown{}, 0, 0},
+		{"order without a breakdown", nil, 2000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tax, err := tt.breakdown.LineTax(eur(333), tt.rateBP)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tax.Amount != tt.want || tax.Currency != "EUR" {
+				t.Errorf("got %v, want %d EUR", tax, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr bool
+	}{
+		{"empty", Rules{}, false},
+		{"full", Rules{TaxRateBP: 2000, ServiceFeeBP: 500, ServiceFeeMin: 50, ServiceFeeMax: amount(300), MinimumOrder: 1500, DeliveryTiers: []DeliveryTier{{UpToM: 1000, Fee: 0}}}, false},
+		{"equal caps", Rules{ServiceFeeMin: 100, ServiceFeeMax: amount(100)}, false},
+		{"tax rate above 100%", Rules{TaxRateBP: 10001}, true},
+		{"negative service fee rate", Rules{ServiceFeeBP: -1}, true},
+		{"negative minimum order", Rules{MinimumOrder: -1}, true},
+		{"negative service fee maximum", Rules{ServiceFeeMax: amount(-1)}, true},
+		{"maximum below minimum", Rules{ServiceFeeMin: 200, ServiceFeeMax: amount(100)}, true},
+		{"duplicate tiers", Rules{DeliveryTiers: []DeliveryTier{{UpToM: 1000, Fee: 100}, {UpToM: 1000, Fee: 200}}}, true},
+		{"tier without a distance", Rules{DeliveryTiers: []DeliveryTier{{UpToM: 0, Fee: 100}}}, true},
+		{"tier with a negative fee", Rules{DeliveryTiers: []DeliveryTier{{UpToM: 1000, Fee: -1}}}, true},
+		{"too many tiers", Rules{DeliveryTiers: make([]DeliveryTier, maxDeliveryTiers+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected an error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateSortsTiers(t *testing.T) {
+	rules := &Rules{DeliveryTiers: []DeliveryTier{{UpToM: 5000, Fee: 399}, {UpToM: 2000, Fee: 199}}}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules.DeliveryTiers[0].UpToM != 2000 || rules.DeliveryTiers[1].UpToM != 5000 {
+		t.Errorf("tiers are not sorted: %+v", rules.DeliveryTiers)
+	}
+
+	breakdown, err := Calculate(rules, &Input{Currency: "EUR", Lines: []Line{{Amount: eur(1000)}}, DistanceM: distance(1500)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(breakdown.Fees) != 1 || breakdown.Fees[0].Amount != 199 {
+		t.Errorf("got fees %+v, want the 199 delivery fee", breakdown.Fees)
+	}
+}
@@ -1,12 +1,18 @@
 package router
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/yourcompany/saas-platform/internal/config"
 	"github.com/yourcompany/saas-platform/internal/handlers"
 	"github.com/yourcompany/saas-platform/internal/middleware"
 	authModule "github.com/yourcompany/saas-platform/internal/modules/auth"
+	cartsModule "github.com/yourcompany/saas-platform/internal/modules/carts"
+	menusModule "github.com/yourcompany/saas-platform/internal/modules/menus"
+	ordersModule "github.com/yourcompany/saas-platform/internal/modules/orders"
+	paymentsModule "github.com/yourcompany/saas-platform/internal/modules/payments"
 	restaurantsModule "github.com/yourcompany/saas-platform/internal/modules/restaurants"
 )
 
@@ -15,7 +21,12 @@ func SetupRouter(
 	healthHandler *handlers.HealthHandler,
 	authHandler *authModule.Handler,
 	restaurantsHandler *restaurantsModule.Handler,
-) *gin.Engine {
+	menusHandler *menusModule.Handler,
+	cartsHandler *cartsModule.Handler,
+	ordersHandler *ordersModule.Handler,
+	paymentsHandler *paymentsModule.Handler,
+	authenticator middleware.Authenticator,
+) (*gin.Engine, error) {
 	// Set Gin mode based on environment
 	if cfg.Server.Environment == "development" {
 		gin.SetMode(gin.DebugMode)
@@ -25,6 +36,12 @@ func SetupRouter(
 
 	r := gin.New()
 
+	// Login throttling keys on the client IP, so forwarded headers are
+	// believed only from the configured proxies
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid SERVER_TRUSTED_PROXIES: %w", err)
+	}
+
 	// Middleware
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
@@ -34,6 +51,11 @@ func SetupRouter(
 	// Health check endpoint
 	r.GET("/health", healthHandler.HealthCheck)
 
+	// Public keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	authMiddleware := middleware.AuthMiddleware(authenticator)
+
 	// Public routes
 	api := r.Group("/api/v1")
 	{
@@ -42,37 +64,153 @@ func SetupRouter(
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/mfa", authHandler.LoginMFA)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", authMiddleware, authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware, authHandler.LogoutAll)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.POST("/email/verify", authHandler.VerifyEmail)
+			auth.POST("/email/resend", authMiddleware, authHandler.ResendVerificationEmail)
 		}
 
+		// Customer-facing catalogue (public, cacheable)
+		public := api.Group("/public")
+		{
+			public.GET("/restaurants", restaurantsHandler.PublicGetAll)
+			public.GET("/restaurants/:slug", restaurantsHandler.PublicGetBySlug)
+			public.GET("/restaurants/:slug/hours", restaurantsHandler.PublicGetOpeningHours)
+			public.GET("/restaurants/:slug/menu", menusHandler.PublicGetMenu)
+		}
+
+		// Payment provider callbacks, authenticated by their signature
+		api.POST("/webhooks/:provider", paymentsHandler.ReceiveWebhook)
+
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware(cfg.JWT.AccessSecret))
+		protected.Use(authMiddleware)
 		{
 			// User routes
 			protected.GET("/me", authHandler.GetMe)
+			protected.POST("/me/password", authHandler.ChangePassword)
+			protected.GET("/me/sessions", authHandler.GetSessions)
+			protected.DELETE("/me/sessions/:id", authHandler.RevokeSession)
+			protected.POST("/me/2fa/setup", authHandler.SetupTwoFactor)
+			protected.POST("/me/2fa/confirm", authHandler.ConfirmTwoFactor)
+			protected.DELETE("/me/2fa", authHandler.DisableTwoFactor)
+		}
 
-			// Restaurant routes (only superadmin)
-			restaurants := protected.Group("/restaurants")
-			restaurants.Use(middleware.RequireSuperAdmin())
+		// Routes unavailable until an initial password has been changed
+		// (and two-factor authentication set up, where it is mandatory)
+		active := protected.Group("")
+		active.Use(middleware.RequirePasswordChanged())
+		active.Use(middleware.RequireMFAEnrolled())
+		if cfg.Auth.RequireVerifiedEmail {
+			active.Use(middleware.RequireVerifiedEmail())
+		}
+		{
+			// Restaurant routes. Creating and deleting restaurants needs the
+			// platform permission; the rest is also open to the restaurant's
+			// members, depending on their role there.
+			canWriteRestaurants := middleware.RequirePermission(authModule.PermissionRestaurantsWrite)
+			restaurants := active.Group("/restaurants")
 			{
 				restaurants.GET("", restaurantsHandler.GetAll)
 				restaurants.GET("/:id", restaurantsHandler.GetByID)
-				restaurants.POST("", restaurantsHandler.Create)
+				restaurants.POST("", canWriteRestaurants, restaurantsHandler.Create)
 				restaurants.PUT("/:id", restaurantsHandler.Update)
-				restaurants.DELETE("/:id", restaurantsHandler.Delete)
+				restaurants.DELETE("/:id", canWriteRestaurants, restaurantsHandler.Delete)
+
+				restaurants.GET("/:id/hours", restaurantsHandler.GetOpeningHours)
+				restaurants.PUT("/:id/hours", restaurantsHandler.SetOpeningHours)
+				restaurants.GET("/:id/pricing", restaurantsHandler.GetPricing)
+				restaurants.PUT("/:id/pricing", restaurantsHandler.SetPricing)
+				restaurants.GET("/:id/members", restaurantsHandler.GetMembers)
+				restaurants.PUT("/:id/members/:userId", restaurantsHandler.UpdateMember)
+				restaurants.DELETE("/:id/members/:userId", restaurantsHandler.RemoveMember)
+				restaurants.GET("/:id/invitations", restaurantsHandler.GetInvitations)
+				restaurants.POST("/:id/invitations", restaurantsHandler.Invite)
+				restaurants.DELETE("/:id/invitations/:invitationId", restaurantsHandler.RevokeInvitation)
+
+				// Menu: readable by every member, edited by owners and managers.
+				// Availability and stock are also open to staff.
+				restaurants.GET("/:id/menu", menusHandler.GetMenu)
+				restaurants.POST("/:id/menu/categories", menusHandler.CreateCategory)
+				restaurants.PUT("/:id/menu/categories/:categoryId", menusHandler.UpdateCategory)
+				restaurants.DELETE("/:id/menu/categories/:categoryId", menusHandler.DeleteCategory)
+				restaurants.PUT("/:id/menu/categories/:categoryId/availability", menusHandler.SetCategoryAvailability)
+				restaurants.POST("/:id/menu/items", menusHandler.CreateItem)
+				restaurants.PUT("/:id/menu/items/:itemId", menusHandler.UpdateItem)
+				restaurants.DELETE("/:id/menu/items/:itemId", menusHandler.DeleteItem)
+				restaurants.PUT("/:id/menu/items/:itemId/availability", menusHandler.SetItemAvailability)
+				restaurants.PUT("/:id/menu/items/:itemId/stock", menusHandler.SetItemStock)
+				restaurants.POST("/:id/menu/items/:itemId/modifier-groups", menusHandler.CreateModifierGroup)
+				restaurants.PUT("/:id/menu/modifier-groups/:groupId", menusHandler.UpdateModifierGroup)
+				restaurants.DELETE("/:id/menu/modifier-groups/:groupId", menusHandler.DeleteModifierGroup)
+				restaurants.POST("/:id/menu/modifier-groups/:groupId/options", menusHandler.CreateModifierOption)
+				restaurants.PUT("/:id/menu/modifier-options/:optionId", menusHandler.UpdateModifierOption)
+				restaurants.DELETE("/:id/menu/modifier-options/:optionId", menusHandler.DeleteModifierOption)
+				restaurants.PUT("/:id/menu/modifier-options/:optionId/availability", menusHandler.SetModifierOptionAvailability)
+
+				// Orders: seen by every member; who may change a status
+				// depends on the status and the member's role
+				restaurants.GET("/:id/orders", ordersHandler.GetRestaurantOrders)
+				restaurants.GET("/:id/orders/:orderId", ordersHandler.GetRestaurantOrder)
+				restaurants.POST("/:id/orders/:orderId/status", ordersHandler.ChangeStatus)
+				restaurants.GET("/:id/orders/:orderId/payment", paymentsHandler.GetRestaurantPayment)
+			}
+
+			// Carts of the current user, one active per restaurant
+			active.GET("/me/carts", cartsHandler.GetCarts)
+			active.POST("/me/carts", cartsHandler.Open)
+			active.GET("/me/carts/:id", cartsHandler.Get)
+			active.GET("/me/carts/:id/quote", cartsHandler.Quote)
+			active.DELETE("/me/carts/:id", cartsHandler.Delete)
+			active.POST("/me/carts/:id/lines", cartsHandler.AddLine)
+			active.PUT("/me/carts/:id/lines/:lineId", cartsHandler.UpdateLine)
+			active.DELETE("/me/carts/:id/lines/:lineId", cartsHandler.DeleteLine)
+
+			// Orders of the current user
+			active.POST("/me/orders", ordersHandler.Place)
+			active.GET("/me/orders", ordersHandler.GetMyOrders)
+			active.GET("/me/orders/:id", ordersHandler.GetMyOrder)
+			active.POST("/me/orders/:id/cancel", ordersHandler.Cancel)
+			active.POST("/me/orders/:id/payment", paymentsHandler.Pay)
+			active.GET("/me/orders/:id/payment", paymentsHandler.GetPayment)
+
+			// Money of any order, for platform admins and the restaurant's
+			// owner and managers
+			active.GET("/orders/:id/financials", paymentsHandler.GetFinancials)
+			active.POST("/orders/:id/refunds", paymentsHandler.Refund)
+
+			// Invitations addressed to the current user
+			active.GET("/me/invitations", restaurantsHandler.GetMyInvitations)
+			active.POST("/me/invitations/:id/accept", restaurantsHandler.AcceptInvitation)
+			active.POST("/me/invitations/:id/decline", restaurantsHandler.DeclineInvitation)
+
+			// Platform administration
+			canManageRoles := middleware.RequirePermission(authModule.PermissionRolesManage)
+			admin := active.Group("/admin")
+			{
+				admin.GET("/permissions", canManageRoles, authHandler.ListPermissions)
+				admin.GET("/roles", canManageRoles, authHandler.ListRoles)
+				admin.POST("/roles", canManageRoles, authHandler.CreateRole)
+				admin.PUT("/roles/:name", canManageRoles, authHandler.UpdateRole)
+				admin.DELETE("/roles/:name", canManageRoles, authHandler.DeleteRole)
+				admin.PUT("/users/:id/role", middleware.RequirePermission(authModule.PermissionUsersManage), authHandler.AssignRole)
+				admin.POST("/login-lockouts/unlock", middleware.RequirePermission(authModule.PermissionSecurityManage), authHandler.UnlockLogin)
 			}
 		}
 	}
 
-	return r
+	return r, nil
 }
 
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/database"
+	authModule "github.com/yourcompany/saas-platform/internal/modules/auth"
+)
+
+func runKeys(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	subcommand, args := args[0], args[1:]
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := requireMigrated(db); err != nil {
+		return err
+	}
+
+	authService, err := newAuthService(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	switch subcommand {
+	case "list":
+		if err := authService.LoadSigningKeys(); err != nil {
+			return fmt.Errorf("failed to load signing keys: %w", err)
+		}
+		return printSigningKeys(authService.SigningKeys())
+
+	case "rotate":
+		flags := flag.NewFlagSet("keys rotate", flag.ExitOnError)
+		algorithm := flags.String("algorithm", cfg.JWT.SigningAlgorithm, "algorithm of the new key (RS256 or EdDSA)")
+		flags.Parse(args)
+
+		key, err := authService.RotateSigningKey(*algorithm)
+		if err != nil {
+			return fmt.Errorf("failed to rotate signing key: %w", err)
+		}
+		fmt.Printf("Signing with new %s key %s\n", key.Algorithm, key.ID)
+		fmt.Printf("Other instances pick it up within %s\n", cfg.JWT.KeyRefreshInterval)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown keys command %q", subcommand)
+	}
+}
+
+func printSigningKeys(keys []*authModule.SigningKey) error {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALGORITHM\tSTATUS\tCREATED AT\tEXPIRES AT")
+
+	const layout = "2006-01-02 15:04:05"
+	for _, key := range keys {
+		state, expiresAt := "signing", "-"
+		if key.RetiredAt != nil {
+			state = "verify only"
+		}
+		if key.ExpiresAt != nil {
+			expiresAt = key.ExpiresAt.UTC().Format(layout)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.ID, key.Algorithm, state, key.CreatedAt.UTC().Format(layout), expiresAt)
+	}
+
+	return w.Flush()
+}
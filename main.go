@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,11 +18,33 @@ import (
 	"github.com/yourcompany/saas-platform/internal/config"
 	"github.com/yourcompany/saas-platform/internal/database"
 	"github.com/yourcompany/saas-platform/internal/handlers"
-	"github.com/yourcompany/saas-platform/internal/router"
+	"github.com/yourcompany/saas-platform/internal/mailer"
 	authModule "github.com/yourcompany/saas-platform/internal/modules/auth"
+	cartsModule "github.com/yourcompany/saas-platform/internal/modules/carts"
+	menusModule "github.com/yourcompany/saas-platform/internal/modules/menus"
+	ordersModule "github.com/yourcompany/saas-platform/internal/modules/orders"
+	paymentsModule "github.com/yourcompany/saas-platform/internal/modules/payments"
 	restaurantsModule "github.com/yourcompany/saas-platform/internal/modules/restaurants"
+	"github.com/yourcompany/saas-platform/internal/router"
 )
 
+const usage = `Usage: main <command> [arguments]
+
+Commands:
+  serve [--migrate]        Start the HTTP server (default command)
+  migrate up [N]           Apply all or the next N pending migrations
+  migrate down [N]         Roll back the last N applied migrations (default 1)
+  migrate redo             Roll back and re-apply the last migration
+  migrate status           List applied and pending migrations
+  migrate create <name>    Create a new empty migration pair
+  bootstrap-admin --email <email> [--name <name>] [--password-stdin]
+                           Create the first superadmin
+  keys list                List the keys that sign and verify access tokens
+  keys rotate [--algorithm RS256|EdDSA]
+                           Start signing with a new key; the old one keeps
+                           verifying until its tokens have expired
+`
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -29,33 +54,126 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Without a command (or with only flags) the server is started, so
+	// existing deployments running "./main" keep working
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = runServe(cfg, args)
+	case "migrate":
+		err = runMigrate(cfg, args)
+	case "bootstrap-admin":
+		err = runBootstrapAdmin(cfg, args)
+	case "keys":
+		err = runKeys(cfg, args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runServe(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrate := flags.Bool("migrate", cfg.Database.AutoMigrate, "apply pending migrations before starting")
+	flags.Parse(args)
+
 	// Initialize database
 	db, err := database.NewConnection(cfg.Database)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer db.Close()
 
-	// Run migrations
-	if err := database.RunMigrations(db); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Run migrations, or refuse to start on an outdated schema
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if *migrate {
+		if _, err := migrator.Up(0); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+	} else {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return fmt.Errorf("failed to check migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s), starting with %s: run \"migrate up\" or start with --migrate (DB_AUTO_MIGRATE=true)", len(pending), pending[0].Name)
+		}
 	}
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(db)
 
+	// Initialize mailer
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+
 	// Initialize auth module
-	authRepo := authModule.NewRepository(db)
-	authService := authModule.NewService(authRepo, cfg.JWT)
+	authService, err := authModule.NewService(authModule.NewRepository(db), cfg.JWT, cfg.Auth, mail, cfg.Mail.AppURL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize auth: %w", err)
+	}
+	if err := authService.LoadSigningKeys(); err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
 	authHandler := authModule.NewHandler(authService)
 
+	stopKeyWatch := make(chan struct{})
+	defer close(stopKeyWatch)
+	go authService.WatchSigningKeys(cfg.JWT.KeyRefreshInterval, stopKeyWatch)
+
 	// Initialize restaurants module
 	restaurantsRepo := restaurantsModule.NewRepository(db)
-	restaurantsService := restaurantsModule.NewService(restaurantsRepo)
+	restaurantsService := restaurantsModule.NewService(restaurantsRepo, mail, cfg.Mail.AppURL)
 	restaurantsHandler := restaurantsModule.NewHandler(restaurantsService)
 
+	// Initialize menus module
+	menusRepo := menusModule.NewRepository(db)
+	menusService := menusModule.NewService(menusRepo, restaurantsService)
+	menusHandler := menusModule.NewHandler(menusService)
+
+	// Initialize carts module
+	cartsService := cartsModule.NewService(cartsModule.NewRepository(db), restaurantsService, menusService)
+	cartsHandler := cartsModule.NewHandler(cartsService)
+
+	// Initialize orders module
+	ordersService := ordersModule.NewService(ordersModule.NewRepository(db), restaurantsService, menusService, cartsService)
+	ordersHandler := ordersModule.NewHandler(ordersService)
+
+	// Initialize payments module
+	provider, err := paymentsModule.NewProvider(cfg.Payments)
+	if err != nil {
+		return fmt.Errorf("failed to initialize payment provider: %w", err)
+	}
+	paymentsService := paymentsModule.NewService(paymentsModule.NewRepository(db), restaurantsService, ordersService, provider, cfg.Payments)
+	paymentsHandler := paymentsModule.NewHandler(paymentsService)
+	ordersService.AddStatusListener(paymentsService)
+
+	stopWebhooks := make(chan struct{})
+	defer close(stopWebhooks)
+	go paymentsService.RunWebhooks(30*time.Second, stopWebhooks)
+
 	// Setup router
-	r := router.SetupRouter(cfg, healthHandler, authHandler, restaurantsHandler)
+	r, err := router.SetupRouter(cfg, healthHandler, authHandler, restaurantsHandler, menusHandler, cartsHandler, ordersHandler, paymentsHandler, authService)
+	if err != nil {
+		return err
+	}
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -86,9 +204,24 @@ func main() {
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
 
 	log.Println("Server exited")
+	return nil
 }
 
+// newAuthService builds the auth service for the command-line tools.
+func newAuthService(cfg *config.Config, db *sql.DB) (*authModule.Service, error) {
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+
+	authService, err := authModule.NewService(authModule.NewRepository(db), cfg.JWT, cfg.Auth, mail, cfg.Mail.AppURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize auth: %w", err)
+	}
+
+	return authService, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/yourcompany/saas-platform/internal/config"
+	"github.com/yourcompany/saas-platform/internal/database"
+)
+
+// migrationsDir is where "migrate create" writes new files, relative to the
+// repository root. The files are embedded into the binary at build time.
+const migrationsDir = "internal/database/migrations"
+
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	subcommand, args := args[0], args[1:]
+
+	// Creating a migration only touches the source tree
+	if subcommand == "create" {
+		if len(args) != 1 {
+			return errors.New("usage: migrate create <name>")
+		}
+		upPath, downPath, err := database.CreateMigration(migrationsDir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+		return nil
+	}
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	switch subcommand {
+	case "up":
+		limit, err := parseLimit(args, 0)
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(limit)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return nil
+
+	case "down":
+		limit, err := parseLimit(args, 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(limit)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+		return nil
+
+	case "redo":
+		reverted, err := migrator.Down(1)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to redo")
+			return nil
+		}
+		_, err = migrator.Up(1)
+		return err
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(statuses)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", subcommand)
+	}
+}
+
+// requireMigrated fails when the schema is behind the binary, for commands
+// that need it up to date.
+func requireMigrated(db *sql.DB) error {
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migration(s): run \"migrate up\" first", len(pending))
+	}
+	return nil
+}
+
+func parseLimit(args []string, defaultLimit int) (int, error) {
+	if len(args) == 0 {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid migration count %q", args[0])
+	}
+
+	return limit, nil
+}
+
+func printMigrationStatus(statuses []database.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT")
+
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+		}
+		if status.Modified {
+			state += " (modified)"
+		}
+		if status.Missing {
+			state += " (no file)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status.Name, state, appliedAt)
+	}
+
+	return w.Flush()
+}